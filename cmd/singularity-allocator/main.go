@@ -0,0 +1,205 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"innit.gg/singularity/pkg/allocator"
+	"innit.gg/singularity/pkg/allocator/allocationpb"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	"net"
+	"os"
+	"time"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	// to ensure that exec-entrypoint and run can make use of them.
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var (
+	scheme   = runtime.NewScheme()
+	setupLog = ctrl.Log.WithName("setup")
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+
+	utilruntime.Must(singularityv1.AddToScheme(scheme))
+}
+
+func main() {
+	var metricsAddr string
+	var probeAddr string
+	var httpAddr string
+	var grpcAddr string
+	var namespace string
+	var certFile string
+	var keyFile string
+	var clientCAFile string
+	var timeout time.Duration
+	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.StringVar(&httpAddr, "http-bind-address", ":8443", "The address the HTTP allocation endpoint binds to.")
+	flag.StringVar(&grpcAddr, "grpc-bind-address", ":8444", "The address the gRPC allocation endpoint binds to.")
+	flag.StringVar(&namespace, "namespace", "", "The namespace to allocate GameServers from, and the default namespace of requests. All namespaces if empty.")
+	flag.StringVar(&certFile, "tls-cert-file", "/etc/singularity/tls/tls.crt", "The server certificate.")
+	flag.StringVar(&keyFile, "tls-key-file", "/etc/singularity/tls/tls.key", "The private key of the server certificate.")
+	flag.StringVar(&clientCAFile, "client-ca-file", "/etc/singularity/tls/ca.crt", "The CA bundle client certificates are verified with.")
+	flag.DurationVar(&timeout, "timeout", allocator.DefaultTimeout, "The maximum duration of a single allocation request.")
+	opts := zap.Options{
+		Development: true,
+	}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	tlsConfig, err := newTLSConfig(certFile, keyFile, clientCAFile)
+	if err != nil {
+		setupLog.Error(err, "unable to load tls configuration")
+		os.Exit(1)
+	}
+
+	// The manager only provides the informer cache, no controllers are run.
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+		Scheme:                 scheme,
+		MetricsBindAddress:     metricsAddr,
+		HealthProbeBindAddress: probeAddr,
+		Namespace:              namespace,
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to start manager")
+		os.Exit(1)
+	}
+
+	if err = allocator.IndexGameServerState(context.Background(), mgr.GetFieldIndexer()); err != nil {
+		setupLog.Error(err, "unable to index gameservers")
+		os.Exit(1)
+	}
+
+	server := &allocator.Server{
+		Allocator: &allocator.Allocator{
+			Client:      mgr.GetClient(),
+			NodeCounter: &allocator.ListNodeCounter{Reader: mgr.GetClient(), Indexed: true},
+		},
+		Timeout:   timeout,
+		Namespace: namespace,
+	}
+
+	// Both servers are only started once the cache is synced
+	if err = mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		return serveHTTP(ctx, server, httpAddr, tlsConfig)
+	})); err != nil {
+		setupLog.Error(err, "unable to add http server")
+		os.Exit(1)
+	}
+	if err = mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		return serveGRPC(ctx, server, grpcAddr, tlsConfig)
+	})); err != nil {
+		setupLog.Error(err, "unable to add grpc server")
+		os.Exit(1)
+	}
+
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up health check")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up ready check")
+		os.Exit(1)
+	}
+
+	setupLog.Info("starting allocator")
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		setupLog.Error(err, "problem running allocator")
+		os.Exit(1)
+	}
+}
+
+// newTLSConfig returns the server TLS configuration, requiring clients to present a certificate signed by the client CA
+func newTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "error loading server certificate")
+	}
+
+	ca, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading client ca")
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("client ca does not contain any certificates")
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+func serveHTTP(ctx context.Context, server *allocator.Server, addr string, tlsConfig *tls.Config) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrapf(err, "error listening on %s", addr)
+	}
+
+	app := server.App()
+	go func() {
+		<-ctx.Done()
+		_ = app.Shutdown()
+	}()
+
+	setupLog.Info("serving http", "addr", addr)
+	return app.Listener(tls.NewListener(ln, tlsConfig))
+}
+
+func serveGRPC(ctx context.Context, server *allocator.Server, addr string, tlsConfig *tls.Config) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrapf(err, "error listening on %s", addr)
+	}
+
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
+	allocationpb.RegisterAllocationServiceServer(s, server)
+	go func() {
+		<-ctx.Done()
+		s.GracefulStop()
+	}()
+
+	setupLog.Info("serving grpc", "addr", addr)
+	return s.Serve(ln)
+}
@@ -19,27 +19,47 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"github.com/miekg/dns"
+	"innit.gg/singularity/pkg/allocator"
 	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	"innit.gg/singularity/pkg/ingressprovider"
+	"innit.gg/singularity/pkg/ingressprovider/rfc2136"
+	"innit.gg/singularity/pkg/ingressprovider/serverlist"
+	"innit.gg/singularity/pkg/ingressprovider/service"
+	"innit.gg/singularity/pkg/ingressprovider/tcpshield"
 	"innit.gg/singularity/pkg/operator/fleet"
 	"innit.gg/singularity/pkg/operator/gameserver"
+	"innit.gg/singularity/pkg/operator/gameserverallocation"
+	"innit.gg/singularity/pkg/operator/gameserveringress"
 	"innit.gg/singularity/pkg/operator/gameserverinstance"
 	"innit.gg/singularity/pkg/operator/gameserverset"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	//+kubebuilder:scaffold:imports
 )
 
+const (
+	// tcpshieldAPIKeyEnv is the environment variable containing the TCPShield API key
+	tcpshieldAPIKeyEnv = "TCPSHIELD_API_KEY"
+	// rfc2136TSIGSecretEnv is the environment variable containing the base64 encoded TSIG secret of DNS updates
+	rfc2136TSIGSecretEnv = "RFC2136_TSIG_SECRET"
+)
+
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
@@ -56,11 +76,39 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var tcpshieldNetworkID uint
+	var serverListNamespace string
+	var serviceNamespace string
+	var serviceType string
+	var servicePort int
+	var rfc2136Server string
+	var rfc2136Zone string
+	var rfc2136TSIGKey string
+	var rfc2136TSIGAlgorithm string
+	var rfc2136TTL time.Duration
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&singularityv1.SDKServerImage, "sdkserver-image", singularityv1.SDKServerImage,
+		"The image of the SDK sidecar injected into GameServer Pods.")
+	flag.UintVar(&tcpshieldNetworkID, "tcpshield-network-id", 0,
+		"The TCPShield network GameServerIngresses are created in. The API key is read from "+tcpshieldAPIKeyEnv+".")
+	flag.StringVar(&serverListNamespace, "serverlist-namespace", "",
+		"The namespace the server lists of GameServerIngresses are published to as ConfigMaps, for proxy plugins to watch.")
+	flag.StringVar(&serviceNamespace, "service-namespace", "",
+		"The namespace the Services of GameServerIngresses are created in, annotated for external-dns.")
+	flag.StringVar(&serviceType, "service-type", string(corev1.ServiceTypeLoadBalancer),
+		"The type of the Services of GameServerIngresses, LoadBalancer or NodePort.")
+	flag.IntVar(&servicePort, "service-port", service.DefaultPort, "The port the Services of GameServerIngresses listen on.")
+	flag.StringVar(&rfc2136Server, "rfc2136-server", "",
+		"The address of the authoritative DNS server the records of GameServerIngresses are updated at, e.g. ns1.example.com:53.")
+	flag.StringVar(&rfc2136Zone, "rfc2136-zone", "", "The zone containing the hostnames of GameServerIngresses.")
+	flag.StringVar(&rfc2136TSIGKey, "rfc2136-tsig-key", "",
+		"The name of the TSIG key DNS updates are signed with. The secret is read from "+rfc2136TSIGSecretEnv+".")
+	flag.StringVar(&rfc2136TSIGAlgorithm, "rfc2136-tsig-algorithm", dns.HmacSHA256, "The algorithm of the TSIG key.")
+	flag.DurationVar(&rfc2136TTL, "rfc2136-ttl", rfc2136.DefaultTTL, "The TTL of the records of GameServerIngresses.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -129,6 +177,65 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err = allocator.IndexGameServerState(context.Background(), mgr.GetFieldIndexer()); err != nil {
+		setupLog.Error(err, "unable to index gameservers")
+		os.Exit(1)
+	}
+
+	if err = (&gameserverallocation.Reconciler{
+		Client:   mgr.GetClient(),
+		Recorder: mgr.GetEventRecorderFor("gameserverallocation-controller"),
+		Log:      ctrl.Log.WithName("controllers").WithValues("controller", "GameServerAllocation"),
+		Allocator: &allocator.Allocator{
+			Client:      mgr.GetClient(),
+			APIReader:   mgr.GetAPIReader(),
+			NodeCounter: &allocator.ListNodeCounter{Reader: mgr.GetClient(), Indexed: true},
+		},
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GameServerAllocation")
+		os.Exit(1)
+	}
+
+	providers := make(map[string]ingressprovider.Provider)
+	if tcpshieldNetworkID != 0 {
+		provider := tcpshield.CreateProvider(os.Getenv(tcpshieldAPIKeyEnv), uint32(tcpshieldNetworkID))
+		providers["tcpshield"] = ingressprovider.Instrument("tcpshield", provider, 2*time.Minute)
+	}
+
+	// The manager's client would cache all ConfigMaps and Services of the cluster.
+	uncached, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+	if err != nil {
+		setupLog.Error(err, "unable to create client")
+		os.Exit(1)
+	}
+	if serverListNamespace != "" {
+		provider := serverlist.CreateProvider(uncached, serverListNamespace)
+		providers["serverlist"] = ingressprovider.Instrument("serverlist", provider, 10*time.Second)
+	}
+	if serviceNamespace != "" {
+		provider := service.CreateProvider(uncached, serviceNamespace,
+			service.WithType(corev1.ServiceType(serviceType)), service.WithPort(int32(servicePort)))
+		providers["service"] = ingressprovider.Instrument("service", provider, 10*time.Second)
+	}
+	if rfc2136Server != "" {
+		opts := []rfc2136.Option{rfc2136.WithTTL(rfc2136TTL)}
+		if rfc2136TSIGKey != "" {
+			opts = append(opts, rfc2136.WithTSIG(rfc2136TSIGKey, os.Getenv(rfc2136TSIGSecretEnv), rfc2136TSIGAlgorithm))
+		}
+		provider := rfc2136.CreateProvider(rfc2136Server, rfc2136Zone, opts...)
+		providers["rfc2136"] = ingressprovider.Instrument("rfc2136", provider, 10*time.Second)
+	}
+
+	if err = (&gameserveringress.Reconciler{
+		Client:    mgr.GetClient(),
+		Recorder:  mgr.GetEventRecorderFor("gameserveringress-controller"),
+		Log:       ctrl.Log.WithName("controllers").WithValues("controller", "GameServerIngress"),
+		Providers: providers,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GameServerIngress")
+		os.Exit(1)
+	}
+
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,121 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	"innit.gg/singularity/pkg/sdk"
+	"innit.gg/singularity/pkg/sdkserver"
+	"innit.gg/singularity/pkg/sdkserver/sdkpb"
+	"net"
+	"os"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	// to ensure that exec-entrypoint and run can make use of them.
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var setupLog = ctrl.Log.WithName("setup")
+
+func main() {
+	var httpAddr string
+	var grpcAddr string
+	var local string
+	flag.StringVar(&httpAddr, "http-bind-address", fmt.Sprintf("localhost:%d", singularityv1.DefaultSDKServerHTTPPort), "The address the HTTP API binds to.")
+	flag.StringVar(&grpcAddr, "grpc-bind-address", fmt.Sprintf("localhost:%d", singularityv1.DefaultSDKServerGRPCPort), "The address the gRPC API binds to.")
+	flag.StringVar(&local, "local", "", "Serve the GameServer in the YAML file instead of one in the cluster, writing changes back to the file.")
+	opts := zap.Options{
+		Development: true,
+	}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	var s *sdk.SDK
+	var err error
+	if local != "" {
+		s, err = sdk.NewLocal(local)
+	} else {
+		s, err = sdk.New()
+	}
+	if err != nil {
+		setupLog.Error(err, "unable to create sdk")
+		os.Exit(1)
+	}
+
+	server := &sdkserver.Server{SDK: s}
+
+	g, ctx := errgroup.WithContext(ctrl.SetupSignalHandler())
+	g.Go(func() error {
+		return serveHTTP(ctx, server, httpAddr)
+	})
+	g.Go(func() error {
+		return serveGRPC(ctx, server, grpcAddr)
+	})
+
+	setupLog.Info("starting sdk server", "local", local != "")
+	if err = g.Wait(); err != nil {
+		setupLog.Error(err, "problem running sdk server")
+		os.Exit(1)
+	}
+}
+
+func serveHTTP(ctx context.Context, server *sdkserver.Server, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrapf(err, "error listening on %s", addr)
+	}
+
+	app := server.App()
+	go func() {
+		<-ctx.Done()
+		_ = app.Shutdown()
+	}()
+
+	setupLog.Info("serving http", "addr", addr)
+	return app.Listener(ln)
+}
+
+func serveGRPC(ctx context.Context, server *sdkserver.Server, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrapf(err, "error listening on %s", addr)
+	}
+
+	s := grpc.NewServer()
+	sdkpb.RegisterSDKServer(s, server)
+	go func() {
+		<-ctx.Done()
+		// Watches never end on their own, so they aren't waited for
+		s.Stop()
+	}()
+
+	setupLog.Info("serving grpc", "addr", addr)
+	return s.Serve(ln)
+}
@@ -0,0 +1,101 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package allocationpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// AllocationServiceClient is the client API for AllocationService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AllocationServiceClient interface {
+	Allocate(ctx context.Context, in *AllocationRequest, opts ...grpc.CallOption) (*AllocationResponse, error)
+}
+
+type allocationServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAllocationServiceClient(cc grpc.ClientConnInterface) AllocationServiceClient {
+	return &allocationServiceClient{cc}
+}
+
+func (c *allocationServiceClient) Allocate(ctx context.Context, in *AllocationRequest, opts ...grpc.CallOption) (*AllocationResponse, error) {
+	out := new(AllocationResponse)
+	err := c.cc.Invoke(ctx, "/singularity.allocation.v1.AllocationService/Allocate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AllocationServiceServer is the server API for AllocationService service.
+// All implementations must embed UnimplementedAllocationServiceServer
+// for forward compatibility
+type AllocationServiceServer interface {
+	Allocate(context.Context, *AllocationRequest) (*AllocationResponse, error)
+	mustEmbedUnimplementedAllocationServiceServer()
+}
+
+// UnimplementedAllocationServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedAllocationServiceServer struct {
+}
+
+func (UnimplementedAllocationServiceServer) Allocate(context.Context, *AllocationRequest) (*AllocationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Allocate not implemented")
+}
+func (UnimplementedAllocationServiceServer) mustEmbedUnimplementedAllocationServiceServer() {}
+
+// UnsafeAllocationServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AllocationServiceServer will
+// result in compilation errors.
+type UnsafeAllocationServiceServer interface {
+	mustEmbedUnimplementedAllocationServiceServer()
+}
+
+func RegisterAllocationServiceServer(s grpc.ServiceRegistrar, srv AllocationServiceServer) {
+	s.RegisterService(&AllocationService_ServiceDesc, srv)
+}
+
+func _AllocationService_Allocate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AllocationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AllocationServiceServer).Allocate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/singularity.allocation.v1.AllocationService/Allocate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AllocationServiceServer).Allocate(ctx, req.(*AllocationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AllocationService_ServiceDesc is the grpc.ServiceDesc for AllocationService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AllocationService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "singularity.allocation.v1.AllocationService",
+	HandlerType: (*AllocationServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Allocate",
+			Handler:    _AllocationService_Allocate_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "allocation.proto",
+}
@@ -0,0 +1,340 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package allocator
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+)
+
+var (
+	ErrorNoGameServerReady = errors.New("no ready gameserver matches the allocation")
+	ErrorContention        = errors.New("all matching gameservers were allocated concurrently")
+)
+
+// Allocator moves Ready GameServers and GameServerInstances into the Allocated state
+type Allocator struct {
+	client.Client
+	// APIReader bypasses the cache when looking up previous allocations, the Client is used if unset
+	APIReader client.Reader
+	// NodeCounter counts the allocations per node for the scheduling strategies, the Client is listed if unset
+	NodeCounter NodeCounter
+}
+
+// Allocation is the result of a successful allocation
+type Allocation struct {
+	GameServer *singularityv1.GameServer
+	// Instance is only set if a GameServerInstance was allocated
+	Instance *singularityv1.GameServerInstance
+}
+
+// Status returns the GameServerAllocationStatus describing the allocation
+func (a *Allocation) Status() singularityv1.GameServerAllocationStatus {
+	status := singularityv1.GameServerAllocationStatus{
+		State:          singularityv1.GameServerAllocationStateAllocated,
+		GameServerName: a.GameServer.ObjectMeta.Name,
+		Address:        a.GameServer.Status.Address,
+		NodeName:       a.GameServer.Status.NodeName,
+		Ports:          a.GameServer.Status.Ports,
+	}
+	if a.Instance != nil {
+		status.GameServerInstanceName = a.Instance.ObjectMeta.Name
+	}
+
+	return status
+}
+
+// Allocate allocates a single Ready GameServer, or GameServerInstance if requested, matching the allocation.
+// The state is changed using optimistic concurrency, so nothing is ever allocated twice.
+// The allocation metadata is applied before the state is changed, and reverted if the state can't be changed.
+func (a *Allocator) Allocate(ctx context.Context, gsa *singularityv1.GameServerAllocation) (*Allocation, error) {
+	opts, err := gsa.ListOptions()
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing allocation selectors")
+	}
+
+	list := &singularityv1.GameServerList{}
+	if err = a.List(ctx, list, opts...); err != nil {
+		return nil, errors.Wrap(err, "error listing gameservers")
+	}
+
+	counter := a.NodeCounter
+	if counter == nil {
+		counter = &ListNodeCounter{Reader: a.Client}
+	}
+	counts, err := counter.CountNodeAllocations(ctx, gsa.ObjectMeta.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if gsa.Spec.Instance != nil {
+		return a.allocateInstance(ctx, gsa, list, counts)
+	}
+
+	candidates := allocatableGameServers(gsa, list, counts)
+	if len(candidates) == 0 {
+		return nil, ErrorNoGameServerReady
+	}
+
+	for _, gs := range candidates {
+		gsCopy := gs.DeepCopy()
+
+		// Both updates are rejected if the GameServer was changed since it was listed.
+		if err = a.applyMetadata(ctx, gsCopy, gsa); err != nil {
+			if k8serrors.IsConflict(err) || k8serrors.IsNotFound(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "error applying metadata to gameserver %s", gs.ObjectMeta.Name)
+		}
+
+		gsCopy.Allocate()
+		if err = a.Status().Update(ctx, gsCopy); err != nil {
+			a.revertMetadata(ctx, gsCopy, gs, gsa)
+			if k8serrors.IsConflict(err) || k8serrors.IsNotFound(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "error allocating gameserver %s", gs.ObjectMeta.Name)
+		}
+
+		return &Allocation{GameServer: gsCopy}, nil
+	}
+
+	return nil, ErrorContention
+}
+
+// allocateInstance allocates a Ready GameServerInstance of a GameServer in the list,
+// and marks its GameServer as Allocated.
+func (a *Allocator) allocateInstance(ctx context.Context, gsa *singularityv1.GameServerAllocation, list *singularityv1.GameServerList, counts NodeAllocations) (*Allocation, error) {
+	// Instances of servers which are already allocated may still be allocated.
+	parents := make(map[string]*singularityv1.GameServer, len(list.Items))
+	for i := range list.Items {
+		gs := &list.Items[i]
+		if (gs.Status.State == singularityv1.GameServerStateReady || gs.Status.State == singularityv1.GameServerStateAllocated) && !gs.IsBeingDeleted() {
+			parents[gs.ObjectMeta.Name] = gs
+		}
+	}
+
+	instances := &singularityv1.GameServerInstanceList{}
+	if err := a.List(ctx, instances, client.InNamespace(gsa.ObjectMeta.Namespace)); err != nil {
+		return nil, errors.Wrap(err, "error listing gameserverinstances")
+	}
+
+	var candidates []*singularityv1.GameServerInstance
+	for i := range instances.Items {
+		gsInstance := &instances.Items[i]
+		parent, ok := parents[gsInstance.ObjectMeta.Labels[singularityv1.GameServerNameLabel]]
+		if ok && metav1.IsControlledBy(gsInstance, parent) &&
+			gsInstance.Status.State == singularityv1.GameServerInstanceStateReady && gsa.Matches(gsInstance, parent) {
+			candidates = append(candidates, gsInstance)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, ErrorNoGameServerReady
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		pi, pj := parentOf(parents, candidates[i]), parentOf(parents, candidates[j])
+		if a, b := gsa.Preference(pi), gsa.Preference(pj); a != b {
+			return a < b
+		}
+		if c := gsa.ComparePriorities(candidates[i].InheritedCountersAndLists(pi), candidates[j].InheritedCountersAndLists(pj)); c != 0 {
+			return c < 0
+		}
+		if pi == pj {
+			return candidates[i].ObjectMeta.Name < candidates[j].ObjectMeta.Name
+		}
+		return lessByStrategy(pi, pj, counts)
+	})
+
+	for _, gsInstance := range candidates {
+		gsInstanceCopy := gsInstance.DeepCopy()
+
+		// Both updates are rejected if the GameServerInstance was changed since it was listed.
+		if err := a.applyMetadata(ctx, gsInstanceCopy, gsa); err != nil {
+			if k8serrors.IsConflict(err) || k8serrors.IsNotFound(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "error applying metadata to gameserverinstance %s", gsInstance.ObjectMeta.Name)
+		}
+
+		gsInstanceCopy.Status.State = singularityv1.GameServerInstanceStateAllocated
+		if err := a.Status().Update(ctx, gsInstanceCopy); err != nil {
+			a.revertMetadata(ctx, gsInstanceCopy, gsInstance, gsa)
+			if k8serrors.IsConflict(err) || k8serrors.IsNotFound(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "error allocating gameserverinstance %s", gsInstance.ObjectMeta.Name)
+		}
+
+		gs, err := a.markAllocated(ctx, parentOf(parents, gsInstance))
+		if err != nil {
+			a.release(ctx, gsInstanceCopy, func() {
+				gsInstanceCopy.Status.State = singularityv1.GameServerInstanceStateReady
+			})
+			a.revertMetadata(ctx, gsInstanceCopy, gsInstance, gsa)
+			return nil, errors.Wrapf(err, "error allocating gameserver %s of gameserverinstance %s", gs.ObjectMeta.Name, gsInstance.ObjectMeta.Name)
+		}
+
+		return &Allocation{GameServer: gs, Instance: gsInstanceCopy}, nil
+	}
+
+	return nil, ErrorContention
+}
+
+// applyMetadata applies the allocation metadata to the object, and labels it with the allocation's UID.
+// The object's state is stored in the status subresource, so it can't be part of the same update.
+// The metadata is written before the state instead, so an allocated object always carries it.
+// The update is rejected if the object was changed since it was listed.
+func (a *Allocator) applyMetadata(ctx context.Context, obj client.Object, gsa *singularityv1.GameServerAllocation) error {
+	metadata := gsa.Spec.Metadata
+	if metadata == nil && gsa.ObjectMeta.UID == "" {
+		return nil
+	}
+
+	if metadata != nil {
+		metadata.Apply(obj)
+	}
+	if gsa.ObjectMeta.UID != "" {
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = make(map[string]string, 1)
+		}
+		labels[singularityv1.GameServerAllocationLabel] = string(gsa.ObjectMeta.UID)
+		obj.SetLabels(labels)
+	}
+
+	return a.Update(ctx, obj)
+}
+
+// revertMetadata restores the labels and annotations the listed object had before applyMetadata, on a best effort basis.
+// The update is rejected if the object was changed since.
+func (a *Allocator) revertMetadata(ctx context.Context, obj, listed client.Object, gsa *singularityv1.GameServerAllocation) {
+	if gsa.Spec.Metadata == nil && gsa.ObjectMeta.UID == "" {
+		return
+	}
+
+	reverted := listed.DeepCopyObject().(client.Object)
+	reverted.SetResourceVersion(obj.GetResourceVersion())
+	_ = a.Update(ctx, reverted)
+}
+
+// Find returns the GameServer, or GameServerInstance, which was already allocated by the GameServerAllocation.
+// Returns nil if nothing was allocated.
+func (a *Allocator) Find(ctx context.Context, gsa *singularityv1.GameServerAllocation) (*Allocation, error) {
+	reader := a.APIReader
+	if reader == nil {
+		reader = a.Client
+	}
+
+	opts := []client.ListOption{
+		client.InNamespace(gsa.ObjectMeta.Namespace),
+		client.MatchingLabels{singularityv1.GameServerAllocationLabel: string(gsa.ObjectMeta.UID)},
+	}
+
+	if gsa.Spec.Instance == nil {
+		list := &singularityv1.GameServerList{}
+		if err := reader.List(ctx, list, opts...); err != nil {
+			return nil, errors.Wrap(err, "error listing allocated gameservers")
+		}
+		for i := range list.Items {
+			// Allocations which couldn't be completed were released again.
+			if gs := &list.Items[i]; gs.Status.State == singularityv1.GameServerStateAllocated {
+				return &Allocation{GameServer: gs}, nil
+			}
+		}
+
+		return nil, nil
+	}
+
+	instances := &singularityv1.GameServerInstanceList{}
+	if err := reader.List(ctx, instances, opts...); err != nil {
+		return nil, errors.Wrap(err, "error listing allocated gameserverinstances")
+	}
+	for i := range instances.Items {
+		gsInstance := &instances.Items[i]
+		if gsInstance.Status.State != singularityv1.GameServerInstanceStateAllocated {
+			continue
+		}
+
+		gs := &singularityv1.GameServer{}
+		key := client.ObjectKey{Namespace: gsInstance.ObjectMeta.Namespace, Name: gsInstance.ObjectMeta.Labels[singularityv1.GameServerNameLabel]}
+		if err := reader.Get(ctx, key, gs); err != nil {
+			return nil, errors.Wrapf(err, "error getting gameserver of gameserverinstance %s", gsInstance.ObjectMeta.Name)
+		}
+
+		return &Allocation{GameServer: gs, Instance: gsInstance}, nil
+	}
+
+	return nil, nil
+}
+
+// release reverts an allocation which couldn't be completed, on a best effort basis.
+// The update is rejected if the object was changed since it was allocated.
+func (a *Allocator) release(ctx context.Context, obj client.Object, revert func()) {
+	revert()
+	_ = a.Status().Update(ctx, obj)
+}
+
+// markAllocated moves the GameServer into the Allocated state, if it isn't already
+func (a *Allocator) markAllocated(ctx context.Context, gs *singularityv1.GameServer) (*singularityv1.GameServer, error) {
+	gsCopy := gs.DeepCopy()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := a.Get(ctx, client.ObjectKeyFromObject(gs), gsCopy); err != nil {
+			return err
+		}
+
+		if gsCopy.Status.State != singularityv1.GameServerStateReady {
+			return nil
+		}
+
+		gsCopy.Allocate()
+		return a.Status().Update(ctx, gsCopy)
+	})
+
+	return gsCopy, err
+}
+
+// allocatableGameServers returns the GameServers in the list which may be allocated, in the order they should be allocated
+func allocatableGameServers(gsa *singularityv1.GameServerAllocation, list *singularityv1.GameServerList, counts NodeAllocations) []*singularityv1.GameServer {
+	var candidates []*singularityv1.GameServer
+	for i := range list.Items {
+		gs := &list.Items[i]
+		allocatable := gs.Status.State == singularityv1.GameServerStateReady ||
+			(gsa.Spec.AllowAllocated && gs.Status.State == singularityv1.GameServerStateAllocated)
+		if allocatable && !gs.IsBeingDeleted() && gsa.MatchesCountersAndLists(gs.CountersAndLists()) {
+			candidates = append(candidates, gs)
+		}
+	}
+
+	sortGameServers(gsa, candidates, counts)
+
+	return candidates
+}
+
+// parentOf returns the GameServer owning the GameServerInstance
+func parentOf(parents map[string]*singularityv1.GameServer, gsInstance *singularityv1.GameServerInstance) *singularityv1.GameServer {
+	return parents[gsInstance.ObjectMeta.Labels[singularityv1.GameServerNameLabel]]
+}
@@ -0,0 +1,208 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package allocator
+
+import (
+	"context"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(singularityv1.AddToScheme(scheme))
+}
+
+// gameServer returns a Ready GameServer with a "rooms" counter
+func gameServer(name string, rooms int64) *singularityv1.GameServer {
+	gs := &singularityv1.GameServer{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, UID: types.UID(name)},
+		Status:     singularityv1.GameServerStatus{State: singularityv1.GameServerStateReady},
+	}
+	gs.Spec.Counters = map[string]singularityv1.Counter{"rooms": {Count: rooms, Capacity: 4}}
+	return gs
+}
+
+// gameServerInstance returns a Ready GameServerInstance controlled by the GameServer, with a "players" counter
+func gameServerInstance(gs *singularityv1.GameServer, name string, players int64) *singularityv1.GameServerInstance {
+	gsInstance := &singularityv1.GameServerInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "default",
+			Name:            name,
+			Labels:          map[string]string{singularityv1.GameServerNameLabel: gs.ObjectMeta.Name},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(gs, singularityv1.GroupVersion.WithKind("GameServer"))},
+		},
+		Spec:   singularityv1.GameServerInstanceSpec{Capacity: 8},
+		Status: singularityv1.GameServerInstanceStatus{State: singularityv1.GameServerInstanceStateReady},
+	}
+	gsInstance.Spec.Counters = map[string]singularityv1.Counter{"players": {Count: players, Capacity: 8}}
+	return gsInstance
+}
+
+func TestAllocateInstanceCountersAndLists(t *testing.T) {
+	full, empty := gameServer("full", 4), gameServer("empty", 0)
+
+	tests := []struct {
+		name     string
+		counters map[string]singularityv1.CounterSelector
+		want     string
+	}{
+		{
+			name:     "counter of the instance",
+			counters: map[string]singularityv1.CounterSelector{"players": {MinCount: 3}},
+			want:     "full-b",
+		},
+		{
+			name:     "counter of the gameserver",
+			counters: map[string]singularityv1.CounterSelector{"rooms": {MinAvailable: 1}},
+			want:     "empty-a",
+		},
+		{
+			name: "counters of both",
+			counters: map[string]singularityv1.CounterSelector{
+				"rooms":   {MinAvailable: 1},
+				"players": {MinCount: 2},
+			},
+			want: "empty-b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := []client.Object{
+				full.DeepCopy(), gameServerInstance(full, "full-a", 0), gameServerInstance(full, "full-b", 3),
+				empty.DeepCopy(), gameServerInstance(empty, "empty-a", 0), gameServerInstance(empty, "empty-b", 2),
+			}
+			a := &Allocator{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()}
+			gsa := &singularityv1.GameServerAllocation{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default"},
+				Spec: singularityv1.GameServerAllocationSpec{
+					Counters: tt.counters,
+					Instance: &singularityv1.GameServerInstanceAllocation{},
+				},
+			}
+
+			allocation, err := a.Allocate(context.Background(), gsa)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if allocation.Instance == nil || allocation.Instance.ObjectMeta.Name != tt.want {
+				t.Fatalf("allocated %v, want %s", allocation.Instance, tt.want)
+			}
+			if allocation.GameServer.Status.State != singularityv1.GameServerStateAllocated {
+				t.Errorf("gameserver state = %s, want %s", allocation.GameServer.Status.State, singularityv1.GameServerStateAllocated)
+			}
+		})
+	}
+}
+
+func TestAllocateInstanceMarkAllocatedError(t *testing.T) {
+	// The GameServer was deleted after it was listed.
+	gs := gameServer("deleted", 0)
+	gsInstance := gameServerInstance(gs, "deleted-a", 0)
+	a := &Allocator{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(gsInstance).Build()}
+	gsa := &singularityv1.GameServerAllocation{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default"},
+		Spec:       singularityv1.GameServerAllocationSpec{Instance: &singularityv1.GameServerInstanceAllocation{}},
+	}
+	list := &singularityv1.GameServerList{Items: []singularityv1.GameServer{*gs}}
+
+	if _, err := a.allocateInstance(context.Background(), gsa, list, NodeAllocations{}); err == nil {
+		t.Fatal("allocateInstance() succeeded, want error")
+	}
+
+	if err := a.Get(context.Background(), client.ObjectKeyFromObject(gsInstance), gsInstance); err != nil {
+		t.Fatal(err)
+	}
+	if gsInstance.Status.State != singularityv1.GameServerInstanceStateReady {
+		t.Errorf("instance state = %s, want %s", gsInstance.Status.State, singularityv1.GameServerInstanceStateReady)
+	}
+}
+
+func TestAllocateMetadata(t *testing.T) {
+	tests := []struct {
+		name       string
+		contention bool
+		wantState  singularityv1.GameServerState
+		wantLabels map[string]string
+	}{
+		{
+			name:      "allocated",
+			wantState: singularityv1.GameServerStateAllocated,
+			wantLabels: map[string]string{
+				"mode":                                  "ranked",
+				"match":                                 "42",
+				singularityv1.GameServerAllocationLabel: "gsa",
+			},
+		},
+		{
+			// The metadata was written, but the state couldn't be changed
+			name:       "reverted",
+			contention: true,
+			wantState:  singularityv1.GameServerStateReady,
+			wantLabels: map[string]string{"mode": "ranked"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := gameServer("ranked", 0)
+			gs.ObjectMeta.Labels = map[string]string{"mode": "ranked"}
+			s := newServer(tt.contention, gs)
+			gsa := &singularityv1.GameServerAllocation{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", UID: "gsa"},
+				Spec: singularityv1.GameServerAllocationSpec{
+					Metadata: &singularityv1.GameServerAllocationMetadata{
+						Labels:      map[string]string{"match": "42"},
+						Annotations: map[string]string{"map": "dust"},
+					},
+				},
+			}
+
+			_, err := s.Allocator.Allocate(context.Background(), gsa)
+			if tt.contention != (err != nil) {
+				t.Fatalf("Allocate() error = %v, want error %v", err, tt.contention)
+			}
+
+			got := &singularityv1.GameServer{}
+			if err = s.Allocator.Get(context.Background(), client.ObjectKeyFromObject(gs), got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.State != tt.wantState {
+				t.Errorf("state = %s, want %s", got.Status.State, tt.wantState)
+			}
+			if !reflect.DeepEqual(got.ObjectMeta.Labels, tt.wantLabels) {
+				t.Errorf("labels = %v, want %v", got.ObjectMeta.Labels, tt.wantLabels)
+			}
+			if annotated := got.ObjectMeta.Annotations["map"] == "dust"; annotated != !tt.contention {
+				t.Errorf("annotations = %v, want them applied %v", got.ObjectMeta.Annotations, !tt.contention)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package allocator
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcError maps an allocation error to a gRPC status
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrorNamespaceNotServed):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrorNoGameServerReady):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, ErrorContention):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
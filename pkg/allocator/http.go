@@ -0,0 +1,75 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package allocator
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"innit.gg/singularity/pkg/allocator/allocationpb"
+)
+
+// App returns the fiber app serving allocations over HTTP.
+// Requests and responses use the JSON mapping of the allocationpb messages.
+func (s *Server) App() *fiber.App {
+	app := fiber.New(fiber.Config{
+		DisableStartupMessage: true,
+	})
+
+	app.Post("/v1/allocate", s.handleAllocate)
+
+	return app
+}
+
+func (s *Server) handleAllocate(c *fiber.Ctx) error {
+	req := &allocationpb.AllocationRequest{}
+	if err := protojson.Unmarshal(c.Body(), req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	res, err := s.allocate(c.UserContext(), TransportHTTP, req)
+	if err != nil {
+		return fiber.NewError(httpStatus(err), err.Error())
+	}
+
+	body, err := protojson.Marshal(res)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(body)
+}
+
+// httpStatus maps an allocation error to an HTTP status code
+func httpStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrorNamespaceNotServed):
+		return fiber.StatusBadRequest
+	case errors.Is(err, ErrorNoGameServerReady):
+		return fiber.StatusTooManyRequests
+	case errors.Is(err, ErrorContention):
+		return fiber.StatusConflict
+	case errors.Is(err, context.DeadlineExceeded):
+		return fiber.StatusGatewayTimeout
+	default:
+		return fiber.StatusInternalServerError
+	}
+}
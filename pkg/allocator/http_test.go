@@ -0,0 +1,150 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package allocator
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"innit.gg/singularity/pkg/allocator/allocationpb"
+	"io"
+	"net/http/httptest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+	"testing"
+)
+
+func TestHTTPAllocate(t *testing.T) {
+	tests := []struct {
+		name       string
+		contention bool
+		objs       []client.Object
+		body       string
+		wantStatus int
+		want       *allocationpb.AllocationResponse
+	}{
+		{
+			name:       "allocated",
+			objs:       []client.Object{rankedGameServer("casual", "casual"), rankedGameServer("ranked", "ranked")},
+			body:       `{"fleetName": "lobby", "required": {"matchLabels": {"mode": "ranked"}}}`,
+			wantStatus: fiber.StatusOK,
+			want: &allocationpb.AllocationResponse{
+				GameServerName: "ranked",
+				Address:        "10.0.0.1",
+				NodeName:       "node-a",
+				Ports:          []*allocationpb.AllocationResponse_Port{{Name: "game", Port: 7000}},
+			},
+		},
+		{name: "invalid body", body: `{"fleetName": 1}`, wantStatus: fiber.StatusBadRequest},
+		{name: "unknown field", body: `{"fleet": "lobby"}`, wantStatus: fiber.StatusBadRequest},
+		{name: "namespace not served", body: `{"namespace": "games"}`, wantStatus: fiber.StatusBadRequest},
+		{name: "no gameserver ready", body: `{}`, wantStatus: fiber.StatusTooManyRequests},
+		{
+			name:       "no gameserver matches",
+			objs:       []client.Object{rankedGameServer("casual", "casual")},
+			body:       `{"required": {"matchLabels": {"mode": "ranked"}}}`,
+			wantStatus: fiber.StatusTooManyRequests,
+		},
+		{
+			name:       "contention",
+			contention: true,
+			objs:       []client.Object{rankedGameServer("ranked", "ranked")},
+			body:       `{}`,
+			wantStatus: fiber.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newServer(tt.contention, tt.objs...).App()
+
+			req := httptest.NewRequest(fiber.MethodPost, "/v1/allocate", strings.NewReader(tt.body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			res, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", res.StatusCode, tt.wantStatus, body)
+			}
+			if tt.want == nil {
+				return
+			}
+
+			if contentType := res.Header.Get(fiber.HeaderContentType); contentType != fiber.MIMEApplicationJSON {
+				t.Errorf("content type = %q, want %q", contentType, fiber.MIMEApplicationJSON)
+			}
+			got := &allocationpb.AllocationResponse{}
+			if err = protojson.Unmarshal(body, got); err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("response = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPAllocateResponseFields(t *testing.T) {
+	app := newServer(false, rankedGameServer("ranked", "ranked")).App()
+
+	res, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/v1/allocate", strings.NewReader(`{}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Clients decode the JSON mapping of the response, which uses lower camel case names
+	for _, field := range []string{`"gameServerName"`, `"address"`, `"nodeName"`, `"ports"`} {
+		if !strings.Contains(string(body), field) {
+			t.Errorf("response %s is missing %s", body, field)
+		}
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: errors.Wrap(ErrorNamespaceNotServed, "games"), want: fiber.StatusBadRequest},
+		{err: ErrorNoGameServerReady, want: fiber.StatusTooManyRequests},
+		{err: ErrorContention, want: fiber.StatusConflict},
+		{err: errors.Wrap(context.DeadlineExceeded, "error listing gameservers"), want: fiber.StatusGatewayTimeout},
+		{err: errors.New("error listing gameservers"), want: fiber.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		if status := httpStatus(tt.err); status != tt.want {
+			t.Errorf("httpStatus(%v) = %d, want %d", tt.err, status, tt.want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package allocator
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// allocationDuration is the latency of allocation requests, by transport and result
+	allocationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "singularity",
+		Subsystem: "allocator",
+		Name:      "allocation_duration_seconds",
+		Help:      "Latency of allocation requests in seconds",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"transport", "result"})
+
+	// allocationRetries is the amount of retries caused by contention
+	allocationRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "singularity",
+		Subsystem: "allocator",
+		Name:      "allocation_retries_total",
+		Help:      "Total amount of allocation attempts retried due to contention",
+	}, []string{"transport"})
+)
+
+func init() {
+	// Served by the metrics endpoint of the manager
+	metrics.Registry.MustRegister(allocationDuration, allocationRetries)
+}
@@ -0,0 +1,234 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package allocator
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"innit.gg/singularity/pkg/allocator/allocationpb"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"time"
+)
+
+const (
+	TransportHTTP = "http"
+	TransportGRPC = "grpc"
+
+	DefaultTimeout = 10 * time.Second
+)
+
+var (
+	ErrorNamespaceNotServed = errors.New("namespace is not served by this allocator")
+)
+
+// Server exposes the Allocator to clients outside the cluster, over HTTP and gRPC
+type Server struct {
+	allocationpb.UnimplementedAllocationServiceServer
+
+	Allocator *Allocator
+	// Timeout is the maximum duration of a single request, including retries
+	Timeout time.Duration
+	// Namespace is the only namespace allocations are served from, and the default namespace of requests.
+	// All namespaces are served if empty, defaulting to metav1.NamespaceDefault.
+	Namespace string
+}
+
+// Allocate implements allocationpb.AllocationServiceServer
+func (s *Server) Allocate(ctx context.Context, req *allocationpb.AllocationRequest) (*allocationpb.AllocationResponse, error) {
+	res, err := s.allocate(ctx, TransportGRPC, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return res, nil
+}
+
+// allocate allocates a GameServer, retrying as long as the allocation runs into contention
+func (s *Server) allocate(ctx context.Context, transport string, req *allocationpb.AllocationRequest) (*allocationpb.AllocationResponse, error) {
+	start := time.Now()
+
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	namespace, err := s.namespace(req)
+	if err != nil {
+		return nil, err
+	}
+	gsa := allocationFromRequest(req, namespace)
+
+	var allocation *Allocation
+	attempt := 0
+	err = retry.OnError(retry.DefaultRetry, func(err error) bool {
+		// The cache may still contain GameServers which were allocated in the meantime
+		return errors.Is(err, ErrorContention) && ctx.Err() == nil
+	}, func() error {
+		if attempt > 0 {
+			allocationRetries.WithLabelValues(transport).Inc()
+		}
+		attempt++
+
+		var err error
+		allocation, err = s.Allocator.Allocate(ctx, gsa)
+		return err
+	})
+
+	allocationDuration.WithLabelValues(transport, result(err)).Observe(time.Since(start).Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	return responseFromAllocation(allocation), nil
+}
+
+// result returns the metric label of the allocation result
+func result(err error) string {
+	switch {
+	case err == nil:
+		return string(singularityv1.GameServerAllocationStateAllocated)
+	case errors.Is(err, ErrorNoGameServerReady):
+		return string(singularityv1.GameServerAllocationStateUnAllocated)
+	case errors.Is(err, ErrorContention):
+		return string(singularityv1.GameServerAllocationStateContention)
+	case errors.Is(err, context.DeadlineExceeded):
+		return "Timeout"
+	default:
+		return "Error"
+	}
+}
+
+// namespace returns the namespace to allocate from, rejecting namespaces which aren't served
+func (s *Server) namespace(req *allocationpb.AllocationRequest) (string, error) {
+	namespace := req.GetNamespace()
+	switch {
+	case namespace == "" && s.Namespace != "":
+		return s.Namespace, nil
+	case namespace == "":
+		return metav1.NamespaceDefault, nil
+	case s.Namespace != "" && namespace != s.Namespace:
+		return "", errors.Wrap(ErrorNamespaceNotServed, namespace)
+	}
+
+	return namespace, nil
+}
+
+// allocationFromRequest returns the GameServerAllocation described by the request, in the given namespace.
+// The allocation is never created in the cluster.
+func allocationFromRequest(req *allocationpb.AllocationRequest, namespace string) *singularityv1.GameServerAllocation {
+	gsa := &singularityv1.GameServerAllocation{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+		},
+		Spec: singularityv1.GameServerAllocationSpec{
+			FleetName:      req.GetFleetName(),
+			Required:       labelSelector(req.GetRequired()),
+			AllowAllocated: req.GetAllowAllocated(),
+		},
+	}
+
+	for _, selector := range req.GetPreferred() {
+		gsa.Spec.Preferred = append(gsa.Spec.Preferred, labelSelector(selector))
+	}
+
+	for name, selector := range req.GetCounters() {
+		if gsa.Spec.Counters == nil {
+			gsa.Spec.Counters = make(map[string]singularityv1.CounterSelector, len(req.GetCounters()))
+		}
+		gsa.Spec.Counters[name] = singularityv1.CounterSelector{
+			MinCount:     selector.GetMinCount(),
+			MaxCount:     selector.GetMaxCount(),
+			MinAvailable: selector.GetMinAvailable(),
+			MaxAvailable: selector.GetMaxAvailable(),
+		}
+	}
+
+	for name, selector := range req.GetLists() {
+		if gsa.Spec.Lists == nil {
+			gsa.Spec.Lists = make(map[string]singularityv1.ListSelector, len(req.GetLists()))
+		}
+		gsa.Spec.Lists[name] = singularityv1.ListSelector{
+			ContainsValue: selector.GetContainsValue(),
+			MinAvailable:  selector.GetMinAvailable(),
+			MaxAvailable:  selector.GetMaxAvailable(),
+		}
+	}
+
+	for _, priority := range req.GetPriorities() {
+		p := singularityv1.GameServerAllocationPriority{
+			Type:  singularityv1.GameServerAllocationPriorityCounter,
+			Key:   priority.GetKey(),
+			Order: singularityv1.GameServerAllocationOrderAscending,
+		}
+		if priority.GetType() == allocationpb.Priority_LIST {
+			p.Type = singularityv1.GameServerAllocationPriorityList
+		}
+		if priority.GetOrder() == allocationpb.Priority_DESCENDING {
+			p.Order = singularityv1.GameServerAllocationOrderDescending
+		}
+		gsa.Spec.Priorities = append(gsa.Spec.Priorities, p)
+	}
+
+	if instance := req.GetInstance(); instance != nil {
+		gsa.Spec.Instance = &singularityv1.GameServerInstanceAllocation{
+			Map:      instance.GetMap(),
+			Players:  instance.GetPlayers(),
+			Selector: labelSelector(instance.GetSelector()),
+		}
+	}
+
+	if metadata := req.GetMetadata(); metadata != nil {
+		gsa.Spec.Metadata = &singularityv1.GameServerAllocationMetadata{
+			Labels:      metadata.GetLabels(),
+			Annotations: metadata.GetAnnotations(),
+		}
+	}
+
+	return gsa
+}
+
+func labelSelector(selector *allocationpb.LabelSelector) metav1.LabelSelector {
+	return metav1.LabelSelector{
+		MatchLabels: selector.GetMatchLabels(),
+	}
+}
+
+// responseFromAllocation returns the response describing the allocation
+func responseFromAllocation(allocation *Allocation) *allocationpb.AllocationResponse {
+	status := allocation.Status()
+
+	res := &allocationpb.AllocationResponse{
+		GameServerName:         status.GameServerName,
+		GameServerInstanceName: status.GameServerInstanceName,
+		Address:                status.Address,
+		NodeName:               status.NodeName,
+	}
+	for _, port := range status.Ports {
+		res.Ports = append(res.Ports, &allocationpb.AllocationResponse_Port{
+			Name: port.Name,
+			Port: port.Port,
+		})
+	}
+
+	return res
+}
@@ -0,0 +1,224 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package allocator
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"innit.gg/singularity/pkg/allocator/allocationpb"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"net"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+// contentionClient rejects every status update with a conflict, as if all GameServers were allocated concurrently
+type contentionClient struct {
+	client.Client
+}
+
+func (c *contentionClient) Status() client.StatusWriter {
+	return &contentionStatusWriter{StatusWriter: c.Client.Status()}
+}
+
+type contentionStatusWriter struct {
+	client.StatusWriter
+}
+
+func (w *contentionStatusWriter) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	return k8serrors.NewConflict(schema.GroupResource{Group: singularityv1.GroupVersion.Group, Resource: "gameservers"}, obj.GetName(), errors.New("conflict"))
+}
+
+// newServer returns a Server serving the default namespace, backed by a fake client containing the objects.
+// Every allocation runs into contention if contention is set.
+func newServer(contention bool, objs ...client.Object) *Server {
+	var c client.Client = fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	if contention {
+		c = &contentionClient{Client: c}
+	}
+
+	return &Server{Allocator: &Allocator{Client: c}, Namespace: "default"}
+}
+
+// rankedGameServer returns a Ready GameServer of the lobby fleet with a mode label, an address and a port
+func rankedGameServer(name, mode string) *singularityv1.GameServer {
+	gs := gameServer(name, 0)
+	gs.ObjectMeta.Labels = map[string]string{singularityv1.FleetNameLabel: "lobby", "mode": mode}
+	gs.Status.Address = "10.0.0.1"
+	gs.Status.NodeName = "node-a"
+	gs.Status.Ports = []singularityv1.GameServerStatusPort{{Name: "game", Port: 7000}}
+	return gs
+}
+
+// dial serves the Server over gRPC in memory and returns a client connected to it
+func dial(t *testing.T, s *Server) allocationpb.AllocationServiceClient {
+	t.Helper()
+
+	ln := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	allocationpb.RegisterAllocationServiceServer(server, s)
+	go func() {
+		_ = server.Serve(ln)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return ln.Dial()
+		}),
+		grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return allocationpb.NewAllocationServiceClient(conn)
+}
+
+func TestServerNamespace(t *testing.T) {
+	tests := []struct {
+		name      string
+		served    string
+		requested string
+		want      string
+		wantErr   error
+	}{
+		{name: "all namespaces default", want: "default"},
+		{name: "all namespaces requested", requested: "games", want: "games"},
+		{name: "served namespace default", served: "games", want: "games"},
+		{name: "served namespace requested", served: "games", requested: "games", want: "games"},
+		{name: "other namespace requested", served: "games", requested: "default", wantErr: ErrorNamespaceNotServed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{Namespace: tt.served}
+
+			got, err := s.namespace(&allocationpb.AllocationRequest{Namespace: tt.requested})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("namespace() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("namespace() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGRPCAllocate(t *testing.T) {
+	s := newServer(false, rankedGameServer("casual", "casual"), rankedGameServer("ranked", "ranked"))
+	c := dial(t, s)
+
+	res, err := c.Allocate(context.Background(), &allocationpb.AllocationRequest{
+		FleetName: "lobby",
+		Required:  &allocationpb.LabelSelector{MatchLabels: map[string]string{"mode": "ranked"}},
+		Metadata:  &allocationpb.Metadata{Labels: map[string]string{"match": "42"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &allocationpb.AllocationResponse{
+		GameServerName: "ranked",
+		Address:        "10.0.0.1",
+		NodeName:       "node-a",
+		Ports:          []*allocationpb.AllocationResponse_Port{{Name: "game", Port: 7000}},
+	}
+	if !proto.Equal(res, want) {
+		t.Errorf("Allocate() = %v, want %v", res, want)
+	}
+
+	gs := &singularityv1.GameServer{}
+	if err = s.Allocator.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "ranked"}, gs); err != nil {
+		t.Fatal(err)
+	}
+	if gs.Status.State != singularityv1.GameServerStateAllocated {
+		t.Errorf("state = %s, want %s", gs.Status.State, singularityv1.GameServerStateAllocated)
+	}
+	if gs.ObjectMeta.Labels["match"] != "42" {
+		t.Errorf("labels = %v, want match=42", gs.ObjectMeta.Labels)
+	}
+}
+
+func TestGRPCAllocateErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		contention bool
+		objs       []client.Object
+		req        *allocationpb.AllocationRequest
+		want       codes.Code
+	}{
+		{name: "no gameserver ready", req: &allocationpb.AllocationRequest{}, want: codes.ResourceExhausted},
+		{
+			name: "no gameserver matches",
+			objs: []client.Object{rankedGameServer("casual", "casual")},
+			req:  &allocationpb.AllocationRequest{Required: &allocationpb.LabelSelector{MatchLabels: map[string]string{"mode": "ranked"}}},
+			want: codes.ResourceExhausted,
+		},
+		{
+			name:       "contention",
+			contention: true,
+			objs:       []client.Object{rankedGameServer("ranked", "ranked")},
+			req:        &allocationpb.AllocationRequest{},
+			want:       codes.Aborted,
+		},
+		{name: "namespace not served", req: &allocationpb.AllocationRequest{Namespace: "games"}, want: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := dial(t, newServer(tt.contention, tt.objs...))
+
+			_, err := c.Allocate(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.want {
+				t.Errorf("Allocate() code = %s, want %s (%v)", code, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestGRPCError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{err: errors.Wrap(ErrorNamespaceNotServed, "games"), want: codes.InvalidArgument},
+		{err: ErrorNoGameServerReady, want: codes.ResourceExhausted},
+		{err: ErrorContention, want: codes.Aborted},
+		{err: errors.Wrap(context.DeadlineExceeded, "error listing gameservers"), want: codes.DeadlineExceeded},
+		{err: context.Canceled, want: codes.Canceled},
+		{err: errors.New("error listing gameservers"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		if code := status.Code(grpcError(tt.err)); code != tt.want {
+			t.Errorf("grpcError(%v) code = %s, want %s", tt.err, code, tt.want)
+		}
+	}
+}
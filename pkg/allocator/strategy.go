@@ -0,0 +1,134 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package allocator
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"innit.gg/singularity/pkg/apis"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+)
+
+const (
+	// GameServerStateField indexes GameServers by their state, see IndexGameServerState
+	GameServerStateField = "status.state"
+)
+
+// NodeAllocations counts the Allocated GameServers per node
+type NodeAllocations map[string]int32
+
+// NodeCounter counts the Allocated GameServers per node in a namespace, which the scheduling strategies order by.
+// All fleets sharing the nodes count towards their allocations.
+type NodeCounter interface {
+	CountNodeAllocations(ctx context.Context, namespace string) (NodeAllocations, error)
+}
+
+// ListNodeCounter counts the Allocated GameServers by listing them
+type ListNodeCounter struct {
+	client.Reader
+	// Indexed only lists Allocated GameServers, which requires the cache to be indexed using IndexGameServerState
+	Indexed bool
+}
+
+// CountNodeAllocations implements NodeCounter
+func (c *ListNodeCounter) CountNodeAllocations(ctx context.Context, namespace string) (NodeAllocations, error) {
+	opts := []client.ListOption{client.InNamespace(namespace)}
+	if c.Indexed {
+		opts = append(opts, client.MatchingFields{GameServerStateField: string(singularityv1.GameServerStateAllocated)})
+	}
+
+	list := &singularityv1.GameServerList{}
+	if err := c.List(ctx, list, opts...); err != nil {
+		return nil, errors.Wrap(err, "error listing gameservers")
+	}
+
+	return countNodeAllocations(list), nil
+}
+
+// IndexGameServerState indexes GameServers in the cache by GameServerStateField
+func IndexGameServerState(ctx context.Context, indexer client.FieldIndexer) error {
+	return indexer.IndexField(ctx, &singularityv1.GameServer{}, GameServerStateField, func(obj client.Object) []string {
+		return []string{string(obj.(*singularityv1.GameServer).Status.State)}
+	})
+}
+
+// countNodeAllocations counts the Allocated GameServers in the list per node
+func countNodeAllocations(list *singularityv1.GameServerList) NodeAllocations {
+	counts := make(NodeAllocations)
+	for i := range list.Items {
+		gs := &list.Items[i]
+		if gs.Status.NodeName == "" || gs.IsBeingDeleted() {
+			continue
+		}
+
+		if gs.Status.State == singularityv1.GameServerStateAllocated {
+			counts[gs.Status.NodeName]++
+		}
+	}
+
+	return counts
+}
+
+// sortGameServers orders the candidates by the allocation's preferred selectors and priorities first.
+// Ties are broken by the scheduling strategy of the GameServers.
+func sortGameServers(gsa *singularityv1.GameServerAllocation, candidates []*singularityv1.GameServer, counts NodeAllocations) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return less(gsa, candidates[i], candidates[j], counts)
+	})
+}
+
+// less returns whether a should be allocated before b
+func less(gsa *singularityv1.GameServerAllocation, a, b *singularityv1.GameServer, counts NodeAllocations) bool {
+	if pa, pb := gsa.Preference(a), gsa.Preference(b); pa != pb {
+		return pa < pb
+	}
+
+	if c := gsa.ComparePriorities(a.CountersAndLists(), b.CountersAndLists()); c != 0 {
+		return c < 0
+	}
+
+	return lessByStrategy(a, b, counts)
+}
+
+// lessByStrategy returns whether a should be allocated before b, according to their scheduling strategy
+func lessByStrategy(a, b *singularityv1.GameServer, counts NodeAllocations) bool {
+	switch a.Spec.Scheduling {
+	case apis.Packed:
+		// Fill up busy nodes and partially allocated GameServers, so empty nodes can be scaled down.
+		if ca, cb := counts[a.Status.NodeName], counts[b.Status.NodeName]; ca != cb {
+			return ca > cb
+		}
+		if a.Status.AllocatedInstances != b.Status.AllocatedInstances {
+			return a.Status.AllocatedInstances > b.Status.AllocatedInstances
+		}
+	case apis.Distributed:
+		// Spread the load over as many nodes and GameServers as possible.
+		if ca, cb := counts[a.Status.NodeName], counts[b.Status.NodeName]; ca != cb {
+			return ca < cb
+		}
+		if a.Status.AllocatedInstances != b.Status.AllocatedInstances {
+			return a.Status.AllocatedInstances < b.Status.AllocatedInstances
+		}
+	}
+
+	// Keep the order stable between allocations
+	return a.ObjectMeta.Name < b.ObjectMeta.Name
+}
@@ -0,0 +1,175 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package allocator
+
+import (
+	"context"
+	"innit.gg/singularity/pkg/apis"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+// fakeNodeCounter returns fixed allocation counts, like a cache which already contains other fleets
+type fakeNodeCounter NodeAllocations
+
+func (c fakeNodeCounter) CountNodeAllocations(context.Context, string) (NodeAllocations, error) {
+	return NodeAllocations(c), nil
+}
+
+// scheduledGameServer returns a GameServer with the scheduling strategy on the given node
+func scheduledGameServer(name string, scheduling apis.SchedulingStrategy, node string, allocatedInstances int32) *singularityv1.GameServer {
+	gs := gameServer(name, 0)
+	gs.Spec.Scheduling = scheduling
+	gs.Status.NodeName = node
+	gs.Status.AllocatedInstances = allocatedInstances
+	return gs
+}
+
+func TestCountNodeAllocations(t *testing.T) {
+	allocated := func(name, node string) singularityv1.GameServer {
+		gs := scheduledGameServer(name, apis.Packed, node, 0)
+		gs.Status.State = singularityv1.GameServerStateAllocated
+		return *gs
+	}
+	deleted := allocated("deleted", "node-a")
+	now := metav1.Now()
+	deleted.ObjectMeta.DeletionTimestamp = &now
+
+	list := &singularityv1.GameServerList{Items: []singularityv1.GameServer{
+		allocated("a-1", "node-a"),
+		allocated("a-2", "node-a"),
+		allocated("b-1", "node-b"),
+		allocated("unscheduled", ""),
+		*scheduledGameServer("ready", apis.Packed, "node-b", 0),
+		deleted,
+	}}
+
+	counts := countNodeAllocations(list)
+	want := NodeAllocations{"node-a": 2, "node-b": 1}
+	if len(counts) != len(want) || counts["node-a"] != want["node-a"] || counts["node-b"] != want["node-b"] {
+		t.Errorf("countNodeAllocations() = %v, want %v", counts, want)
+	}
+}
+
+func TestLessByStrategy(t *testing.T) {
+	counts := NodeAllocations{"busy": 3, "quiet": 1}
+
+	tests := []struct {
+		name string
+		a, b *singularityv1.GameServer
+		want bool
+	}{
+		{
+			name: "packed prefers busy nodes",
+			a:    scheduledGameServer("a", apis.Packed, "busy", 0),
+			b:    scheduledGameServer("b", apis.Packed, "quiet", 0),
+			want: true,
+		},
+		{
+			name: "packed avoids empty nodes",
+			a:    scheduledGameServer("a", apis.Packed, "empty", 0),
+			b:    scheduledGameServer("b", apis.Packed, "quiet", 0),
+			want: false,
+		},
+		{
+			name: "packed prefers partially allocated servers on the same node",
+			a:    scheduledGameServer("b", apis.Packed, "busy", 2),
+			b:    scheduledGameServer("a", apis.Packed, "busy", 1),
+			want: true,
+		},
+		{
+			name: "distributed prefers quiet nodes",
+			a:    scheduledGameServer("a", apis.Distributed, "busy", 0),
+			b:    scheduledGameServer("b", apis.Distributed, "quiet", 0),
+			want: false,
+		},
+		{
+			name: "distributed prefers empty nodes",
+			a:    scheduledGameServer("b", apis.Distributed, "empty", 0),
+			b:    scheduledGameServer("a", apis.Distributed, "quiet", 0),
+			want: true,
+		},
+		{
+			name: "distributed prefers unallocated servers on the same node",
+			a:    scheduledGameServer("b", apis.Distributed, "quiet", 0),
+			b:    scheduledGameServer("a", apis.Distributed, "quiet", 1),
+			want: true,
+		},
+		{
+			name: "ties are broken by name",
+			a:    scheduledGameServer("a", apis.Packed, "busy", 0),
+			b:    scheduledGameServer("b", apis.Packed, "busy", 0),
+			want: true,
+		},
+		{
+			name: "unknown strategy only orders by name",
+			a:    scheduledGameServer("b", "", "busy", 0),
+			b:    scheduledGameServer("a", "", "quiet", 0),
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lessByStrategy(tt.a, tt.b, counts); got != tt.want {
+				t.Errorf("lessByStrategy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllocateStrategy(t *testing.T) {
+	// The other fleets sharing the nodes are only known to the node counter.
+	counts := fakeNodeCounter{"node-a": 5, "node-b": 1}
+
+	tests := []struct {
+		name       string
+		scheduling apis.SchedulingStrategy
+		want       string
+	}{
+		{name: "packed", scheduling: apis.Packed, want: "on-a"},
+		{name: "distributed", scheduling: apis.Distributed, want: "on-c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := []client.Object{
+				scheduledGameServer("on-b", tt.scheduling, "node-b", 0),
+				scheduledGameServer("on-a", tt.scheduling, "node-a", 0),
+				scheduledGameServer("on-c", tt.scheduling, "node-c", 0),
+			}
+			a := &Allocator{
+				Client:      fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+				NodeCounter: counts,
+			}
+			gsa := &singularityv1.GameServerAllocation{ObjectMeta: metav1.ObjectMeta{Namespace: "default"}}
+
+			allocation, err := a.Allocate(context.Background(), gsa)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if allocation.GameServer.ObjectMeta.Name != tt.want {
+				t.Errorf("allocated %s, want %s", allocation.GameServer.ObjectMeta.Name, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package v1
+
+import (
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrorCounterNotFound   = errors.New("counter not found")
+	ErrorCounterOutOfRange = errors.New("counter out of range")
+	ErrorListNotFound      = errors.New("list not found")
+	ErrorListAtCapacity    = errors.New("list at capacity")
+)
+
+// Counter is a named count with an upper bound, e.g. the amount of rooms hosted by a server
+type Counter struct {
+	Count int64 `json:"count"`
+	//+kubebuilder:validation:Minimum=0
+	Capacity int64 `json:"capacity"`
+}
+
+// Available returns the amount the counter can still be incremented by
+func (c Counter) Available() int64 {
+	return c.Capacity - c.Count
+}
+
+// List is a named set of values with an upper bound, e.g. the spectators of a game
+type List struct {
+	//+kubebuilder:validation:Minimum=0
+	Capacity int64    `json:"capacity"`
+	Values   []string `json:"values,omitempty"`
+}
+
+// Available returns the amount of values that can still be added to the list
+func (l List) Available() int64 {
+	return l.Capacity - int64(len(l.Values))
+}
+
+// Contains returns whether the value is in the list
+func (l List) Contains(value string) bool {
+	for _, v := range l.Values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CountersAndLists are the counters and lists of a GameServer or GameServerInstance.
+// The spec contains their initial values, the status their current ones.
+type CountersAndLists struct {
+	Counters map[string]Counter `json:"counters,omitempty"`
+	Lists    map[string]List    `json:"lists,omitempty"`
+}
+
+// merge returns the current counters and lists, falling back to the initial values of those which weren't changed yet
+func (current *CountersAndLists) merge(initial *CountersAndLists) CountersAndLists {
+	merged := CountersAndLists{}
+	if len(initial.Counters)+len(current.Counters) > 0 {
+		merged.Counters = make(map[string]Counter, len(initial.Counters))
+		for name, counter := range initial.Counters {
+			merged.Counters[name] = counter
+		}
+		for name, counter := range current.Counters {
+			merged.Counters[name] = counter
+		}
+	}
+	if len(initial.Lists)+len(current.Lists) > 0 {
+		merged.Lists = make(map[string]List, len(initial.Lists))
+		for name, list := range initial.Lists {
+			merged.Lists[name] = *list.DeepCopy()
+		}
+		for name, list := range current.Lists {
+			merged.Lists[name] = *list.DeepCopy()
+		}
+	}
+
+	return merged
+}
+
+// updateCounter adds delta to the count of the named counter, keeping it between zero and its capacity
+func (current *CountersAndLists) updateCounter(initial *CountersAndLists, name string, delta int64) error {
+	counter, ok := current.merge(initial).Counters[name]
+	if !ok {
+		return errors.Wrap(ErrorCounterNotFound, name)
+	}
+
+	counter.Count += delta
+	if counter.Count < 0 || counter.Count > counter.Capacity {
+		return errors.Wrapf(ErrorCounterOutOfRange, "%s: %d not within 0 and %d", name, counter.Count, counter.Capacity)
+	}
+
+	if current.Counters == nil {
+		current.Counters = make(map[string]Counter, 1)
+	}
+	current.Counters[name] = counter
+
+	return nil
+}
+
+// appendList adds the values missing from the named list, if it has the capacity for them
+func (current *CountersAndLists) appendList(initial *CountersAndLists, name string, values ...string) error {
+	list, ok := current.merge(initial).Lists[name]
+	if !ok {
+		return errors.Wrap(ErrorListNotFound, name)
+	}
+
+	for _, value := range values {
+		if list.Contains(value) {
+			continue
+		}
+		if list.Available() <= 0 {
+			return errors.Wrap(ErrorListAtCapacity, name)
+		}
+		list.Values = append(list.Values, value)
+	}
+
+	if current.Lists == nil {
+		current.Lists = make(map[string]List, 1)
+	}
+	current.Lists[name] = list
+
+	return nil
+}
+
+// removeList removes the values from the named list
+func (current *CountersAndLists) removeList(initial *CountersAndLists, name string, values ...string) error {
+	list, ok := current.merge(initial).Lists[name]
+	if !ok {
+		return errors.Wrap(ErrorListNotFound, name)
+	}
+
+	remove := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		remove[value] = struct{}{}
+	}
+
+	retained := list.Values[:0]
+	for _, value := range list.Values {
+		if _, ok := remove[value]; !ok {
+			retained = append(retained, value)
+		}
+	}
+	list.Values = retained
+
+	if current.Lists == nil {
+		current.Lists = make(map[string]List, 1)
+	}
+	current.Lists[name] = list
+
+	return nil
+}
+
+// CountersAndLists returns the current counters and lists of the GameServer
+func (gs *GameServer) CountersAndLists() CountersAndLists {
+	return gs.Status.CountersAndLists.merge(&gs.Spec.CountersAndLists)
+}
+
+// UpdateCounter adds delta to the count of the named counter, which has to stay between zero and its capacity
+func (gs *GameServer) UpdateCounter(name string, delta int64) error {
+	return gs.Status.CountersAndLists.updateCounter(&gs.Spec.CountersAndLists, name, delta)
+}
+
+// AppendList adds the values to the named list, which can't exceed its capacity
+func (gs *GameServer) AppendList(name string, values ...string) error {
+	return gs.Status.CountersAndLists.appendList(&gs.Spec.CountersAndLists, name, values...)
+}
+
+// RemoveList removes the values from the named list
+func (gs *GameServer) RemoveList(name string, values ...string) error {
+	return gs.Status.CountersAndLists.removeList(&gs.Spec.CountersAndLists, name, values...)
+}
+
+// CountersAndLists returns the current counters and lists of the GameServerInstance
+func (gsInstance *GameServerInstance) CountersAndLists() CountersAndLists {
+	return gsInstance.Status.CountersAndLists.merge(&gsInstance.Spec.CountersAndLists)
+}
+
+// InheritedCountersAndLists returns the current counters and lists of the instance,
+// falling back to those of its GameServer which the instance doesn't have itself
+func (gsInstance *GameServerInstance) InheritedCountersAndLists(gs *GameServer) CountersAndLists {
+	current, inherited := gsInstance.CountersAndLists(), gs.CountersAndLists()
+	return current.merge(&inherited)
+}
+
+// UpdateCounter adds delta to the count of the named counter, which has to stay between zero and its capacity
+func (gsInstance *GameServerInstance) UpdateCounter(name string, delta int64) error {
+	return gsInstance.Status.CountersAndLists.updateCounter(&gsInstance.Spec.CountersAndLists, name, delta)
+}
+
+// AppendList adds the values to the named list, which can't exceed its capacity
+func (gsInstance *GameServerInstance) AppendList(name string, values ...string) error {
+	return gsInstance.Status.CountersAndLists.appendList(&gsInstance.Spec.CountersAndLists, name, values...)
+}
+
+// RemoveList removes the values from the named list
+func (gsInstance *GameServerInstance) RemoveList(name string, values ...string) error {
+	return gsInstance.Status.CountersAndLists.removeList(&gsInstance.Spec.CountersAndLists, name, values...)
+}
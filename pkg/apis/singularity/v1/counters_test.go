@@ -0,0 +1,124 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package v1
+
+import (
+	"github.com/pkg/errors"
+	"reflect"
+	"testing"
+)
+
+func TestUpdateCounter(t *testing.T) {
+	tests := []struct {
+		name    string
+		delta   int64
+		counter string
+		want    int64
+		wantErr error
+	}{
+		{name: "increment from the initial count", counter: "rooms", delta: 2, want: 3},
+		{name: "decrement to zero", counter: "rooms", delta: -1, want: 0},
+		{name: "increment to capacity", counter: "rooms", delta: 3, want: 4},
+		{name: "exceed capacity", counter: "rooms", delta: 4, wantErr: ErrorCounterOutOfRange},
+		{name: "below zero", counter: "rooms", delta: -2, wantErr: ErrorCounterOutOfRange},
+		{name: "missing counter", counter: "players", delta: 1, wantErr: ErrorCounterNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := &GameServer{}
+			gs.Spec.CountersAndLists.Counters = map[string]Counter{"rooms": {Count: 1, Capacity: 4}}
+
+			err := gs.UpdateCounter(tt.counter, tt.delta)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateCounter() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if gs.Status.CountersAndLists.Counters != nil {
+					t.Errorf("status counters = %v, want unchanged", gs.Status.CountersAndLists.Counters)
+				}
+				return
+			}
+			if count := gs.CountersAndLists().Counters[tt.counter].Count; count != tt.want {
+				t.Errorf("count = %d, want %d", count, tt.want)
+			}
+			if spec := gs.Spec.CountersAndLists.Counters["rooms"].Count; spec != 1 {
+				t.Errorf("spec count = %d, want the initial 1", spec)
+			}
+		})
+	}
+}
+
+func TestAppendList(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    string
+		values  []string
+		want    []string
+		wantErr error
+	}{
+		{name: "append to the initial values", list: "spectators", values: []string{"b"}, want: []string{"a", "b"}},
+		{name: "skip existing values", list: "spectators", values: []string{"a", "b"}, want: []string{"a", "b"}},
+		{name: "fill capacity", list: "spectators", values: []string{"b", "c"}, want: []string{"a", "b", "c"}},
+		{name: "exceed capacity", list: "spectators", values: []string{"b", "c", "d"}, wantErr: ErrorListAtCapacity},
+		{name: "missing list", list: "players", values: []string{"b"}, wantErr: ErrorListNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := &GameServer{}
+			gs.Spec.CountersAndLists.Lists = map[string]List{"spectators": {Capacity: 3, Values: []string{"a"}}}
+
+			err := gs.AppendList(tt.list, tt.values...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AppendList() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if gs.Status.CountersAndLists.Lists != nil {
+					t.Errorf("status lists = %v, want unchanged", gs.Status.CountersAndLists.Lists)
+				}
+				return
+			}
+			if values := gs.CountersAndLists().Lists[tt.list].Values; !reflect.DeepEqual(values, tt.want) {
+				t.Errorf("values = %v, want %v", values, tt.want)
+			}
+			if spec := gs.Spec.CountersAndLists.Lists["spectators"].Values; !reflect.DeepEqual(spec, []string{"a"}) {
+				t.Errorf("spec values = %v, want the initial [a]", spec)
+			}
+		})
+	}
+}
+
+func TestRemoveList(t *testing.T) {
+	gs := &GameServer{}
+	gs.Spec.CountersAndLists.Lists = map[string]List{"spectators": {Capacity: 3, Values: []string{"a", "b", "c"}}}
+
+	if err := gs.RemoveList("spectators", "b", "d"); err != nil {
+		t.Fatal(err)
+	}
+	if values := gs.CountersAndLists().Lists["spectators"].Values; !reflect.DeepEqual(values, []string{"a", "c"}) {
+		t.Errorf("values = %v, want [a c]", values)
+	}
+	if spec := gs.Spec.CountersAndLists.Lists["spectators"].Values; !reflect.DeepEqual(spec, []string{"a", "b", "c"}) {
+		t.Errorf("spec values = %v, want the initial [a b c]", spec)
+	}
+	if err := gs.RemoveList("players", "a"); !errors.Is(err, ErrorListNotFound) {
+		t.Errorf("RemoveList() error = %v, want %v", err, ErrorListNotFound)
+	}
+}
@@ -24,12 +24,30 @@ import (
 	"innit.gg/singularity/pkg/apis/singularity"
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"math"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
 )
 
 const (
 	// FleetNameLabel is the name of Fleet which owns resources like GameServerSet and GameServer
 	FleetNameLabel = singularity.GroupName + "/fleet"
+
+	// FleetPromoteAnnotation advances a canary rollout past its current step when present on a Fleet
+	FleetPromoteAnnotation = singularity.GroupName + "/promote"
+
+	// DefaultRevisionHistoryLimit is the amount of inactive GameServerSets retained if not specified by the Fleet
+	DefaultRevisionHistoryLimit = 10
+	// DefaultProgressDeadlineSeconds is the progress deadline of a rollout if not specified by the Fleet
+	DefaultProgressDeadlineSeconds = 600
+
+	// FleetConditionProgressing indicates that the Fleet is rolling out a template, or scaling its GameServerSets
+	FleetConditionProgressing = "Progressing"
+	// FleetConditionAvailable indicates that the Fleet has its minimum amount of available GameServers
+	FleetConditionAvailable = "Available"
+	// FleetConditionReplicaFailure indicates that the Fleet failed to create or update a GameServerSet
+	FleetConditionReplicaFailure = "ReplicaFailure"
 )
 
 //+kubebuilder:object:root=true
@@ -38,6 +56,7 @@ const (
 //+kubebuilder:printcolumn:name="Scheduling",type=string,JSONPath=`.spec.scheduling`
 //+kubebuilder:printcolumn:name="Desired",type=string,JSONPath=`.spec.replicas`
 //+kubebuilder:printcolumn:name="Current",type=string,JSONPath=`.status.replicas`
+//+kubebuilder:printcolumn:name="Updated",type=string,JSONPath=`.status.updatedReplicas`
 //+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Fleet is the Schema for the fleets API
@@ -64,16 +83,96 @@ type FleetSpec struct {
 	Strategy   appsv1.DeploymentStrategy `json:"strategy"`
 	Scheduling apis.SchedulingStrategy   `json:"scheduling"`
 	Template   GameServerTemplate        `json:"template"`
+
+	// RevisionHistoryLimit is the amount of inactive GameServerSets to retain for rollbacks.
+	// Defaults to DefaultRevisionHistoryLimit.
+	//+optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// RollbackTo requests the Fleet to be rolled back to a previous revision.
+	// It is cleared by the controller once the rollback has been applied.
+	//+optional
+	RollbackTo *FleetRollbackConfig `json:"rollbackTo,omitempty"`
+
+	// Paused holds an ongoing rollout where it is, and prevents new rollouts from being started.
+	// The Fleet can still be scaled while paused.
+	//+optional
+	Paused bool `json:"paused,omitempty"`
+
+	// CanarySteps are applied in order whenever a new template is rolled out.
+	// Once all steps are complete, the rollout continues according to the deployment strategy.
+	//+optional
+	CanarySteps []FleetCanaryStep `json:"canarySteps,omitempty"`
+
+	// ProgressDeadlineSeconds is the maximum time a rollout may go without progress,
+	// before it is reported as failed using the Progressing condition.
+	// Defaults to DefaultProgressDeadlineSeconds.
+	//+optional
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+}
+
+// FleetCanaryStep limits the share of replicas running the new template during a rollout
+type FleetCanaryStep struct {
+	// Weight is the percentage of the Fleet's replicas which should run the new template
+	//+kubebuilder:validation:Minimum=0
+	//+kubebuilder:validation:Maximum=100
+	Weight int32 `json:"weight"`
+
+	// Pause is how long the step is held once its replicas are available.
+	// If unset, the step is held until the Fleet is promoted using FleetPromoteAnnotation.
+	//+optional
+	Pause *metav1.Duration `json:"pause,omitempty"`
+}
+
+// FleetRollbackConfig describes the revision a Fleet should be rolled back to
+type FleetRollbackConfig struct {
+	// Revision to roll back to. If set to 0, the Fleet is rolled back to the previous revision.
+	Revision int64 `json:"revision,omitempty"`
 }
 
 // FleetStatus defines the observed state of Fleet
 type FleetStatus struct {
-	Replicas           int32 `json:"replicas"`
-	ReadyReplicas      int32 `json:"readyReplicas"`
-	AllocatedReplicas  int32 `json:"allocatedReplicas"`
+	// ObservedGeneration is the most recent generation of the Fleet observed by the controller
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	Replicas          int32 `json:"replicas"`
+	ReadyReplicas     int32 `json:"readyReplicas"`
+	AllocatedReplicas int32 `json:"allocatedReplicas"`
+	ReservedReplicas  int32 `json:"reservedReplicas"`
+	// UpdatedReplicas is the amount of GameServers running the Fleet's current template
+	UpdatedReplicas    int32 `json:"updatedReplicas"`
 	Instances          int32 `json:"instances"`
 	ReadyInstances     int32 `json:"readyInstances"`
 	AllocatedInstances int32 `json:"allocatedInstances"`
+
+	// LabelSelector is the serialized label selector of GameServers owned by this Fleet.
+	// This is required by the scale subresource, so that autoscalers can find the Fleet's Pods.
+	LabelSelector string `json:"labelSelector,omitempty"`
+
+	// Revision is the revision of the active GameServerSet
+	Revision int64 `json:"revision,omitempty"`
+
+	// CurrentStep is the index of the canary step the ongoing rollout is at.
+	// It equals the amount of canary steps once all of them are complete, and is unset if there is no ongoing rollout.
+	//+optional
+	CurrentStep *int32 `json:"currentStep,omitempty"`
+
+	// CurrentStepStartTime is the time the current canary step was entered
+	//+optional
+	CurrentStepStartTime *metav1.Time `json:"currentStepStartTime,omitempty"`
+
+	// CurrentStepAvailableTime is the time the replicas of the current canary step became available.
+	// The pause of the step is counted from it, it is unset while they aren't available yet.
+	//+optional
+	CurrentStepAvailableTime *metav1.Time `json:"currentStepAvailableTime,omitempty"`
+
+	// Conditions represent the latest observations of the Fleet's state
+	//+optional
+	//+patchMergeKey=type
+	//+patchStrategy=merge
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 }
 
 // GameServerSet returns a single GameServerSet for this Fleet definition
@@ -97,16 +196,51 @@ func (f *Fleet) GameServerSet() *GameServerSet {
 	ref := metav1.NewControllerRef(f, GroupVersion.WithKind("Fleet"))
 	gsSet.ObjectMeta.OwnerReferences = append(gsSet.ObjectMeta.OwnerReferences, *ref)
 
-	// Append Fleet name
+	// Append Fleet name and template hash labels
 	if gsSet.ObjectMeta.Labels == nil {
-		gsSet.ObjectMeta.Labels = make(map[string]string, 1)
+		gsSet.ObjectMeta.Labels = make(map[string]string, 2)
 	}
 
 	gsSet.ObjectMeta.Labels[FleetNameLabel] = f.ObjectMeta.Name
+	gsSet.ObjectMeta.Labels[TemplateHashLabel] = f.Spec.Template.Hash()
 
 	return gsSet
 }
 
+// GetRevisionHistoryLimit returns the amount of inactive GameServerSets which should be retained
+func (f *Fleet) GetRevisionHistoryLimit() int32 {
+	if f.Spec.RevisionHistoryLimit == nil {
+		return DefaultRevisionHistoryLimit
+	}
+	return *f.Spec.RevisionHistoryLimit
+}
+
+// GetProgressDeadline returns the maximum time a rollout may go without progress
+func (f *Fleet) GetProgressDeadline() time.Duration {
+	if f.Spec.ProgressDeadlineSeconds == nil {
+		return DefaultProgressDeadlineSeconds * time.Second
+	}
+	return time.Duration(*f.Spec.ProgressDeadlineSeconds) * time.Second
+}
+
+// CanaryReplicas returns the maximum amount of replicas running the new template at the given canary step.
+// Returns false if the step doesn't limit the rollout.
+func (f *Fleet) CanaryReplicas(step *int32) (int32, bool) {
+	if step == nil || *step < 0 || int(*step) >= len(f.Spec.CanarySteps) {
+		return 0, false
+	}
+
+	weight := f.Spec.CanarySteps[*step].Weight
+	return int32(math.Ceil(float64(f.Spec.Replicas) * float64(weight) / 100)), true
+}
+
+// LabelSelector returns the serialized label selector matching all resources owned by this Fleet
+func (f *Fleet) LabelSelector() string {
+	return labels.SelectorFromSet(labels.Set{
+		FleetNameLabel: f.ObjectMeta.Name,
+	}).String()
+}
+
 // ListGameServerSet lists all owned GameServerSet
 func (f *Fleet) ListGameServerSet(ctx context.Context, c client.Client) ([]*GameServerSet, error) {
 	list := &GameServerSetList{}
@@ -153,6 +287,18 @@ func CountStatusAllocatedReplicas(list []*GameServerSet) int32 {
 	return total
 }
 
+// CountStatusReservedReplicas returns the count of GameServer with GameServerStateReserved in a list of GameServerSet
+func CountStatusReservedReplicas(list []*GameServerSet) int32 {
+	total := int32(0)
+	for _, gsSet := range list {
+		if gsSet != nil {
+			total += gsSet.Status.ReservedReplicas
+		}
+	}
+
+	return total
+}
+
 func CountStatusReplicas(list []*GameServerSet) int32 {
 	total := int32(0)
 	for _, gsSet := range list {
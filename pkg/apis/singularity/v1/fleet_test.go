@@ -0,0 +1,60 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package v1
+
+import (
+	"testing"
+)
+
+func TestFleetCanaryReplicas(t *testing.T) {
+	steps := []FleetCanaryStep{{Weight: 10}, {Weight: 50}, {Weight: 100}, {Weight: 0}}
+
+	tests := []struct {
+		name       string
+		replicas   int32
+		step       *int32
+		want       int32
+		wantCanary bool
+	}{
+		{name: "no step", replicas: 10, step: nil},
+		{name: "negative step", replicas: 10, step: int32Ptr(-1)},
+		{name: "completed steps", replicas: 10, step: int32Ptr(4)},
+		{name: "exact share", replicas: 10, step: int32Ptr(0), want: 1, wantCanary: true},
+		{name: "rounded up share", replicas: 3, step: int32Ptr(0), want: 1, wantCanary: true},
+		{name: "half", replicas: 5, step: int32Ptr(1), want: 3, wantCanary: true},
+		{name: "full", replicas: 7, step: int32Ptr(2), want: 7, wantCanary: true},
+		{name: "zero weight", replicas: 7, step: int32Ptr(3), want: 0, wantCanary: true},
+		{name: "no replicas", replicas: 0, step: int32Ptr(1), want: 0, wantCanary: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fleet{Spec: FleetSpec{Replicas: tt.replicas, CanarySteps: steps}}
+
+			got, canary := f.CanaryReplicas(tt.step)
+			if got != tt.want || canary != tt.wantCanary {
+				t.Errorf("CanaryReplicas() = %d, %v, want %d, %v", got, canary, tt.want, tt.wantCanary)
+			}
+		})
+	}
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
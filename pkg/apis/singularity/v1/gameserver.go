@@ -19,13 +19,19 @@
 package v1
 
 import (
+	"encoding/json"
 	"fmt"
+	"github.com/pkg/errors"
+	"hash/fnv"
 	"innit.gg/singularity/pkg/apis"
 	"innit.gg/singularity/pkg/apis/singularity"
 	v1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
 	"sort"
+	"strconv"
+	"time"
 )
 
 const (
@@ -46,6 +52,9 @@ const (
 	GameServerStateRequestReady GameServerState = "RequestReady"
 	// GameServerStateReady indicates that the server is ready to accept player (and optionally Allocated)
 	GameServerStateReady GameServerState = "Ready"
+	// GameServerStateReserved indicates that the server is held for a limited time. It can neither be allocated
+	// nor removed by scaling, and becomes Ready again once GameServerStatus.ReservedUntil passes.
+	GameServerStateReserved GameServerState = "Reserved"
 	// GameServerStateAllocated indicates that the server has been allocated and shall not be removed
 	GameServerStateAllocated GameServerState = "Allocated"
 	// GameServerStateDrain indicates the server is no longer accepting new players, and is waiting for existing
@@ -61,14 +70,43 @@ const (
 	// GameServerRole is the GameServer label value for singularity.RoleLabel
 	GameServerRole = "gameserver"
 	// GameServerNameLabel is the name of GameServer which owns resources like v1.Pod
-	GameServerNameLabel = singularity.GroupName + "/fleet"
+	GameServerNameLabel = singularity.GroupName + "/gameserver"
+	// LegacyGameServerNameLabel is the previous key of GameServerNameLabel. It collided with FleetNameLabel,
+	// so resources created before carry the GameServer name as their Fleet name. The GameServer controller relabels them.
+	// Deprecated: remove once all resources were relabelled.
+	LegacyGameServerNameLabel = singularity.GroupName + "/fleet"
 
 	// GameServerEnvNamespace is the namespace of GameServer which owns the pod
 	GameServerEnvNamespace = "SINGULARITY_GAMESERVER_NAMESPACE"
 	// GameServerEnvName is the name of GameServer which owns the pod
 	GameServerEnvName = "SINGULARITY_GAMESERVER_NAME"
+
+	// DefaultHealthPeriodSeconds is the default interval servers are expected to report their health in
+	DefaultHealthPeriodSeconds = 5
+	// DefaultHealthFailureThreshold is the default amount of missed health reports before a server is Unhealthy
+	DefaultHealthFailureThreshold = 3
+
+	// GameServerMetadataVolume is the name of the downward API volume exposing the labels and annotations of the Pod
+	GameServerMetadataVolume = "singularity-metadata"
+	// GameServerMetadataPath is where the GameServerMetadataVolume is mounted, containing a labels and annotations file
+	GameServerMetadataPath = "/etc/singularity/metadata"
+
+	// SDKServerContainerName is the name of the SDK sidecar container injected into the Pod
+	SDKServerContainerName = "singularity-sdkserver"
+	// SDKServerEnvHTTPPort is the localhost port of the SDK sidecar's HTTP API
+	SDKServerEnvHTTPPort = "SINGULARITY_SDK_HTTP_PORT"
+	// SDKServerEnvGRPCPort is the localhost port of the SDK sidecar's gRPC API
+	SDKServerEnvGRPCPort = "SINGULARITY_SDK_GRPC_PORT"
+	// DefaultSDKServerHTTPPort is the default port of the SDK sidecar's HTTP API
+	DefaultSDKServerHTTPPort = 9358
+	// DefaultSDKServerGRPCPort is the default port of the SDK sidecar's gRPC API
+	DefaultSDKServerGRPCPort = 9357
 )
 
+// SDKServerImage is the image of the SDK sidecar, unless overridden by the GameServer.
+// It is set by the operator.
+var SDKServerImage = "singularity-sdkserver:latest"
+
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.state`
@@ -109,14 +147,98 @@ type GameServerSpec struct {
 	Instances        int32                      `json:"instances"`
 	InstanceTemplate GameServerInstanceTemplate `json:"instanceTemplate"`
 	Template         v1.PodTemplateSpec         `json:"template"`
+
+	// RecyclePolicy shuts the server down instead of returning it to Ready, once it was used often or long enough
+	//+optional
+	RecyclePolicy *GameServerRecyclePolicy `json:"recyclePolicy,omitempty"`
+
+	// Health marks the server Unhealthy once it stops reporting its health, health checks are disabled if unset
+	//+optional
+	Health *GameServerHealth `json:"health,omitempty"`
+
+	// SDKServer configures the SDK sidecar injected into the Pod
+	//+optional
+	SDKServer *GameServerSDKServer `json:"sdkServer,omitempty"`
+
+	// CountersAndLists are the initial counters and lists of the server
+	CountersAndLists `json:",inline"`
+}
+
+// GameServerSDKServer configures the SDK sidecar, which exposes the SDK to the server over localhost
+type GameServerSDKServer struct {
+	// Disabled doesn't inject the sidecar, e.g. if the server uses the Go SDK directly
+	Disabled bool `json:"disabled,omitempty"`
+	// Image overrides the image of the sidecar
+	Image string `json:"image,omitempty"`
+	// HTTPPort is the localhost port of the HTTP API
+	//+kubebuilder:default=9358
+	HTTPPort int32 `json:"httpPort,omitempty"`
+	// GRPCPort is the localhost port of the gRPC API
+	//+kubebuilder:default=9357
+	GRPCPort int32 `json:"grpcPort,omitempty"`
 }
 
+// GameServerHealth configures the health checks of a GameServer, which are reported through the SDK
+type GameServerHealth struct {
+	// InitialDelaySeconds is the time after the server first became Ready before health checks are enforced
+	//+kubebuilder:default=5
+	InitialDelaySeconds int32 `json:"initialDelaySeconds,omitempty"`
+	// PeriodSeconds is the interval the server is expected to report its health in
+	//+kubebuilder:default=5
+	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+	// FailureThreshold is the amount of missed periods after which the server is Unhealthy
+	//+kubebuilder:default=3
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+}
+
+// GameServerRecyclePolicy limits the reuse of a GameServer across allocations
+type GameServerRecyclePolicy struct {
+	// MaxAllocations is the amount of allocations after which the server is shut down, unlimited if zero
+	//+kubebuilder:validation:Minimum=0
+	MaxAllocations int32 `json:"maxAllocations,omitempty"`
+	// MaxLifetime is the age after which the server is shut down once it isn't allocated anymore
+	MaxLifetime *metav1.Duration `json:"maxLifetime,omitempty"`
+}
+
+var ErrorInvalidStateTransition = errors.New("invalid state transition")
+
 type GameServerType string
 type GameServerState string
 
 // GameServerStatus defines the observed state of GameServer
 type GameServerStatus struct {
 	State GameServerState `json:"state"`
+
+	// Address is the address players can connect to, populated once the server is Ready
+	Address string `json:"address,omitempty"`
+	// NodeName is the name of the Node the server is running on
+	NodeName string `json:"nodeName,omitempty"`
+	// Ports are the resolved ports of GameServerSpec.Ports
+	Ports []GameServerStatusPort `json:"ports,omitempty"`
+	// ReservedUntil is the time a Reserved server returns to Ready, it is reserved indefinitely if unset
+	ReservedUntil *metav1.Time `json:"reservedUntil,omitempty"`
+	// ReadyRequestedFrom is the state the server requested to be Ready from, it is restored if the request is rejected.
+	// Status patches setting the RequestReady state have to set it as well, see CanRequestReady.
+	ReadyRequestedFrom GameServerState `json:"readyRequestedFrom,omitempty"`
+	// Allocations is the amount of times the server has been allocated
+	Allocations int32 `json:"allocations,omitempty"`
+	// FirstReady is the time the server first became Ready, health checks are only enforced from then on
+	FirstReady *metav1.Time `json:"firstReady,omitempty"`
+	// LastHealthy is the last time the server reported its health
+	LastHealthy *metav1.Time `json:"lastHealthy,omitempty"`
+
+	// CountersAndLists are the counters and lists which changed since the server was created
+	CountersAndLists `json:",inline"`
+
+	Instances          int32 `json:"instances,omitempty"`
+	ReadyInstances     int32 `json:"readyInstances,omitempty"`
+	AllocatedInstances int32 `json:"allocatedInstances,omitempty"`
+}
+
+// GameServerStatusPort is a resolved GameServerPort
+type GameServerStatusPort struct {
+	Name string `json:"name"`
+	Port int32  `json:"port"`
 }
 
 type GameServerDrainStrategy struct {
@@ -132,16 +254,148 @@ type GameServerPort struct {
 	ContainerPort string          `json:"containerPort"`
 }
 
+// Hash returns a stable hash of the normalized template, similar to the pod-template-hash label of ReplicaSets.
+// Templates which only differ in fields defaulted by the API server or the controllers have the same hash.
+func (t *GameServerTemplate) Hash() string {
+	template := t.DeepCopy()
+	delete(template.ObjectMeta.Labels, TemplateHashLabel)
+	template.normalize()
+
+	hasher := fnv.New32a()
+	_ = json.NewEncoder(hasher).Encode(template)
+
+	return rand.SafeEncodeString(fmt.Sprint(hasher.Sum32()))
+}
+
+// normalize resets fields which are equal to their default to their zero value
+func (t *GameServerTemplate) normalize() {
+	if len(t.Spec.Ports) == 0 {
+		t.Spec.Ports = nil
+	}
+
+	if sdkServer := t.Spec.SDKServer; sdkServer != nil {
+		if sdkServer.HTTPPort == DefaultSDKServerHTTPPort {
+			sdkServer.HTTPPort = 0
+		}
+		if sdkServer.GRPCPort == DefaultSDKServerGRPCPort {
+			sdkServer.GRPCPort = 0
+		}
+		if *sdkServer == (GameServerSDKServer{}) {
+			t.Spec.SDKServer = nil
+		}
+	}
+
+	if health := t.Spec.Health; health != nil {
+		if health.PeriodSeconds == DefaultHealthPeriodSeconds {
+			health.PeriodSeconds = 0
+		}
+		if health.FailureThreshold == DefaultHealthFailureThreshold {
+			health.FailureThreshold = 0
+		}
+	}
+}
+
 // IsDeletable returns whether the server is currently allocated/reserved and is not already in the
 // process of being deleted
 func (gs *GameServer) IsDeletable() bool {
-	if gs.Status.State == GameServerStateAllocated {
+	if gs.Status.State == GameServerStateAllocated || gs.Status.State == GameServerStateReserved {
 		return !gs.ObjectMeta.DeletionTimestamp.IsZero()
 	}
 
 	return true
 }
 
+// Allocate moves the server into the Allocated state and counts the allocation
+func (gs *GameServer) Allocate() {
+	gs.Status.State = GameServerStateAllocated
+	gs.Status.Allocations++
+}
+
+// IsRetired returns whether the server should be shut down instead of becoming Ready, according to its RecyclePolicy
+func (gs *GameServer) IsRetired(now time.Time) bool {
+	policy := gs.Spec.RecyclePolicy
+	if policy == nil {
+		return false
+	}
+
+	if policy.MaxAllocations > 0 && gs.Status.Allocations >= policy.MaxAllocations {
+		return true
+	}
+
+	return policy.MaxLifetime != nil && gs.RetiresIn(now) <= 0
+}
+
+// RetiresIn returns the remaining lifetime of the server, or zero if its lifetime isn't limited
+func (gs *GameServer) RetiresIn(now time.Time) time.Duration {
+	policy := gs.Spec.RecyclePolicy
+	if policy == nil || policy.MaxLifetime == nil {
+		return 0
+	}
+
+	return gs.ObjectMeta.CreationTimestamp.Add(policy.MaxLifetime.Duration).Sub(now)
+}
+
+// HealthDeadline returns the time the server is considered Unhealthy if it doesn't report its health,
+// and whether health checks are enforced. They are only enforced once the server was Ready, as servers
+// may take longer to start than their health check period.
+func (gs *GameServer) HealthDeadline() (time.Time, bool) {
+	health := gs.Spec.Health
+	if health == nil || gs.Status.FirstReady == nil {
+		return time.Time{}, false
+	}
+
+	last := gs.Status.FirstReady.Add(time.Duration(health.InitialDelaySeconds) * time.Second)
+	if gs.Status.LastHealthy != nil && gs.Status.LastHealthy.Time.After(last) {
+		last = gs.Status.LastHealthy.Time
+	}
+
+	period, threshold := health.PeriodSeconds, health.FailureThreshold
+	if period <= 0 {
+		period = DefaultHealthPeriodSeconds
+	}
+	if threshold <= 0 {
+		threshold = DefaultHealthFailureThreshold
+	}
+
+	return last.Add(time.Duration(period*threshold) * time.Second), true
+}
+
+// RequestReady moves the server into the RequestReady state, which the controller turns into Ready.
+// A server which is already Ready, or requested to be, is left as is.
+func (gs *GameServer) RequestReady() error {
+	switch {
+	case gs.Status.State == GameServerStateReady || gs.Status.State == GameServerStateRequestReady:
+		return nil
+	case !CanRequestReady(gs.Status.State):
+		return errors.Wrapf(ErrorInvalidStateTransition, "%s to %s", gs.Status.State, GameServerStateRequestReady)
+	}
+
+	gs.Status.ReadyRequestedFrom = gs.Status.State
+	gs.Status.State = GameServerStateRequestReady
+	return nil
+}
+
+// CanRequestReady returns whether a server in the state may request to be Ready,
+// which is only the case once it started or to reuse it after being Allocated or Reserved
+func CanRequestReady(state GameServerState) bool {
+	switch state {
+	case GameServerStateStarting, GameServerStateScheduled, GameServerStateAllocated, GameServerStateReserved:
+		return true
+	}
+
+	return false
+}
+
+// Reserve moves the server into the Reserved state for the given duration, or indefinitely if the duration is zero
+func (gs *GameServer) Reserve(d time.Duration) {
+	gs.Status.State = GameServerStateReserved
+	gs.Status.ReservedUntil = nil
+	if d > 0 {
+		until := metav1.NewTime(time.Now().Add(d))
+		gs.Status.ReservedUntil = &until
+	}
+}
+
 // IsBeingDeleted returns true if the server is in the process of being deleted.
 func (gs *GameServer) IsBeingDeleted() bool {
 	return !gs.ObjectMeta.DeletionTimestamp.IsZero() || gs.Status.State == GameServerStateShutdown
@@ -168,16 +422,101 @@ func (gs *GameServer) Pod() *v1.Pod {
 		Name:  GameServerEnvNamespace,
 		Value: gs.ObjectMeta.Namespace,
 	}
+	// The labels and annotations of the GameServer are propagated to the Pod, including those applied on allocation.
+	pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{
+		Name: GameServerMetadataVolume,
+		VolumeSource: v1.VolumeSource{
+			DownwardAPI: &v1.DownwardAPIVolumeSource{
+				Items: []v1.DownwardAPIVolumeFile{
+					{Path: "labels", FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.labels"}},
+					{Path: "annotations", FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.annotations"}},
+				},
+			},
+		},
+	})
+	mount := v1.VolumeMount{
+		Name:      GameServerMetadataVolume,
+		MountPath: GameServerMetadataPath,
+		ReadOnly:  true,
+	}
+
 	for i := range pod.Spec.Containers {
 		container := &pod.Spec.Containers[i]
 		container.Env = append(container.Env, envName, envNamespace)
+		container.VolumeMounts = append(container.VolumeMounts, mount)
 	}
 
+	gs.configureSDKServer(pod, envName, envNamespace)
+
 	// TODO: hostPort allocation
 
 	return pod
 }
 
+// configureSDKServer injects the SDK sidecar into the Pod, unless it's disabled.
+// The sidecar uses the ServiceAccount of the Pod, so it's only allowed to access this GameServer.
+func (gs *GameServer) configureSDKServer(pod *v1.Pod, env ...v1.EnvVar) {
+	sdkServer := gs.Spec.SDKServer
+	if sdkServer == nil {
+		sdkServer = &GameServerSDKServer{}
+	}
+	if sdkServer.Disabled {
+		return
+	}
+
+	image := SDKServerImage
+	if sdkServer.Image != "" {
+		image = sdkServer.Image
+	}
+	httpPort, grpcPort := sdkServer.HTTPPort, sdkServer.GRPCPort
+	if httpPort == 0 {
+		httpPort = DefaultSDKServerHTTPPort
+	}
+	if grpcPort == 0 {
+		grpcPort = DefaultSDKServerGRPCPort
+	}
+
+	ports := []v1.EnvVar{
+		{Name: SDKServerEnvHTTPPort, Value: strconv.Itoa(int(httpPort))},
+		{Name: SDKServerEnvGRPCPort, Value: strconv.Itoa(int(grpcPort))},
+	}
+	for i := range pod.Spec.Containers {
+		pod.Spec.Containers[i].Env = append(pod.Spec.Containers[i].Env, ports...)
+	}
+
+	pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{
+		Name:  SDKServerContainerName,
+		Image: image,
+		Args: []string{
+			fmt.Sprintf("--http-bind-address=localhost:%d", httpPort),
+			fmt.Sprintf("--grpc-bind-address=localhost:%d", grpcPort),
+		},
+		Env: env,
+	})
+}
+
+// StatusPorts resolves the ports of the GameServer against the containers of its Pod.
+// A ContainerPort is either a port number, or the name of a container port.
+func (gs *GameServer) StatusPorts(pod *v1.Pod) []GameServerStatusPort {
+	ports := make([]GameServerStatusPort, 0, len(gs.Spec.Ports))
+	for _, port := range gs.Spec.Ports {
+		if number, err := strconv.ParseInt(port.ContainerPort, 10, 32); err == nil {
+			ports = append(ports, GameServerStatusPort{Name: port.Name, Port: int32(number)})
+			continue
+		}
+
+		for _, container := range pod.Spec.Containers {
+			for _, containerPort := range container.Ports {
+				if containerPort.Name == port.ContainerPort {
+					ports = append(ports, GameServerStatusPort{Name: port.Name, Port: containerPort.ContainerPort})
+				}
+			}
+		}
+	}
+
+	return ports
+}
+
 func (gs *GameServer) ServiceAccount() *v1.ServiceAccount {
 	ref := metav1.NewControllerRef(gs, GroupVersion.WithKind("GameServer"))
 
@@ -196,6 +535,34 @@ func (gs *GameServer) ServiceAccount() *v1.ServiceAccount {
 func (gs *GameServer) Role() *rbacv1.Role {
 	ref := metav1.NewControllerRef(gs, GroupVersion.WithKind("GameServer"))
 
+	// Only allow access to its own GameServer and Pod resources
+	rules := []rbacv1.PolicyRule{
+		{
+			Verbs:           []string{"get", "update", "patch", "list", "watch"},
+			APIGroups:       []string{singularity.GroupName},
+			Resources:       []string{"gameservers", "gameservers/status"},
+			ResourceNames:   []string{gs.ObjectMeta.Name},
+			NonResourceURLs: nil,
+		},
+		{
+			Verbs:           []string{"get", "update", "patch", "list", "watch"},
+			APIGroups:       []string{""}, // Default Kubernetes API group
+			Resources:       []string{"pods", "pods/status"},
+			ResourceNames:   []string{gs.ObjectMeta.Name},
+			NonResourceURLs: nil,
+		},
+	}
+	// and its own GameServerInstances, if it has any, as a rule without ResourceNames allows access to all of them
+	if gs.Spec.Instances > 0 {
+		rules = append(rules, rbacv1.PolicyRule{
+			Verbs:           []string{"get", "update", "patch", "list", "watch"},
+			APIGroups:       []string{singularity.GroupName},
+			Resources:       []string{"gameserverinstances", "gameserverinstances/status"},
+			ResourceNames:   gs.gameServerInstanceNames(),
+			NonResourceURLs: nil,
+		})
+	}
+
 	return &rbacv1.Role{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      gs.Name,
@@ -205,23 +572,7 @@ func (gs *GameServer) Role() *rbacv1.Role {
 			},
 			OwnerReferences: []metav1.OwnerReference{*ref},
 		},
-		// Only allow access to its own GameServer and Pod resources
-		Rules: []rbacv1.PolicyRule{
-			{
-				Verbs:           []string{"get", "update", "patch", "list", "watch"},
-				APIGroups:       []string{singularity.GroupName},
-				Resources:       []string{"gameservers", "gameservers/status"},
-				ResourceNames:   []string{gs.ObjectMeta.Name},
-				NonResourceURLs: nil,
-			},
-			{
-				Verbs:           []string{"get", "update", "patch", "list", "watch"},
-				APIGroups:       []string{""}, // Default Kubernetes API group
-				Resources:       []string{"pods", "pods/status"},
-				ResourceNames:   []string{gs.ObjectMeta.Name},
-				NonResourceURLs: nil,
-			},
-		},
+		Rules: rules,
 	}
 }
 
@@ -251,21 +602,66 @@ func (gs *GameServer) RoleBinding() *rbacv1.RoleBinding {
 	}
 }
 
+// GameServerInstanceName returns the name of the GameServerInstance with the id, owned by the named GameServer
+func GameServerInstanceName(gameServerName string, id int) string {
+	return fmt.Sprintf("%s-%d", gameServerName, id)
+}
+
+// gameServerInstanceNames returns the names of all GameServerInstances of the GameServer
+func (gs *GameServer) gameServerInstanceNames() []string {
+	names := make([]string, gs.Spec.Instances)
+	for i := range names {
+		names[i] = GameServerInstanceName(gs.ObjectMeta.Name, i)
+	}
+
+	return names
+}
+
 func (gs *GameServer) GameServerInstance(id int) *GameServerInstance {
+	gsInstance := &GameServerInstance{
+		ObjectMeta: *gs.Spec.InstanceTemplate.ObjectMeta.DeepCopy(),
+		Spec:       *gs.Spec.InstanceTemplate.Spec.DeepCopy(),
+	}
+
+	// The name is derived from the GameServer and the instance id. Also, reset the ObjectMeta.
+	gsInstance.ObjectMeta.GenerateName = ""
+	gsInstance.ObjectMeta.Name = GameServerInstanceName(gs.ObjectMeta.Name, id)
+	gsInstance.ObjectMeta.Namespace = gs.ObjectMeta.Namespace
+	gsInstance.ObjectMeta.ResourceVersion = ""
+	gsInstance.ObjectMeta.UID = ""
+
 	ref := metav1.NewControllerRef(gs, GroupVersion.WithKind("GameServer"))
+	gsInstance.ObjectMeta.OwnerReferences = []metav1.OwnerReference{*ref}
 
-	// TODO: Copy metadata from instance template
-	return &GameServerInstance{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf("%s-%d", gs.ObjectMeta.Name, id),
-			Namespace: gs.ObjectMeta.Namespace,
-			Labels: map[string]string{
-				GameServerNameLabel: gs.ObjectMeta.Name,
-			},
-			OwnerReferences: []metav1.OwnerReference{*ref},
-		},
-		Spec: *gs.Spec.InstanceTemplate.Spec.DeepCopy(),
+	// Append GameServer and Fleet name labels
+	if gsInstance.ObjectMeta.Labels == nil {
+		gsInstance.ObjectMeta.Labels = make(map[string]string, 2)
 	}
+	gsInstance.ObjectMeta.Labels[GameServerNameLabel] = gs.ObjectMeta.Name
+	if fleetName, ok := gs.ObjectMeta.Labels[FleetNameLabel]; ok {
+		gsInstance.ObjectMeta.Labels[FleetNameLabel] = fleetName
+	}
+
+	return gsInstance
+}
+
+// RelabelLegacy moves the GameServer name of a resource created before GameServerNameLabel was renamed to the new key,
+// and restores its Fleet name label. It returns whether the labels were changed.
+func (gs *GameServer) RelabelLegacy(obj metav1.Object) bool {
+	labels := obj.GetLabels()
+	if _, ok := labels[GameServerNameLabel]; ok || labels[LegacyGameServerNameLabel] != gs.ObjectMeta.Name {
+		return false
+	}
+
+	labels[GameServerNameLabel] = gs.ObjectMeta.Name
+	if fleetName, ok := gs.ObjectMeta.Labels[FleetNameLabel]; ok {
+		labels[FleetNameLabel] = fleetName
+	} else {
+		delete(labels, FleetNameLabel)
+	}
+	obj.SetLabels(labels)
+
+	return true
 }
 
 // SortDescending returns GameServers sorted by newest created
@@ -292,11 +688,18 @@ func (gs *GameServer) configurePodMeta(pod *v1.Pod) {
 
 	// Append labels
 	if pod.ObjectMeta.Labels == nil {
-		pod.ObjectMeta.Labels = make(map[string]string, 2)
+		pod.ObjectMeta.Labels = make(map[string]string, 3)
 	}
 	pod.ObjectMeta.Labels[singularity.RoleLabel] = GameServerRole
 	pod.ObjectMeta.Labels[GameServerNameLabel] = gs.ObjectMeta.Name
 
+	// Propagate the Fleet name and template hash, so that Pods can be selected by Fleet and revision
+	for _, label := range []string{FleetNameLabel, TemplateHashLabel} {
+		if value, ok := gs.ObjectMeta.Labels[label]; ok {
+			pod.ObjectMeta.Labels[label] = value
+		}
+	}
+
 	// Append GameServer owner reference
 	ref := metav1.NewControllerRef(gs, GroupVersion.WithKind("GameServer"))
 	pod.ObjectMeta.OwnerReferences = append(pod.ObjectMeta.OwnerReferences, *ref)
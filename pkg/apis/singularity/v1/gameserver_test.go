@@ -0,0 +1,260 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package v1
+
+import (
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRelabelLegacy(t *testing.T) {
+	tests := []struct {
+		name       string
+		gsLabels   map[string]string
+		labels     map[string]string
+		want       map[string]string
+		wantChange bool
+	}{
+		{
+			name:       "legacy label of a fleet server",
+			gsLabels:   map[string]string{FleetNameLabel: "lobby"},
+			labels:     map[string]string{LegacyGameServerNameLabel: "lobby-abcde"},
+			want:       map[string]string{GameServerNameLabel: "lobby-abcde", FleetNameLabel: "lobby"},
+			wantChange: true,
+		},
+		{
+			name:       "legacy label of a standalone server",
+			labels:     map[string]string{LegacyGameServerNameLabel: "lobby-abcde", "app": "lobby"},
+			want:       map[string]string{GameServerNameLabel: "lobby-abcde", "app": "lobby"},
+			wantChange: true,
+		},
+		{
+			name:     "already relabelled",
+			gsLabels: map[string]string{FleetNameLabel: "lobby"},
+			labels:   map[string]string{GameServerNameLabel: "lobby-abcde", FleetNameLabel: "lobby"},
+			want:     map[string]string{GameServerNameLabel: "lobby-abcde", FleetNameLabel: "lobby"},
+		},
+		{
+			name:   "fleet label of another resource",
+			labels: map[string]string{FleetNameLabel: "lobby"},
+			want:   map[string]string{FleetNameLabel: "lobby"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := &GameServer{ObjectMeta: metav1.ObjectMeta{Name: "lobby-abcde", Labels: tt.gsLabels}}
+			obj := &GameServerInstance{ObjectMeta: metav1.ObjectMeta{Labels: tt.labels}}
+
+			if changed := gs.RelabelLegacy(obj); changed != tt.wantChange {
+				t.Errorf("RelabelLegacy() = %v, want %v", changed, tt.wantChange)
+			}
+			if !reflect.DeepEqual(obj.ObjectMeta.Labels, tt.want) {
+				t.Errorf("labels = %v, want %v", obj.ObjectMeta.Labels, tt.want)
+			}
+		})
+	}
+}
+
+func TestGameServerTemplateHash(t *testing.T) {
+	undefaulted := GameServerTemplate{
+		Spec: GameServerSpec{
+			Type:      GameServerTypeStatic,
+			Instances: 2,
+			SDKServer: &GameServerSDKServer{},
+			Health:    &GameServerHealth{InitialDelaySeconds: 10},
+		},
+	}
+	defaulted := *undefaulted.DeepCopy()
+	defaulted.ObjectMeta.Labels = map[string]string{TemplateHashLabel: "abcde"}
+	defaulted.Spec.Ports = []GameServerPort{}
+	defaulted.Spec.SDKServer = &GameServerSDKServer{HTTPPort: DefaultSDKServerHTTPPort, GRPCPort: DefaultSDKServerGRPCPort}
+	defaulted.Spec.Health = &GameServerHealth{
+		InitialDelaySeconds: 10,
+		PeriodSeconds:       DefaultHealthPeriodSeconds,
+		FailureThreshold:    DefaultHealthFailureThreshold,
+	}
+	withoutSDKServer := *undefaulted.DeepCopy()
+	withoutSDKServer.Spec.SDKServer = nil
+
+	if undefaulted.Hash() != defaulted.Hash() {
+		t.Errorf("hash of defaulted template = %s, want %s", defaulted.Hash(), undefaulted.Hash())
+	}
+	if undefaulted.Hash() != withoutSDKServer.Hash() {
+		t.Errorf("hash of template without sdk server = %s, want %s", withoutSDKServer.Hash(), undefaulted.Hash())
+	}
+
+	changed := *defaulted.DeepCopy()
+	changed.Spec.SDKServer.HTTPPort = 8080
+	if changed.Hash() == defaulted.Hash() {
+		t.Errorf("hash of template with custom port = %s, want a different hash", changed.Hash())
+	}
+	changed = *defaulted.DeepCopy()
+	changed.Spec.Instances = 3
+	if changed.Hash() == defaulted.Hash() {
+		t.Errorf("hash of template with more instances = %s, want a different hash", changed.Hash())
+	}
+}
+
+func TestGameServerReserve(t *testing.T) {
+	gs := &GameServer{Status: GameServerStatus{State: GameServerStateReady}}
+
+	gs.Reserve(time.Minute)
+	if gs.Status.State != GameServerStateReserved || gs.Status.ReservedUntil == nil ||
+		time.Until(gs.Status.ReservedUntil.Time) > time.Minute {
+		t.Errorf("Reserve(1m) = %s until %v", gs.Status.State, gs.Status.ReservedUntil)
+	}
+	if gs.IsDeletable() {
+		t.Error("reserved gameserver is deletable")
+	}
+
+	gs.Reserve(0)
+	if gs.Status.State != GameServerStateReserved || gs.Status.ReservedUntil != nil {
+		t.Errorf("Reserve(0) = %s until %v, want indefinitely", gs.Status.State, gs.Status.ReservedUntil)
+	}
+}
+
+func TestGameServerRequestReady(t *testing.T) {
+	tests := []struct {
+		state     GameServerState
+		wantState GameServerState
+		wantErr   bool
+	}{
+		{state: GameServerStateStarting, wantState: GameServerStateRequestReady},
+		{state: GameServerStateScheduled, wantState: GameServerStateRequestReady},
+		{state: GameServerStateAllocated, wantState: GameServerStateRequestReady},
+		{state: GameServerStateReserved, wantState: GameServerStateRequestReady},
+		{state: GameServerStateReady, wantState: GameServerStateReady},
+		{state: GameServerStateRequestReady, wantState: GameServerStateRequestReady},
+		{state: GameServerStateCreating, wantState: GameServerStateCreating, wantErr: true},
+		{state: GameServerStateDrain, wantState: GameServerStateDrain, wantErr: true},
+		{state: GameServerStateShutdown, wantState: GameServerStateShutdown, wantErr: true},
+		{state: GameServerStateError, wantState: GameServerStateError, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.state), func(t *testing.T) {
+			gs := &GameServer{Status: GameServerStatus{State: tt.state}}
+
+			err := gs.RequestReady()
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrorInvalidStateTransition)) {
+				t.Fatalf("RequestReady() error = %v, want error %v", err, tt.wantErr)
+			}
+			if gs.Status.State != tt.wantState {
+				t.Errorf("state = %s, want %s", gs.Status.State, tt.wantState)
+			}
+			if gs.Status.State == GameServerStateRequestReady && tt.state != GameServerStateRequestReady && gs.Status.ReadyRequestedFrom != tt.state {
+				t.Errorf("readyRequestedFrom = %s, want %s", gs.Status.ReadyRequestedFrom, tt.state)
+			}
+		})
+	}
+}
+
+func TestGameServerRole(t *testing.T) {
+	tests := []struct {
+		name      string
+		instances int32
+		want      []string
+	}{
+		{name: "without instances"},
+		{name: "with instances", instances: 2, want: []string{"lobby-abcde-0", "lobby-abcde-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := &GameServer{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "lobby-abcde"}}
+			gs.Spec.Instances = tt.instances
+
+			var instanceRules int
+			for _, rule := range gs.Role().Rules {
+				// An empty ResourceNames allows access to every resource of the rule
+				if len(rule.ResourceNames) == 0 {
+					t.Errorf("rule for %v allows all names", rule.Resources)
+				}
+				for _, resource := range rule.Resources {
+					if resource != "gameserverinstances" {
+						continue
+					}
+					instanceRules++
+					if !reflect.DeepEqual(rule.ResourceNames, tt.want) {
+						t.Errorf("instance names = %v, want %v", rule.ResourceNames, tt.want)
+					}
+				}
+			}
+
+			wantRules := 0
+			if tt.instances > 0 {
+				wantRules = 1
+			}
+			if instanceRules != wantRules {
+				t.Errorf("got %d GameServerInstance rules, want %d", instanceRules, wantRules)
+			}
+		})
+	}
+}
+
+func TestGameServerHealthDeadline(t *testing.T) {
+	created := metav1.NewTime(time.Now().Add(-time.Hour))
+	ready := metav1.NewTime(time.Now().Add(-time.Minute))
+	healthy := metav1.NewTime(time.Now().Add(-10 * time.Second))
+
+	tests := []struct {
+		name        string
+		health      *GameServerHealth
+		firstReady  *metav1.Time
+		lastHealthy *metav1.Time
+		want        time.Time
+		wantOk      bool
+	}{
+		{name: "disabled", firstReady: &ready},
+		// Servers may take longer to start than their health check period
+		{name: "not ready yet", health: &GameServerHealth{}},
+		{
+			name:       "ready without report",
+			health:     &GameServerHealth{InitialDelaySeconds: 5},
+			firstReady: &ready,
+			want:       ready.Add(5*time.Second + DefaultHealthPeriodSeconds*DefaultHealthFailureThreshold*time.Second),
+			wantOk:     true,
+		},
+		{
+			name:        "reported",
+			health:      &GameServerHealth{PeriodSeconds: 2, FailureThreshold: 2},
+			firstReady:  &ready,
+			lastHealthy: &healthy,
+			want:        healthy.Add(4 * time.Second),
+			wantOk:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := &GameServer{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created}}
+			gs.Spec.Health = tt.health
+			gs.Status.FirstReady, gs.Status.LastHealthy = tt.firstReady, tt.lastHealthy
+
+			got, ok := gs.HealthDeadline()
+			if ok != tt.wantOk || !got.Equal(tt.want) {
+				t.Errorf("HealthDeadline() = %s, %v, want %s, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
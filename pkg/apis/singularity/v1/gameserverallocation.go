@@ -0,0 +1,326 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package v1
+
+import (
+	"innit.gg/singularity/pkg/apis/singularity"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// GameServerAllocationPriorityCounter sorts by the count of a Counter
+	GameServerAllocationPriorityCounter GameServerAllocationPriorityType = "Counter"
+	// GameServerAllocationPriorityList sorts by the amount of values in a List
+	GameServerAllocationPriorityList GameServerAllocationPriorityType = "List"
+
+	// GameServerAllocationOrderAscending prefers the lowest values
+	GameServerAllocationOrderAscending GameServerAllocationOrder = "Ascending"
+	// GameServerAllocationOrderDescending prefers the highest values
+	GameServerAllocationOrderDescending GameServerAllocationOrder = "Descending"
+
+	// GameServerAllocationLabel is the UID of the GameServerAllocation which last allocated a GameServer or GameServerInstance
+	GameServerAllocationLabel = singularity.GroupName + "/allocation"
+
+	// GameServerAllocationStatePending indicates that the allocation is in progress.
+	// A GameServer may already have been allocated, it is looked up using GameServerAllocationLabel.
+	GameServerAllocationStatePending GameServerAllocationState = "Pending"
+	// GameServerAllocationStateAllocated indicates that a GameServer has been allocated
+	GameServerAllocationStateAllocated GameServerAllocationState = "Allocated"
+	// GameServerAllocationStateUnAllocated indicates that no Ready GameServer matched the allocation
+	GameServerAllocationStateUnAllocated GameServerAllocationState = "UnAllocated"
+	// GameServerAllocationStateContention indicates that all matching GameServers were allocated concurrently
+	GameServerAllocationStateContention GameServerAllocationState = "Contention"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+//+kubebuilder:printcolumn:name="GameServer",type=string,JSONPath=`.status.gameServerName`
+//+kubebuilder:printcolumn:name="Instance",type=string,JSONPath=`.status.gameServerInstanceName`
+//+kubebuilder:printcolumn:name="Address",type=string,JSONPath=`.status.address`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// GameServerAllocation is the Schema for the GameServerAllocations API.
+// A GameServerAllocation is processed once after it is created, its spec is not reconciled afterwards.
+type GameServerAllocation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GameServerAllocationSpec   `json:"spec,omitempty"`
+	Status GameServerAllocationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GameServerAllocationList contains a list of GameServerAllocation
+type GameServerAllocationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GameServerAllocation `json:"items"`
+}
+
+// GameServerAllocationSpec defines the desired state of GameServerAllocation
+type GameServerAllocationSpec struct {
+	// FleetName restricts the allocation to GameServers of a Fleet
+	FleetName string `json:"fleetName,omitempty"`
+	// Required is the label selector every allocated GameServer has to match
+	Required metav1.LabelSelector `json:"required,omitempty"`
+	// Preferred label selectors are tried in order, before falling back to any GameServer matching Required
+	Preferred []metav1.LabelSelector `json:"preferred,omitempty"`
+
+	// Counters filters GameServers, or GameServerInstances if an instance is allocated, by their counters.
+	// Instances inherit the counters and lists of their GameServer which they don't have themselves.
+	Counters map[string]CounterSelector `json:"counters,omitempty"`
+	// Lists filters GameServers, or GameServerInstances if an instance is allocated, by their lists
+	Lists map[string]ListSelector `json:"lists,omitempty"`
+	// Priorities order the matching GameServers, or GameServerInstances, after the Preferred selectors
+	Priorities []GameServerAllocationPriority `json:"priorities,omitempty"`
+	// AllowAllocated allows GameServers which are already Allocated to be allocated again,
+	// e.g. to fill up lobby-style servers according to their counters
+	AllowAllocated bool `json:"allowAllocated,omitempty"`
+
+	// Instance allocates a single GameServerInstance of a matching GameServer, instead of the whole GameServer
+	//+optional
+	Instance *GameServerInstanceAllocation `json:"instance,omitempty"`
+
+	// Metadata is applied to the allocated GameServer, or GameServerInstance if an instance is allocated
+	//+optional
+	Metadata *GameServerAllocationMetadata `json:"metadata,omitempty"`
+}
+
+// GameServerAllocationMetadata are the labels and annotations applied on allocation
+type GameServerAllocationMetadata struct {
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Apply merges the labels and annotations into the object's metadata, overwriting existing keys
+func (m *GameServerAllocationMetadata) Apply(obj metav1.Object) {
+	obj.SetLabels(mergeStringMaps(obj.GetLabels(), m.Labels))
+	obj.SetAnnotations(mergeStringMaps(obj.GetAnnotations(), m.Annotations))
+}
+
+// mergeStringMaps copies src into dst, allocating dst if necessary
+func mergeStringMaps(dst, src map[string]string) map[string]string {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[string]string, len(src))
+	}
+	for k, v := range src {
+		dst[k] = v
+	}
+
+	return dst
+}
+
+// CounterSelector matches a Counter by its count and available capacity, zero maximums are unbounded
+type CounterSelector struct {
+	MinCount     int64 `json:"minCount,omitempty"`
+	MaxCount     int64 `json:"maxCount,omitempty"`
+	MinAvailable int64 `json:"minAvailable,omitempty"`
+	MaxAvailable int64 `json:"maxAvailable,omitempty"`
+}
+
+// Matches returns whether the counter is within the bounds of the selector
+func (s *CounterSelector) Matches(counter Counter) bool {
+	return withinBounds(counter.Count, s.MinCount, s.MaxCount) &&
+		withinBounds(counter.Available(), s.MinAvailable, s.MaxAvailable)
+}
+
+// ListSelector matches a List by a value it contains and its available capacity, zero maximums are unbounded
+type ListSelector struct {
+	ContainsValue string `json:"containsValue,omitempty"`
+	MinAvailable  int64  `json:"minAvailable,omitempty"`
+	MaxAvailable  int64  `json:"maxAvailable,omitempty"`
+}
+
+// Matches returns whether the list contains the value and is within the bounds of the selector
+func (s *ListSelector) Matches(list List) bool {
+	if s.ContainsValue != "" && !list.Contains(s.ContainsValue) {
+		return false
+	}
+
+	return withinBounds(list.Available(), s.MinAvailable, s.MaxAvailable)
+}
+
+func withinBounds(value, min, max int64) bool {
+	return value >= min && (max == 0 || value <= max)
+}
+
+type GameServerAllocationPriorityType string
+type GameServerAllocationOrder string
+
+// GameServerAllocationPriority orders allocation candidates by a Counter or List
+type GameServerAllocationPriority struct {
+	//+kubebuilder:validation:Enum=Counter;List
+	Type GameServerAllocationPriorityType `json:"type"`
+	Key  string                           `json:"key"`
+	//+kubebuilder:validation:Enum=Ascending;Descending
+	//+kubebuilder:default=Ascending
+	Order GameServerAllocationOrder `json:"order,omitempty"`
+}
+
+// GameServerInstanceAllocation describes the GameServerInstance to allocate
+type GameServerInstanceAllocation struct {
+	// Map is the map the instance has to host, any map is accepted if empty
+	Map string `json:"map,omitempty"`
+	// Players is the party size the instance's capacity has to cover
+	Players uint32 `json:"players,omitempty"`
+	// Selector is the label selector the instance has to match
+	Selector metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+type GameServerAllocationState string
+
+// GameServerAllocationStatus defines the observed state of GameServerAllocation
+type GameServerAllocationStatus struct {
+	State                  GameServerAllocationState `json:"state,omitempty"`
+	GameServerName         string                    `json:"gameServerName,omitempty"`
+	GameServerInstanceName string                    `json:"gameServerInstanceName,omitempty"`
+	Address                string                    `json:"address,omitempty"`
+	NodeName               string                    `json:"nodeName,omitempty"`
+	Ports                  []GameServerStatusPort    `json:"ports,omitempty"`
+}
+
+// ListOptions returns the options to list GameServers which may be allocated
+func (gsa *GameServerAllocation) ListOptions() ([]client.ListOption, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&gsa.Spec.Required)
+	if err != nil {
+		return nil, err
+	}
+
+	if gsa.Spec.FleetName != "" {
+		requirement, err := labels.NewRequirement(FleetNameLabel, "=", []string{gsa.Spec.FleetName})
+		if err != nil {
+			return nil, err
+		}
+		selector = selector.Add(*requirement)
+	}
+
+	return []client.ListOption{
+		client.InNamespace(gsa.ObjectMeta.Namespace),
+		client.MatchingLabelsSelector{Selector: selector},
+	}, nil
+}
+
+// Matches returns whether the GameServerInstance of the GameServer may be allocated by this allocation
+func (gsa *GameServerAllocation) Matches(gsInstance *GameServerInstance, gs *GameServer) bool {
+	if gsa.Spec.Instance == nil {
+		return false
+	}
+
+	if gsa.Spec.Instance.Map != "" && gsa.Spec.Instance.Map != gsInstance.Spec.Map {
+		return false
+	}
+
+	if gsa.Spec.Instance.Players > gsInstance.Spec.Capacity {
+		return false
+	}
+
+	if !gsa.MatchesCountersAndLists(gsInstance.InheritedCountersAndLists(gs)) {
+		return false
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&gsa.Spec.Instance.Selector)
+	if err != nil {
+		return false
+	}
+
+	return selector.Matches(labels.Set(gsInstance.ObjectMeta.Labels))
+}
+
+// MatchesCountersAndLists returns whether the counters and lists match the Counters and Lists selectors.
+// Counters and lists which don't exist never match.
+func (gsa *GameServerAllocation) MatchesCountersAndLists(cl CountersAndLists) bool {
+	for name, selector := range gsa.Spec.Counters {
+		counter, ok := cl.Counters[name]
+		if !ok || !selector.Matches(counter) {
+			return false
+		}
+	}
+
+	for name, selector := range gsa.Spec.Lists {
+		list, ok := cl.Lists[name]
+		if !ok || !selector.Matches(list) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ComparePriorities compares the counters and lists of two candidates by the allocation's Priorities.
+// Returns a negative number if a should be allocated first, a positive one if b should, and zero if they're equal.
+// Missing counters and lists are sorted last.
+func (gsa *GameServerAllocation) ComparePriorities(a, b CountersAndLists) int {
+	for _, priority := range gsa.Spec.Priorities {
+		va, oka := priority.value(a)
+		vb, okb := priority.value(b)
+		switch {
+		case oka && !okb:
+			return -1
+		case !oka && okb:
+			return 1
+		case va == vb:
+			continue
+		}
+
+		if (va < vb) == (priority.Order != GameServerAllocationOrderDescending) {
+			return -1
+		}
+		return 1
+	}
+
+	return 0
+}
+
+// value returns the value to sort by, and whether the counter or list exists
+func (p *GameServerAllocationPriority) value(cl CountersAndLists) (int64, bool) {
+	if p.Type == GameServerAllocationPriorityList {
+		list, ok := cl.Lists[p.Key]
+		return int64(len(list.Values)), ok
+	}
+
+	counter, ok := cl.Counters[p.Key]
+	return counter.Count, ok
+}
+
+// Preference returns the index of the first Preferred selector the GameServer matches,
+// or the amount of Preferred selectors if it doesn't match any of them.
+func (gsa *GameServerAllocation) Preference(gs *GameServer) int {
+	for i := range gsa.Spec.Preferred {
+		selector, err := metav1.LabelSelectorAsSelector(&gsa.Spec.Preferred[i])
+		if err != nil {
+			continue
+		}
+		if selector.Matches(labels.Set(gs.ObjectMeta.Labels)) {
+			return i
+		}
+	}
+
+	return len(gsa.Spec.Preferred)
+}
+
+func init() {
+	SchemeBuilder.Register(&GameServerAllocation{}, &GameServerAllocationList{})
+}
@@ -0,0 +1,168 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package v1
+
+import (
+	"testing"
+)
+
+func TestCounterSelectorMatches(t *testing.T) {
+	counter := Counter{Count: 3, Capacity: 10}
+	tests := []struct {
+		name     string
+		selector CounterSelector
+		want     bool
+	}{
+		{name: "unbounded", want: true},
+		{name: "within count bounds", selector: CounterSelector{MinCount: 3, MaxCount: 3}, want: true},
+		{name: "below min count", selector: CounterSelector{MinCount: 4}},
+		{name: "above max count", selector: CounterSelector{MaxCount: 2}},
+		{name: "within available bounds", selector: CounterSelector{MinAvailable: 7, MaxAvailable: 7}, want: true},
+		{name: "below min available", selector: CounterSelector{MinAvailable: 8}},
+		{name: "above max available", selector: CounterSelector{MaxAvailable: 6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.selector.Matches(counter); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListSelectorMatches(t *testing.T) {
+	list := List{Capacity: 4, Values: []string{"a", "b"}}
+	tests := []struct {
+		name     string
+		selector ListSelector
+		want     bool
+	}{
+		{name: "unbounded", want: true},
+		{name: "contains value", selector: ListSelector{ContainsValue: "a"}, want: true},
+		{name: "missing value", selector: ListSelector{ContainsValue: "c"}},
+		{name: "within available bounds", selector: ListSelector{MinAvailable: 2, MaxAvailable: 2}, want: true},
+		{name: "below min available", selector: ListSelector{MinAvailable: 3}},
+		{name: "above max available", selector: ListSelector{MaxAvailable: 1}},
+		{name: "contains value below min available", selector: ListSelector{ContainsValue: "a", MinAvailable: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.selector.Matches(list); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesCountersAndLists(t *testing.T) {
+	cl := CountersAndLists{
+		Counters: map[string]Counter{"rooms": {Count: 1, Capacity: 4}},
+		Lists:    map[string]List{"spectators": {Capacity: 2, Values: []string{"a"}}},
+	}
+	tests := []struct {
+		name     string
+		counters map[string]CounterSelector
+		lists    map[string]ListSelector
+		want     bool
+	}{
+		{name: "no selectors", want: true},
+		{
+			name:     "matching counter and list",
+			counters: map[string]CounterSelector{"rooms": {MinAvailable: 1}},
+			lists:    map[string]ListSelector{"spectators": {ContainsValue: "a"}},
+			want:     true,
+		},
+		{
+			name:     "counter doesn't match",
+			counters: map[string]CounterSelector{"rooms": {MinCount: 2}},
+			lists:    map[string]ListSelector{"spectators": {ContainsValue: "a"}},
+		},
+		{
+			name:     "list doesn't match",
+			counters: map[string]CounterSelector{"rooms": {MinAvailable: 1}},
+			lists:    map[string]ListSelector{"spectators": {MinAvailable: 2}},
+		},
+		{name: "missing counter", counters: map[string]CounterSelector{"players": {}}},
+		{name: "missing list", lists: map[string]ListSelector{"players": {}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gsa := &GameServerAllocation{Spec: GameServerAllocationSpec{Counters: tt.counters, Lists: tt.lists}}
+			if got := gsa.MatchesCountersAndLists(cl); got != tt.want {
+				t.Errorf("MatchesCountersAndLists() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComparePriorities(t *testing.T) {
+	counters := func(rooms int64) CountersAndLists {
+		return CountersAndLists{Counters: map[string]Counter{"rooms": {Count: rooms, Capacity: 10}}}
+	}
+	lists := func(values ...string) CountersAndLists {
+		return CountersAndLists{Lists: map[string]List{"spectators": {Capacity: 10, Values: values}}}
+	}
+	roomsAscending := GameServerAllocationPriority{Type: GameServerAllocationPriorityCounter, Key: "rooms", Order: GameServerAllocationOrderAscending}
+	roomsDescending := GameServerAllocationPriority{Type: GameServerAllocationPriorityCounter, Key: "rooms", Order: GameServerAllocationOrderDescending}
+	spectatorsDescending := GameServerAllocationPriority{Type: GameServerAllocationPriorityList, Key: "spectators", Order: GameServerAllocationOrderDescending}
+
+	tests := []struct {
+		name       string
+		priorities []GameServerAllocationPriority
+		a, b       CountersAndLists
+		want       int
+	}{
+		{name: "no priorities", a: counters(1), b: counters(2)},
+		{name: "ascending counter", priorities: []GameServerAllocationPriority{roomsAscending}, a: counters(1), b: counters(2), want: -1},
+		{name: "descending counter", priorities: []GameServerAllocationPriority{roomsDescending}, a: counters(1), b: counters(2), want: 1},
+		{name: "default order is ascending", priorities: []GameServerAllocationPriority{{Type: GameServerAllocationPriorityCounter, Key: "rooms"}}, a: counters(2), b: counters(1), want: 1},
+		{name: "equal counters", priorities: []GameServerAllocationPriority{roomsAscending}, a: counters(1), b: counters(1)},
+		{name: "descending list", priorities: []GameServerAllocationPriority{spectatorsDescending}, a: lists("a", "b"), b: lists("a"), want: -1},
+		{name: "missing counter sorts last", priorities: []GameServerAllocationPriority{roomsDescending}, a: CountersAndLists{}, b: counters(0), want: 1},
+		{name: "missing list sorts last", priorities: []GameServerAllocationPriority{spectatorsDescending}, a: lists(), b: CountersAndLists{}, want: -1},
+		{
+			name:       "ties fall through to the next priority",
+			priorities: []GameServerAllocationPriority{roomsAscending, spectatorsDescending},
+			a: CountersAndLists{
+				Counters: counters(1).Counters,
+				Lists:    lists("a").Lists,
+			},
+			b: CountersAndLists{
+				Counters: counters(1).Counters,
+				Lists:    lists("a", "b").Lists,
+			},
+			want: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gsa := &GameServerAllocation{Spec: GameServerAllocationSpec{Priorities: tt.priorities}}
+			if got := gsa.ComparePriorities(tt.a, tt.b); got != tt.want {
+				t.Errorf("ComparePriorities() = %d, want %d", got, tt.want)
+			}
+			if got := gsa.ComparePriorities(tt.b, tt.a); got != -tt.want {
+				t.Errorf("ComparePriorities() reversed = %d, want %d", got, -tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package v1
+
+import (
+	"innit.gg/singularity/pkg/apis/singularity"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// GameServerIngressFinalizer removes the ingress from its provider before the GameServerIngress is deleted
+	GameServerIngressFinalizer = singularity.GroupName + "/ingress"
+	// GameServerIngressWeightAnnotation is the GameServer annotation containing its weight as a backend, 1 by default
+	GameServerIngressWeightAnnotation = singularity.GroupName + "/ingress-weight"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Hostname",type=string,JSONPath=`.spec.hostname`
+//+kubebuilder:printcolumn:name="Provider",type=string,JSONPath=`.spec.provider`
+//+kubebuilder:printcolumn:name="ID",type=string,JSONPath=`.status.id`
+//+kubebuilder:printcolumn:name="Backends",type=integer,JSONPath=`.status.replicas`
+//+kubebuilder:printcolumn:name="Verified",type=boolean,JSONPath=`.status.verified`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// GameServerIngress is the Schema for the GameServerIngresses API.
+// It routes a hostname to the Ready GameServers it selects through an ingress provider, e.g. TCPShield.
+type GameServerIngress struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GameServerIngressSpec   `json:"spec,omitempty"`
+	Status GameServerIngressStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GameServerIngressList contains a list of GameServerIngress
+type GameServerIngressList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GameServerIngress `json:"items"`
+}
+
+// GameServerIngressSpec defines the desired state of GameServerIngress
+type GameServerIngressSpec struct {
+	// Hostname is the domain players connect to
+	//+kubebuilder:validation:MinLength=1
+	Hostname string `json:"hostname"`
+	// Provider is the name of the ingress provider configured in the operator, e.g. tcpshield
+	Provider string `json:"provider"`
+
+	// FleetName restricts the backends to GameServers of a Fleet
+	FleetName string `json:"fleetName,omitempty"`
+	// Selector is the label selector of the GameServers used as backends
+	Selector metav1.LabelSelector `json:"selector,omitempty"`
+	// Port is the name of the GameServer port traffic is routed to, the first port if empty
+	Port string `json:"port,omitempty"`
+	// Protocol is the transport protocol of the port, e.g. UDP for Bedrock edition servers
+	//+kubebuilder:validation:Enum=TCP;UDP
+	//+kubebuilder:default=TCP
+	Protocol v1.Protocol `json:"protocol,omitempty"`
+	// Instances uses the Ready GameServerInstances of the selected GameServers as backends instead,
+	// e.g. for server lists of Minecraft proxies
+	Instances bool `json:"instances,omitempty"`
+
+	// ProxyProtocol sends the PROXY protocol header to the GameServers, so they see the addresses of players
+	ProxyProtocol bool `json:"proxyProtocol,omitempty"`
+	// TCPShield contains options specific to the tcpshield provider
+	//+optional
+	TCPShield *GameServerIngressTCPShield `json:"tcpshield,omitempty"`
+}
+
+// GameServerIngressTCPShield contains options specific to the tcpshield provider
+type GameServerIngressTCPShield struct {
+	// BAC enables Backend Access Control for the domain
+	BAC bool `json:"bac,omitempty"`
+}
+
+// GameServerIngressStatus defines the observed state of GameServerIngress
+type GameServerIngressStatus struct {
+	// ObservedGeneration is the generation of the spec the ingress was last created or updated with
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// ID is the id the provider assigned to the ingress
+	ID string `json:"id,omitempty"`
+	// Hostname is the hostname the ingress was created with
+	Hostname string `json:"hostname,omitempty"`
+	// Backends are the addresses of the Ready GameServers or GameServerInstances the ingress routes new players to
+	Backends []string `json:"backends,omitempty"`
+	// BackendsHash is a hash of the backends including their metadata, the ingress is updated when it changes
+	BackendsHash string `json:"backendsHash,omitempty"`
+	// Replicas is the amount of backends, including draining ones
+	Replicas int32 `json:"replicas"`
+	// Verified is whether the provider verified the ownership of the hostname
+	Verified bool `json:"verified,omitempty"`
+}
+
+// ListOptions returns the options to list the GameServers selected by the ingress
+func (ingress *GameServerIngress) ListOptions() ([]client.ListOption, error) {
+	selector, err := ingress.selector()
+	if err != nil {
+		return nil, err
+	}
+
+	return []client.ListOption{
+		client.InNamespace(ingress.ObjectMeta.Namespace),
+		client.MatchingLabelsSelector{Selector: selector},
+	}, nil
+}
+
+// Selects returns whether the GameServer is selected by the ingress, regardless of its state
+func (ingress *GameServerIngress) Selects(gs *GameServer) bool {
+	if gs.ObjectMeta.Namespace != ingress.ObjectMeta.Namespace {
+		return false
+	}
+
+	selector, err := ingress.selector()
+	if err != nil {
+		return false
+	}
+
+	return selector.Matches(labels.Set(gs.ObjectMeta.Labels))
+}
+
+// selector returns the label selector of the GameServers selected by the ingress
+func (ingress *GameServerIngress) selector() (labels.Selector, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&ingress.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	if ingress.Spec.FleetName != "" {
+		requirement, err := labels.NewRequirement(FleetNameLabel, "=", []string{ingress.Spec.FleetName})
+		if err != nil {
+			return nil, err
+		}
+		selector = selector.Add(*requirement)
+	}
+
+	return selector, nil
+}
+
+func init() {
+	SchemeBuilder.Register(&GameServerIngress{}, &GameServerIngressList{})
+}
@@ -19,9 +19,15 @@
 package v1
 
 import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"innit.gg/singularity/pkg/apis/singularity"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
 )
 
+var ErrorInstanceAtCapacity = errors.New("instance at capacity")
+
 const (
 	// GameServerInstanceStateStarting indicates that the GameServer is starting
 	GameServerInstanceStateStarting GameServerInstanceState = "Starting"
@@ -29,6 +35,11 @@ const (
 	GameServerInstanceStateReady GameServerInstanceState = "Ready"
 	// GameServerInstanceStateAllocated indicates that the GameServerInstance is currently running a game
 	GameServerInstanceStateAllocated GameServerInstanceState = "Allocated"
+
+	// GameServerInstanceMetadataDomain is the suffix of the Pod annotation key prefixes exposing instance metadata.
+	// The labels and annotations of an instance are exposed as JSON objects in the Pod annotations
+	// "<instance>.instances.singularity.innit.gg/labels" and "<instance>.instances.singularity.innit.gg/annotations".
+	GameServerInstanceMetadataDomain = "instances." + singularity.GroupName
 )
 
 //+kubebuilder:object:root=true
@@ -66,6 +77,9 @@ type GameServerInstanceSpec struct {
 	Capacity uint32 `json:"capacity"`
 	Map      string `json:"map"`
 	Extra    string `json:"extra,omitempty"`
+
+	// CountersAndLists are the initial counters and lists of the instance
+	CountersAndLists `json:",inline"`
 }
 
 type GameServerInstanceState string
@@ -73,6 +87,63 @@ type GameServerInstanceState string
 // GameServerInstanceStatus defines the observed state of GameServerInstance
 type GameServerInstanceStatus struct {
 	State GameServerInstanceState `json:"state"`
+	// Players are the players connected to the instance, bounded by its capacity
+	Players []string `json:"players,omitempty"`
+
+	// CountersAndLists are the counters and lists which changed since the instance was created
+	CountersAndLists `json:",inline"`
+}
+
+// AddPlayer adds the player to the instance, if it has the capacity for them
+func (gsInstance *GameServerInstance) AddPlayer(player string) error {
+	for _, p := range gsInstance.Status.Players {
+		if p == player {
+			return nil
+		}
+	}
+
+	if uint32(len(gsInstance.Status.Players)) >= gsInstance.Spec.Capacity {
+		return errors.Wrapf(ErrorInstanceAtCapacity, "%s", gsInstance.ObjectMeta.Name)
+	}
+
+	gsInstance.Status.Players = append(gsInstance.Status.Players, player)
+	return nil
+}
+
+// RemovePlayer removes the player from the instance
+func (gsInstance *GameServerInstance) RemovePlayer(player string) {
+	players := gsInstance.Status.Players[:0]
+	for _, p := range gsInstance.Status.Players {
+		if p != player {
+			players = append(players, p)
+		}
+	}
+	gsInstance.Status.Players = players
+}
+
+// MetadataAnnotations returns the Pod annotations exposing the labels and annotations of the instance,
+// see GameServerInstanceMetadataDomain
+func (gsInstance *GameServerInstance) MetadataAnnotations() map[string]string {
+	prefix := gsInstance.ObjectMeta.Name + "." + GameServerInstanceMetadataDomain + "/"
+	annotations := make(map[string]string, 2)
+	for name, m := range map[string]map[string]string{
+		"labels":      gsInstance.ObjectMeta.Labels,
+		"annotations": gsInstance.ObjectMeta.Annotations,
+	} {
+		if m == nil {
+			m = map[string]string{}
+		}
+		value, _ := json.Marshal(m)
+		annotations[prefix+name] = string(value)
+	}
+
+	return annotations
+}
+
+// IsInstanceMetadataAnnotation returns whether the Pod annotation exposes the metadata of an instance
+func IsInstanceMetadataAnnotation(key string) bool {
+	prefix, _, ok := strings.Cut(key, "/")
+	return ok && strings.HasSuffix(prefix, "."+GameServerInstanceMetadataDomain)
 }
 
 func init() {
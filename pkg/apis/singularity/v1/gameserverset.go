@@ -24,11 +24,18 @@ import (
 	"innit.gg/singularity/pkg/apis/singularity"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strconv"
 )
 
 const (
 	// GameServerSetNameLabel is the name of GameServerSet which owns resources like GameServer
 	GameServerSetNameLabel = singularity.GroupName + "/gameserverset"
+	// GameServerSetRevisionAnnotation is the revision number of a GameServerSet within its Fleet
+	GameServerSetRevisionAnnotation = singularity.GroupName + "/revision"
+	// TemplateHashLabel is the hash of the GameServerTemplate which resources like GameServerSet and GameServer
+	// were created from
+	TemplateHashLabel = singularity.GroupName + "/template-hash"
 )
 
 //+kubebuilder:object:root=true
@@ -77,6 +84,7 @@ type GameServerSetStatus struct {
 	Replicas           int32 `json:"replicas"`
 	ReadyReplicas      int32 `json:"readyReplicas"`
 	AllocatedReplicas  int32 `json:"allocatedReplicas"`
+	ReservedReplicas   int32 `json:"reservedReplicas"`
 	ShutdownReplicas   int32 `json:"shutdownReplicas"`
 	Instances          int32 `json:"instances"`
 	ReadyInstances     int32 `json:"readyInstances"`
@@ -104,13 +112,16 @@ func (gsSet *GameServerSet) GameServer() *GameServer {
 	ref := metav1.NewControllerRef(gsSet, GroupVersion.WithKind("GameServerSet"))
 	gs.ObjectMeta.OwnerReferences = append(gs.ObjectMeta.OwnerReferences, *ref)
 
-	// Append Fleet name and GameServerSet name labels
+	// Append Fleet name, GameServerSet name and template hash labels
 	if gs.ObjectMeta.Labels == nil {
-		gs.ObjectMeta.Labels = make(map[string]string, 2)
+		gs.ObjectMeta.Labels = make(map[string]string, 3)
 	}
 
 	gs.ObjectMeta.Labels[FleetNameLabel] = gsSet.ObjectMeta.Labels[FleetNameLabel]
 	gs.ObjectMeta.Labels[GameServerSetNameLabel] = gsSet.ObjectMeta.Name
+	if hash, ok := gsSet.ObjectMeta.Labels[TemplateHashLabel]; ok {
+		gs.ObjectMeta.Labels[TemplateHashLabel] = hash
+	}
 	return gs
 }
 
@@ -136,6 +147,44 @@ func (gsSet *GameServerSet) ListGameServer(ctx context.Context, c client.Client)
 	return result, nil
 }
 
+// Revision returns the revision number of the GameServerSet, or 0 if it has none
+func (gsSet *GameServerSet) Revision() int64 {
+	revision, err := strconv.ParseInt(gsSet.ObjectMeta.Annotations[GameServerSetRevisionAnnotation], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return revision
+}
+
+// SetRevision sets the revision number of the GameServerSet
+func (gsSet *GameServerSet) SetRevision(revision int64) {
+	if gsSet.ObjectMeta.Annotations == nil {
+		gsSet.ObjectMeta.Annotations = make(map[string]string, 1)
+	}
+	gsSet.ObjectMeta.Annotations[GameServerSetRevisionAnnotation] = strconv.FormatInt(revision, 10)
+}
+
+// MaxRevision returns the highest revision number in a list of GameServerSet
+func MaxRevision(list []*GameServerSet) int64 {
+	max := int64(0)
+	for _, gsSet := range list {
+		if revision := gsSet.Revision(); revision > max {
+			max = revision
+		}
+	}
+
+	return max
+}
+
+// SortByRevisionDescending returns GameServerSets sorted by newest revision
+func SortByRevisionDescending(list []*GameServerSet) []*GameServerSet {
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Revision() > list[j].Revision()
+	})
+
+	return list
+}
+
 func init() {
 	SchemeBuilder.Register(&GameServerSet{}, &GameServerSetList{})
 }
@@ -6,16 +6,76 @@
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Counter) DeepCopyInto(out *Counter) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Counter.
+func (in *Counter) DeepCopy() *Counter {
+	if in == nil {
+		return nil
+	}
+	out := new(Counter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CounterSelector) DeepCopyInto(out *CounterSelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CounterSelector.
+func (in *CounterSelector) DeepCopy() *CounterSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(CounterSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CountersAndLists) DeepCopyInto(out *CountersAndLists) {
+	*out = *in
+	if in.Counters != nil {
+		in, out := &in.Counters, &out.Counters
+		*out = make(map[string]Counter, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Lists != nil {
+		in, out := &in.Lists, &out.Lists
+		*out = make(map[string]List, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CountersAndLists.
+func (in *CountersAndLists) DeepCopy() *CountersAndLists {
+	if in == nil {
+		return nil
+	}
+	out := new(CountersAndLists)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Fleet) DeepCopyInto(out *Fleet) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Fleet.
@@ -36,6 +96,26 @@ func (in *Fleet) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FleetCanaryStep) DeepCopyInto(out *FleetCanaryStep) {
+	*out = *in
+	if in.Pause != nil {
+		in, out := &in.Pause, &out.Pause
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FleetCanaryStep.
+func (in *FleetCanaryStep) DeepCopy() *FleetCanaryStep {
+	if in == nil {
+		return nil
+	}
+	out := new(FleetCanaryStep)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FleetList) DeepCopyInto(out *FleetList) {
 	*out = *in
@@ -68,11 +148,48 @@ func (in *FleetList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FleetRollbackConfig) DeepCopyInto(out *FleetRollbackConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FleetRollbackConfig.
+func (in *FleetRollbackConfig) DeepCopy() *FleetRollbackConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(FleetRollbackConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FleetSpec) DeepCopyInto(out *FleetSpec) {
 	*out = *in
 	in.Strategy.DeepCopyInto(&out.Strategy)
 	in.Template.DeepCopyInto(&out.Template)
+	if in.RevisionHistoryLimit != nil {
+		in, out := &in.RevisionHistoryLimit, &out.RevisionHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RollbackTo != nil {
+		in, out := &in.RollbackTo, &out.RollbackTo
+		*out = new(FleetRollbackConfig)
+		**out = **in
+	}
+	if in.CanarySteps != nil {
+		in, out := &in.CanarySteps, &out.CanarySteps
+		*out = make([]FleetCanaryStep, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ProgressDeadlineSeconds != nil {
+		in, out := &in.ProgressDeadlineSeconds, &out.ProgressDeadlineSeconds
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FleetSpec.
@@ -88,6 +205,26 @@ func (in *FleetSpec) DeepCopy() *FleetSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FleetStatus) DeepCopyInto(out *FleetStatus) {
 	*out = *in
+	if in.CurrentStep != nil {
+		in, out := &in.CurrentStep, &out.CurrentStep
+		*out = new(int32)
+		**out = **in
+	}
+	if in.CurrentStepStartTime != nil {
+		in, out := &in.CurrentStepStartTime, &out.CurrentStepStartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CurrentStepAvailableTime != nil {
+		in, out := &in.CurrentStepAvailableTime, &out.CurrentStepAvailableTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FleetStatus.
@@ -106,7 +243,7 @@ func (in *GameServer) DeepCopyInto(out *GameServer) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServer.
@@ -127,6 +264,181 @@ func (in *GameServer) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GameServerAllocation) DeepCopyInto(out *GameServerAllocation) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerAllocation.
+func (in *GameServerAllocation) DeepCopy() *GameServerAllocation {
+	if in == nil {
+		return nil
+	}
+	out := new(GameServerAllocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GameServerAllocation) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GameServerAllocationList) DeepCopyInto(out *GameServerAllocationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GameServerAllocation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerAllocationList.
+func (in *GameServerAllocationList) DeepCopy() *GameServerAllocationList {
+	if in == nil {
+		return nil
+	}
+	out := new(GameServerAllocationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GameServerAllocationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GameServerAllocationMetadata) DeepCopyInto(out *GameServerAllocationMetadata) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerAllocationMetadata.
+func (in *GameServerAllocationMetadata) DeepCopy() *GameServerAllocationMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(GameServerAllocationMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GameServerAllocationPriority) DeepCopyInto(out *GameServerAllocationPriority) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerAllocationPriority.
+func (in *GameServerAllocationPriority) DeepCopy() *GameServerAllocationPriority {
+	if in == nil {
+		return nil
+	}
+	out := new(GameServerAllocationPriority)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GameServerAllocationSpec) DeepCopyInto(out *GameServerAllocationSpec) {
+	*out = *in
+	in.Required.DeepCopyInto(&out.Required)
+	if in.Preferred != nil {
+		in, out := &in.Preferred, &out.Preferred
+		*out = make([]metav1.LabelSelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Counters != nil {
+		in, out := &in.Counters, &out.Counters
+		*out = make(map[string]CounterSelector, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Lists != nil {
+		in, out := &in.Lists, &out.Lists
+		*out = make(map[string]ListSelector, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Priorities != nil {
+		in, out := &in.Priorities, &out.Priorities
+		*out = make([]GameServerAllocationPriority, len(*in))
+		copy(*out, *in)
+	}
+	if in.Instance != nil {
+		in, out := &in.Instance, &out.Instance
+		*out = new(GameServerInstanceAllocation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = new(GameServerAllocationMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerAllocationSpec.
+func (in *GameServerAllocationSpec) DeepCopy() *GameServerAllocationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GameServerAllocationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GameServerAllocationStatus) DeepCopyInto(out *GameServerAllocationStatus) {
+	*out = *in
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]GameServerStatusPort, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerAllocationStatus.
+func (in *GameServerAllocationStatus) DeepCopy() *GameServerAllocationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GameServerAllocationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GameServerDrainStrategy) DeepCopyInto(out *GameServerDrainStrategy) {
 	*out = *in
@@ -142,13 +454,143 @@ func (in *GameServerDrainStrategy) DeepCopy() *GameServerDrainStrategy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GameServerHealth) DeepCopyInto(out *GameServerHealth) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerHealth.
+func (in *GameServerHealth) DeepCopy() *GameServerHealth {
+	if in == nil {
+		return nil
+	}
+	out := new(GameServerHealth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GameServerIngress) DeepCopyInto(out *GameServerIngress) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerIngress.
+func (in *GameServerIngress) DeepCopy() *GameServerIngress {
+	if in == nil {
+		return nil
+	}
+	out := new(GameServerIngress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GameServerIngress) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GameServerIngressList) DeepCopyInto(out *GameServerIngressList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GameServerIngress, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerIngressList.
+func (in *GameServerIngressList) DeepCopy() *GameServerIngressList {
+	if in == nil {
+		return nil
+	}
+	out := new(GameServerIngressList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GameServerIngressList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GameServerIngressSpec) DeepCopyInto(out *GameServerIngressSpec) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+	if in.TCPShield != nil {
+		in, out := &in.TCPShield, &out.TCPShield
+		*out = new(GameServerIngressTCPShield)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerIngressSpec.
+func (in *GameServerIngressSpec) DeepCopy() *GameServerIngressSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GameServerIngressSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GameServerIngressStatus) DeepCopyInto(out *GameServerIngressStatus) {
+	*out = *in
+	if in.Backends != nil {
+		in, out := &in.Backends, &out.Backends
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerIngressStatus.
+func (in *GameServerIngressStatus) DeepCopy() *GameServerIngressStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GameServerIngressStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GameServerIngressTCPShield) DeepCopyInto(out *GameServerIngressTCPShield) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerIngressTCPShield.
+func (in *GameServerIngressTCPShield) DeepCopy() *GameServerIngressTCPShield {
+	if in == nil {
+		return nil
+	}
+	out := new(GameServerIngressTCPShield)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GameServerInstance) DeepCopyInto(out *GameServerInstance) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerInstance.
@@ -169,6 +611,22 @@ func (in *GameServerInstance) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GameServerInstanceAllocation) DeepCopyInto(out *GameServerInstanceAllocation) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerInstanceAllocation.
+func (in *GameServerInstanceAllocation) DeepCopy() *GameServerInstanceAllocation {
+	if in == nil {
+		return nil
+	}
+	out := new(GameServerInstanceAllocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GameServerInstanceList) DeepCopyInto(out *GameServerInstanceList) {
 	*out = *in
@@ -204,6 +662,7 @@ func (in *GameServerInstanceList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GameServerInstanceSpec) DeepCopyInto(out *GameServerInstanceSpec) {
 	*out = *in
+	in.CountersAndLists.DeepCopyInto(&out.CountersAndLists)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerInstanceSpec.
@@ -219,6 +678,12 @@ func (in *GameServerInstanceSpec) DeepCopy() *GameServerInstanceSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GameServerInstanceStatus) DeepCopyInto(out *GameServerInstanceStatus) {
 	*out = *in
+	if in.Players != nil {
+		in, out := &in.Players, &out.Players
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.CountersAndLists.DeepCopyInto(&out.CountersAndLists)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerInstanceStatus.
@@ -235,7 +700,7 @@ func (in *GameServerInstanceStatus) DeepCopy() *GameServerInstanceStatus {
 func (in *GameServerInstanceTemplate) DeepCopyInto(out *GameServerInstanceTemplate) {
 	*out = *in
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerInstanceTemplate.
@@ -295,6 +760,41 @@ func (in *GameServerPort) DeepCopy() *GameServerPort {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GameServerRecyclePolicy) DeepCopyInto(out *GameServerRecyclePolicy) {
+	*out = *in
+	if in.MaxLifetime != nil {
+		in, out := &in.MaxLifetime, &out.MaxLifetime
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerRecyclePolicy.
+func (in *GameServerRecyclePolicy) DeepCopy() *GameServerRecyclePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(GameServerRecyclePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GameServerSDKServer) DeepCopyInto(out *GameServerSDKServer) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerSDKServer.
+func (in *GameServerSDKServer) DeepCopy() *GameServerSDKServer {
+	if in == nil {
+		return nil
+	}
+	out := new(GameServerSDKServer)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GameServerSet) DeepCopyInto(out *GameServerSet) {
 	*out = *in
@@ -413,6 +913,22 @@ func (in *GameServerSpec) DeepCopyInto(out *GameServerSpec) {
 	}
 	in.InstanceTemplate.DeepCopyInto(&out.InstanceTemplate)
 	in.Template.DeepCopyInto(&out.Template)
+	if in.RecyclePolicy != nil {
+		in, out := &in.RecyclePolicy, &out.RecyclePolicy
+		*out = new(GameServerRecyclePolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Health != nil {
+		in, out := &in.Health, &out.Health
+		*out = new(GameServerHealth)
+		**out = **in
+	}
+	if in.SDKServer != nil {
+		in, out := &in.SDKServer, &out.SDKServer
+		*out = new(GameServerSDKServer)
+		**out = **in
+	}
+	in.CountersAndLists.DeepCopyInto(&out.CountersAndLists)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerSpec.
@@ -428,6 +944,24 @@ func (in *GameServerSpec) DeepCopy() *GameServerSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GameServerStatus) DeepCopyInto(out *GameServerStatus) {
 	*out = *in
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]GameServerStatusPort, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReservedUntil != nil {
+		in, out := &in.ReservedUntil, &out.ReservedUntil
+		*out = (*in).DeepCopy()
+	}
+	if in.FirstReady != nil {
+		in, out := &in.FirstReady, &out.FirstReady
+		*out = (*in).DeepCopy()
+	}
+	if in.LastHealthy != nil {
+		in, out := &in.LastHealthy, &out.LastHealthy
+		*out = (*in).DeepCopy()
+	}
+	in.CountersAndLists.DeepCopyInto(&out.CountersAndLists)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerStatus.
@@ -440,6 +974,21 @@ func (in *GameServerStatus) DeepCopy() *GameServerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GameServerStatusPort) DeepCopyInto(out *GameServerStatusPort) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerStatusPort.
+func (in *GameServerStatusPort) DeepCopy() *GameServerStatusPort {
+	if in == nil {
+		return nil
+	}
+	out := new(GameServerStatusPort)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GameServerTemplate) DeepCopyInto(out *GameServerTemplate) {
 	*out = *in
@@ -456,3 +1005,38 @@ func (in *GameServerTemplate) DeepCopy() *GameServerTemplate {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *List) DeepCopyInto(out *List) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new List.
+func (in *List) DeepCopy() *List {
+	if in == nil {
+		return nil
+	}
+	out := new(List)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ListSelector) DeepCopyInto(out *ListSelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ListSelector.
+func (in *ListSelector) DeepCopy() *ListSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(ListSelector)
+	in.DeepCopyInto(out)
+	return out
+}
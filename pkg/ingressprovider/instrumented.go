@@ -0,0 +1,117 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ingressprovider
+
+import (
+	"context"
+	"time"
+)
+
+type instrumented struct {
+	name     string
+	provider Provider
+	timeout  time.Duration
+}
+
+// Instrument wraps the provider, limiting the duration of its calls to the timeout and recording their metrics under the name.
+// Calls aren't limited if the timeout is zero.
+func Instrument(name string, provider Provider, timeout time.Duration) Provider {
+	return &instrumented{
+		name:     name,
+		provider: provider,
+		timeout:  timeout,
+	}
+}
+
+func (i *instrumented) Create(ctx context.Context, hostName string, backendSet []*Backend, opts Options) (string, error) {
+	var id string
+	err := i.call(ctx, "create", func(ctx context.Context) (err error) {
+		id, err = i.provider.Create(ctx, hostName, backendSet, opts)
+		return err
+	})
+
+	return id, err
+}
+
+func (i *instrumented) Update(ctx context.Context, hostName string, backendSet []*Backend, opts Options) error {
+	return i.call(ctx, "update", func(ctx context.Context) error {
+		return i.provider.Update(ctx, hostName, backendSet, opts)
+	})
+}
+
+func (i *instrumented) Delete(ctx context.Context, id string) error {
+	return i.call(ctx, "delete", func(ctx context.Context) error {
+		return i.provider.Delete(ctx, id)
+	})
+}
+
+func (i *instrumented) Get(ctx context.Context, id string) (*Ingress, error) {
+	var ingress *Ingress
+	err := i.call(ctx, "get", func(ctx context.Context) (err error) {
+		ingress, err = i.provider.Get(ctx, id)
+		return err
+	})
+
+	return ingress, err
+}
+
+func (i *instrumented) List(ctx context.Context) ([]*Ingress, error) {
+	var ingresses []*Ingress
+	err := i.call(ctx, "list", func(ctx context.Context) (err error) {
+		ingresses, err = i.provider.List(ctx)
+		return err
+	})
+
+	return ingresses, err
+}
+
+// Sweep sweeps the wrapped provider, if it implements Sweeper
+func (i *instrumented) Sweep(ctx context.Context) (int, error) {
+	sweeper, ok := i.provider.(Sweeper)
+	if !ok {
+		return 0, nil
+	}
+
+	var deleted int
+	err := i.call(ctx, "sweep", func(ctx context.Context) (err error) {
+		deleted, err = sweeper.Sweep(ctx)
+		return err
+	})
+
+	return deleted, err
+}
+
+// call calls f with the timeout, recording its metrics
+func (i *instrumented) call(ctx context.Context, method string, f func(ctx context.Context) error) error {
+	if i.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, i.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := f(ctx)
+	providerDuration.WithLabelValues(i.name, method).Observe(time.Since(start).Seconds())
+	providerCalls.WithLabelValues(i.name, method).Inc()
+	if err != nil {
+		providerErrors.WithLabelValues(i.name, method).Inc()
+	}
+
+	return err
+}
@@ -0,0 +1,121 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ingressprovider
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/util/flowcontrol"
+	"net/http"
+	"time"
+)
+
+// Limits configures the requests of a provider sent through a Limiter
+type Limits struct {
+	// QPS is the sustained rate of requests per second, requests exceeding it wait for the token bucket to refill.
+	// Requests aren't rate limited if it's zero.
+	QPS float32
+	// Burst is the amount of requests which may be sent at once
+	Burst int
+	// Retries is the maximum amount of times a request is retried, see Limiter.Do
+	Retries int
+	// Backoff is the delay before the first retry, doubled with every retry unless the API asks for a delay
+	Backoff time.Duration
+	// MaxBackoff is the maximum delay between retries
+	MaxBackoff time.Duration
+}
+
+// DefaultLimits are suitable for APIs which rate limit to a few requests per second
+var DefaultLimits = Limits{
+	QPS:        2,
+	Burst:      5,
+	Retries:    5,
+	Backoff:    500 * time.Millisecond,
+	MaxBackoff: 30 * time.Second,
+}
+
+// Limiter rate limits and retries the single requests a provider sends to its API.
+// Provider calls consist of several requests, so only the failed request is retried instead of the whole call.
+type Limiter struct {
+	name    string
+	limits  Limits
+	limiter flowcontrol.RateLimiter
+}
+
+// NewLimiter returns a limiter of the provider's requests, recording its retries under the name
+func NewLimiter(name string, limits Limits) *Limiter {
+	limiter := flowcontrol.NewFakeAlwaysRateLimiter()
+	if limits.QPS > 0 {
+		limiter = flowcontrol.NewTokenBucketRateLimiter(limits.QPS, limits.Burst)
+	}
+
+	return &Limiter{
+		name:    name,
+		limits:  limits,
+		limiter: limiter,
+	}
+}
+
+// Do sends the request once the rate limiter allows it, retrying it with exponential backoff as long as it's retryable.
+// Requests failing with a StatusError of 5xx are only retried if they're idempotent, as they may have been processed.
+// Those rejected with 429 Too Many Requests weren't processed, so they're retried either way.
+func (l *Limiter) Do(ctx context.Context, idempotent bool, request func(ctx context.Context) error) error {
+	backoff := l.limits.Backoff
+	for attempt := 0; ; attempt++ {
+		if err := l.limiter.Wait(ctx); err != nil {
+			return errors.Wrap(err, "error waiting for rate limiter")
+		}
+
+		err := request(ctx)
+
+		var statusErr *StatusError
+		if err == nil || !errors.As(err, &statusErr) || attempt >= l.limits.Retries {
+			return err
+		}
+		if statusErr.Code != http.StatusTooManyRequests && (!idempotent || !statusErr.Retryable()) {
+			return err
+		}
+
+		delay := backoff
+		if statusErr.RetryAfter > 0 {
+			delay = statusErr.RetryAfter
+		}
+		if l.limits.MaxBackoff > 0 && delay > l.limits.MaxBackoff {
+			delay = l.limits.MaxBackoff
+		}
+		backoff *= 2
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		providerRetries.WithLabelValues(l.name).Inc()
+	}
+}
+
+// Idempotent returns whether requests of the HTTP method can be sent repeatedly without changing their effect
+func Idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,212 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ingressprovider
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLimiterRetry(t *testing.T) {
+	tests := []struct {
+		name        string
+		idempotent  bool
+		errs        []error
+		wantCalls   int
+		wantRetries float64
+		wantErr     bool
+	}{
+		{name: "success", idempotent: true, errs: []error{nil}, wantCalls: 1},
+		{
+			name:        "idempotent server error",
+			idempotent:  true,
+			errs:        []error{&StatusError{Code: http.StatusBadGateway}, nil},
+			wantCalls:   2,
+			wantRetries: 1,
+		},
+		{
+			name:      "server error",
+			errs:      []error{&StatusError{Code: http.StatusBadGateway}, nil},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:        "too many requests",
+			errs:        []error{&StatusError{Code: http.StatusTooManyRequests}, nil},
+			wantCalls:   2,
+			wantRetries: 1,
+		},
+		{
+			name:       "client error",
+			idempotent: true,
+			errs:       []error{&StatusError{Code: http.StatusNotFound}, nil},
+			wantCalls:  1,
+			wantErr:    true,
+		},
+		{
+			name:       "transport error",
+			idempotent: true,
+			errs:       []error{errors.New("connection refused"), nil},
+			wantCalls:  1,
+			wantErr:    true,
+		},
+		{
+			name:        "retries exhausted",
+			idempotent:  true,
+			errs:        []error{&StatusError{Code: 500}, &StatusError{Code: 500}, &StatusError{Code: 500}, nil},
+			wantCalls:   3,
+			wantRetries: 2,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLimiter(t.Name(), Limits{Retries: 2, Backoff: time.Millisecond})
+
+			calls := 0
+			err := l.Do(context.Background(), tt.idempotent, func(ctx context.Context) error {
+				calls++
+				return tt.errs[calls-1]
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Do() error = %v, want error %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if retries := testutil.ToFloat64(providerRetries.WithLabelValues(t.Name())); retries != tt.wantRetries {
+				t.Errorf("retries = %v, want %v", retries, tt.wantRetries)
+			}
+		})
+	}
+}
+
+func TestLimiterRetryAfter(t *testing.T) {
+	l := NewLimiter(t.Name(), Limits{Retries: 1, Backoff: time.Hour, MaxBackoff: 10 * time.Millisecond})
+
+	// The delay asked for by the API is limited to MaxBackoff as well
+	calls := 0
+	start := time.Now()
+	err := l.Do(context.Background(), false, func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return &StatusError{Code: http.StatusTooManyRequests, RetryAfter: time.Hour}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond || elapsed > time.Minute {
+		t.Errorf("retried after %s, want after the maximum backoff of 10ms", elapsed)
+	}
+}
+
+func TestLimiterRateLimit(t *testing.T) {
+	l := NewLimiter(t.Name(), Limits{QPS: 0.001, Burst: 1})
+	request := func(ctx context.Context) error {
+		return nil
+	}
+
+	if err := l.Do(context.Background(), true, request); err != nil {
+		t.Fatal(err)
+	}
+
+	// The burst is used up, so the next request has to wait far longer than the context allows
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	sent := false
+	err := l.Do(ctx, true, func(ctx context.Context) error {
+		sent = true
+		return nil
+	})
+	if err == nil || sent {
+		t.Errorf("Do() error = %v, sent %v, want the request to be rate limited", err, sent)
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	for method, want := range map[string]bool{
+		http.MethodGet:    true,
+		http.MethodPut:    true,
+		http.MethodDelete: true,
+		http.MethodPost:   false,
+		http.MethodPatch:  false,
+	} {
+		if got := Idempotent(method); got != want {
+			t.Errorf("Idempotent(%s) = %v, want %v", method, got, want)
+		}
+	}
+}
+
+func TestInstrument(t *testing.T) {
+	ctx := context.Background()
+	provider := &failingProvider{err: errors.New("unavailable")}
+	p := Instrument(t.Name(), provider, time.Minute)
+
+	if _, err := p.List(ctx); err == nil {
+		t.Fatal("List() error = nil, want the provider's error")
+	}
+	provider.err = nil
+	if _, err := p.List(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := testutil.ToFloat64(providerCalls.WithLabelValues(t.Name(), "list")); calls != 2 {
+		t.Errorf("calls = %v, want 2", calls)
+	}
+	if errs := testutil.ToFloat64(providerErrors.WithLabelValues(t.Name(), "list")); errs != 1 {
+		t.Errorf("errors = %v, want 1", errs)
+	}
+	if !provider.deadline {
+		t.Error("call without a deadline, want the timeout to be applied")
+	}
+}
+
+// failingProvider fails its calls with err, recording whether their context had a deadline
+type failingProvider struct {
+	err      error
+	deadline bool
+}
+
+func (p *failingProvider) Create(context.Context, string, []*Backend, Options) (string, error) {
+	return "", p.err
+}
+
+func (p *failingProvider) Update(context.Context, string, []*Backend, Options) error {
+	return p.err
+}
+
+func (p *failingProvider) Delete(context.Context, string) error {
+	return p.err
+}
+
+func (p *failingProvider) Get(context.Context, string) (*Ingress, error) {
+	return nil, p.err
+}
+
+func (p *failingProvider) List(ctx context.Context) ([]*Ingress, error) {
+	_, p.deadline = ctx.Deadline()
+	return nil, p.err
+}
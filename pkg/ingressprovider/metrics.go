@@ -0,0 +1,64 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ingressprovider
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// providerCalls is the amount of calls to providers
+	providerCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "singularity",
+		Subsystem: "ingressprovider",
+		Name:      "calls_total",
+		Help:      "Total amount of ingress provider calls",
+	}, []string{"provider", "method"})
+
+	// providerErrors is the amount of failed calls to providers
+	providerErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "singularity",
+		Subsystem: "ingressprovider",
+		Name:      "errors_total",
+		Help:      "Total amount of failed ingress provider calls",
+	}, []string{"provider", "method"})
+
+	// providerRetries is the amount of requests providers retried
+	providerRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "singularity",
+		Subsystem: "ingressprovider",
+		Name:      "retries_total",
+		Help:      "Total amount of retried ingress provider requests",
+	}, []string{"provider"})
+
+	// providerDuration is the latency of single calls to providers
+	providerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "singularity",
+		Subsystem: "ingressprovider",
+		Name:      "call_duration_seconds",
+		Help:      "Latency of ingress provider calls in seconds",
+		Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"provider", "method"})
+)
+
+func init() {
+	// Served by the metrics endpoint of the manager
+	metrics.Registry.MustRegister(providerCalls, providerErrors, providerRetries, providerDuration)
+}
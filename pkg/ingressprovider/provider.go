@@ -18,21 +18,235 @@
 
 package ingressprovider
 
-import "net"
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"hash/fnv"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorNotFound is returned by providers when the ingress to update doesn't exist anymore
+var ErrorNotFound = errors.New("ingress not found")
 
 type Provider interface {
 	// Create creates an ingress and return the id
-	Create(hostName string, backendSet []*Backend) (string, error)
+	Create(ctx context.Context, hostName string, backendSet []*Backend, opts Options) (string, error)
 
-	// Update updates an existing ingress' backends
-	Update(hostName string, backendSet []*Backend) error
+	// Update updates an existing ingress' backends and options
+	Update(ctx context.Context, hostName string, backendSet []*Backend, opts Options) error
 
 	// Delete deletes an existing ingress
-	Delete(id string) error
+	Delete(ctx context.Context, id string) error
+
+	// Get returns the current state of an existing ingress, or ErrorNotFound
+	Get(ctx context.Context, id string) (*Ingress, error)
+
+	// List returns the current state of all ingresses
+	List(ctx context.Context) ([]*Ingress, error)
+}
+
+// Options configure an ingress independent of its provider
+type Options struct {
+	// ProxyProtocol sends the PROXY protocol header to the backends, so they see the addresses of clients
+	ProxyProtocol bool
+	// Extensions are provider specific options, e.g. *tcpshield.Extension.
+	// Providers ignore the extensions of other providers.
+	Extensions []interface{}
+}
+
+// Matches returns whether the options of an ingress reported by a provider match the desired ones.
+// Extensions which aren't part of the desired options are compared with their zero value.
+func (o Options) Matches(actual Options) bool {
+	if o.ProxyProtocol != actual.ProxyProtocol {
+		return false
+	}
+
+	for _, extension := range actual.Extensions {
+		if extension == nil {
+			continue
+		}
+
+		// Extensions are usually pointers, whose zero value is a pointer to the zero value of their element
+		t := reflect.TypeOf(extension)
+		desired := reflect.Zero(t).Interface()
+		if t.Kind() == reflect.Ptr {
+			desired = reflect.New(t.Elem()).Interface()
+		}
+		for _, e := range o.Extensions {
+			if reflect.TypeOf(e) == t {
+				desired = e
+			}
+		}
+		if !reflect.DeepEqual(desired, extension) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Ingress is the state of an ingress as reported by its provider
+type Ingress struct {
+	ID         string
+	HostName   string
+	BackendSet []*Backend
+	Options    Options
+	// Verified is whether the provider verified the ownership of the hostname, always true if it doesn't verify it
+	Verified bool
 }
 
+// Sweeper is implemented by providers which can remove resources leaked by failed or interrupted calls
+type Sweeper interface {
+	// Sweep deletes the resources created for ingresses which don't exist anymore, returning how many were deleted
+	Sweep(ctx context.Context) (int, error)
+}
+
+// Protocol is the transport protocol of a backend
+type Protocol string
+
+const (
+	ProtocolTCP Protocol = "TCP"
+	// ProtocolUDP is used by e.g. Bedrock edition servers
+	ProtocolUDP Protocol = "UDP"
+)
+
+// DefaultWeight is the weight of backends which weren't weighted
+const DefaultWeight = 1
+
 // Backend represents a Minecraft server's connection details
 type Backend struct {
 	IP   net.IP
 	Port uint16
+	// Protocol is the transport protocol of the port, TCP if empty
+	Protocol Protocol
+	// Weight is the share of new connections the backend receives relative to the others
+	Weight uint16
+	// Draining backends don't receive new connections, but existing ones aren't interrupted.
+	// Providers which can't drain backends remove them instead.
+	Draining bool
+
+	// Name identifies the backend, e.g. the name of its GameServer or GameServerInstance
+	Name string
+	// Metadata describes the backend to providers which publish it, e.g. its map, capacity and players
+	Metadata map[string]string
+}
+
+// ResourceName returns the name of the Kubernetes resource of the hostname, e.g. its ConfigMap or Service.
+// The name is a DNS label starting with the prefix, followed by the readable part of the hostname and its hash.
+// Hostnames only differing in characters which aren't allowed in labels, e.g. play-eu.example.com and
+// play.eu.example.com or wildcards, have different names, as their hashes differ.
+func ResourceName(prefix, hostName string) string {
+	hostName = strings.ToLower(hostName)
+	hasher := fnv.New32a()
+	_, _ = hasher.Write([]byte(hostName))
+	suffix := fmt.Sprintf("%08x", hasher.Sum32())
+
+	name := strings.Trim(strings.Map(func(r rune) rune {
+		if ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '-'
+	}, hostName), "-")
+	if max := validation.DNS1123LabelMaxLength - len(prefix) - len(suffix) - 1; len(name) > max {
+		name = strings.TrimRight(name[:max], "-")
+	}
+	if name == "" {
+		return prefix + suffix
+	}
+
+	return prefix + name + "-" + suffix
+}
+
+// Address returns the address of the backend, with IPv6 addresses in brackets
+func (b *Backend) Address() string {
+	return net.JoinHostPort(b.IP.String(), strconv.Itoa(int(b.Port)))
+}
+
+// IsUDP returns whether the backend uses UDP
+func (b *Backend) IsUDP() bool {
+	return b.Protocol == ProtocolUDP
+}
+
+// Feature is a capability of providers which ingresses may require
+type Feature string
+
+const (
+	FeatureProxyProtocol Feature = "ProxyProtocol"
+	FeatureUDP           Feature = "UDP"
+	FeatureWeights       Feature = "Weights"
+)
+
+// UnsupportedError is returned by providers when an ingress requires a feature they don't support
+type UnsupportedError struct {
+	Feature Feature
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("feature not supported: %s", e.Feature)
+}
+
+// CheckFeatures returns an *UnsupportedError for the first feature required by the backends and options
+// which isn't supported. Weights are only required if the backends which aren't draining are weighted differently.
+func CheckFeatures(backendSet []*Backend, opts Options, supported ...Feature) error {
+	has := make(map[Feature]bool, len(supported))
+	for _, feature := range supported {
+		has[feature] = true
+	}
+
+	if opts.ProxyProtocol && !has[FeatureProxyProtocol] {
+		return &UnsupportedError{Feature: FeatureProxyProtocol}
+	}
+
+	weight := -1
+	for _, backend := range backendSet {
+		if backend.IsUDP() && !has[FeatureUDP] {
+			return &UnsupportedError{Feature: FeatureUDP}
+		}
+		if backend.Draining {
+			continue
+		}
+		if weight >= 0 && int(backend.Weight) != weight && !has[FeatureWeights] {
+			return &UnsupportedError{Feature: FeatureWeights}
+		}
+		weight = int(backend.Weight)
+	}
+
+	return nil
+}
+
+// StatusError is returned by providers when their API responds with an unexpected status code
+type StatusError struct {
+	Code int
+	// RetryAfter is the delay the API asked for before retrying, zero if it didn't
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.Code)
+}
+
+// Retryable returns whether the request may succeed if it's retried
+func (e *StatusError) Retryable() bool {
+	return e.Code == http.StatusTooManyRequests || e.Code >= http.StatusInternalServerError
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, either in seconds or as an HTTP date
+func ParseRetryAfter(value string) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+
+	return 0
 }
@@ -0,0 +1,206 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ingressprovider
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"net"
+	"strings"
+	"testing"
+)
+
+type pointerExtension struct {
+	Enabled bool
+}
+
+type valueExtension struct {
+	Enabled bool
+}
+
+func TestOptionsMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired Options
+		actual  Options
+		want    bool
+	}{
+		{name: "empty", want: true},
+		{name: "proxy protocol differs", desired: Options{ProxyProtocol: true}},
+		{
+			name:    "pointer extension matches",
+			desired: Options{Extensions: []interface{}{&pointerExtension{Enabled: true}}},
+			actual:  Options{Extensions: []interface{}{&pointerExtension{Enabled: true}}},
+			want:    true,
+		},
+		{
+			name:    "pointer extension differs",
+			desired: Options{Extensions: []interface{}{&pointerExtension{}}},
+			actual:  Options{Extensions: []interface{}{&pointerExtension{Enabled: true}}},
+		},
+		{
+			name:   "missing pointer extension is its zero value",
+			actual: Options{Extensions: []interface{}{&pointerExtension{}}},
+			want:   true,
+		},
+		{
+			name:   "missing pointer extension differs from its zero value",
+			actual: Options{Extensions: []interface{}{&pointerExtension{Enabled: true}}},
+		},
+		{
+			name:    "value extension matches",
+			desired: Options{Extensions: []interface{}{valueExtension{Enabled: true}}},
+			actual:  Options{Extensions: []interface{}{valueExtension{Enabled: true}}},
+			want:    true,
+		},
+		{
+			name:   "missing value extension is its zero value",
+			actual: Options{Extensions: []interface{}{valueExtension{}}},
+			want:   true,
+		},
+		{
+			name:   "missing value extension differs from its zero value",
+			actual: Options{Extensions: []interface{}{valueExtension{Enabled: true}}},
+		},
+		{
+			name:    "extensions of other providers are ignored",
+			desired: Options{Extensions: []interface{}{&pointerExtension{Enabled: true}}},
+			actual:  Options{Extensions: []interface{}{valueExtension{}, nil}},
+			want:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.desired.Matches(tt.actual); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourceName(t *testing.T) {
+	hostNames := []string{
+		"play.example.com",
+		"play-eu.example.com",
+		"play.eu.example.com",
+		"*.example.com",
+		"PLAY.example.com.",
+		"...",
+		strings.Repeat("long-subdomain.", 10) + "example.com",
+		strings.Repeat("long-subdomain.", 10) + "example.org",
+	}
+
+	names := make(map[string]string, len(hostNames))
+	for _, hostName := range hostNames {
+		name := ResourceName("ingress-", hostName)
+		if errs := validation.IsDNS1035Label(name); len(errs) > 0 {
+			t.Errorf("ResourceName(%q) = %q, which isn't a DNS label: %v", hostName, name, errs)
+		}
+		if !strings.HasPrefix(name, "ingress-") {
+			t.Errorf("ResourceName(%q) = %q, want the prefix ingress-", hostName, name)
+		}
+		if other, ok := names[name]; ok {
+			t.Errorf("ResourceName(%q) = ResourceName(%q) = %q, want different names", hostName, other, name)
+		}
+		names[name] = hostName
+	}
+
+	// Hostnames are case insensitive
+	if ResourceName("ingress-", "Play.Example.com") != ResourceName("ingress-", "play.example.com") {
+		t.Error("names of hostnames only differing in case differ")
+	}
+}
+
+func TestBackendAddress(t *testing.T) {
+	tests := []struct {
+		ip   string
+		port uint16
+		want string
+	}{
+		{ip: "10.0.0.1", port: 25565, want: "10.0.0.1:25565"},
+		{ip: "::ffff:10.0.0.1", port: 25565, want: "10.0.0.1:25565"},
+		{ip: "fd00::1", port: 25565, want: "[fd00::1]:25565"},
+		{ip: "2001:db8:0:0:0:0:0:1", port: 19132, want: "[2001:db8::1]:19132"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			backend := &Backend{IP: net.ParseIP(tt.ip), Port: tt.port}
+			if got := backend.Address(); got != tt.want {
+				t.Errorf("Address() = %s, want %s", got, tt.want)
+			}
+			if _, _, err := net.SplitHostPort(backend.Address()); err != nil {
+				t.Errorf("Address() = %s can't be split: %v", backend.Address(), err)
+			}
+		})
+	}
+}
+
+func TestCheckFeatures(t *testing.T) {
+	tcp := func(weight uint16, draining bool) *Backend {
+		return &Backend{IP: net.IPv4(10, 0, 0, 1), Port: 25565, Protocol: ProtocolTCP, Weight: weight, Draining: draining}
+	}
+	udp := &Backend{IP: net.IPv4(10, 0, 0, 2), Port: 19132, Protocol: ProtocolUDP, Weight: DefaultWeight}
+
+	tests := []struct {
+		name       string
+		backendSet []*Backend
+		opts       Options
+		supported  []Feature
+		want       Feature
+	}{
+		{name: "no backends"},
+		{name: "equally weighted TCP backends", backendSet: []*Backend{tcp(1, false), tcp(1, false), {IP: net.IPv4(10, 0, 0, 3), Weight: 1}}},
+		{name: "proxy protocol", opts: Options{ProxyProtocol: true}, want: FeatureProxyProtocol},
+		{name: "supported proxy protocol", opts: Options{ProxyProtocol: true}, supported: []Feature{FeatureProxyProtocol}},
+		{name: "UDP", backendSet: []*Backend{tcp(1, false), udp}, want: FeatureUDP},
+		{name: "supported UDP", backendSet: []*Backend{tcp(1, false), udp}, supported: []Feature{FeatureUDP}},
+		{name: "draining UDP", backendSet: []*Backend{{IP: udp.IP, Protocol: ProtocolUDP, Draining: true}}, want: FeatureUDP},
+		{name: "weights", backendSet: []*Backend{tcp(1, false), tcp(2, false)}, want: FeatureWeights},
+		{name: "supported weights", backendSet: []*Backend{tcp(1, false), tcp(2, false)}, supported: []Feature{FeatureWeights}},
+		{name: "single weighted backend", backendSet: []*Backend{tcp(5, false)}},
+		// Draining backends don't receive new connections, so their weight doesn't matter
+		{name: "draining backend", backendSet: []*Backend{tcp(1, false), tcp(0, true)}},
+		{
+			name:       "first unsupported feature",
+			backendSet: []*Backend{tcp(1, false), tcp(2, false), udp},
+			opts:       Options{ProxyProtocol: true},
+			supported:  []Feature{FeatureProxyProtocol},
+			want:       FeatureWeights,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckFeatures(tt.backendSet, tt.opts, tt.supported...)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("CheckFeatures() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var unsupported *UnsupportedError
+			if !errors.As(err, &unsupported) || unsupported.Feature != tt.want {
+				t.Errorf("CheckFeatures() error = %v, want %s to be unsupported", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package fake implements an in-memory authoritative DNS server for a single zone, accepting the dynamic updates,
+// queries and zone transfers of the rfc2136 provider
+package fake
+
+import (
+	"github.com/miekg/dns"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is an in-memory authoritative DNS server for a single zone
+type Server struct {
+	Zone string
+
+	keyName string
+	secret  string
+
+	mu       sync.Mutex
+	records  []dns.RR
+	failures []int
+
+	server *dns.Server
+}
+
+// NewServer returns a server for the zone. Requests have to be signed with the key if a name is given.
+func NewServer(zone, keyName, secret string) *Server {
+	return &Server{
+		Zone:    dns.Fqdn(strings.ToLower(zone)),
+		keyName: dns.Fqdn(strings.ToLower(keyName)),
+		secret:  secret,
+	}
+}
+
+// Start serves the zone over TCP on a random local port, returning its address
+func (s *Server) Start() (string, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+
+	started := make(chan struct{})
+	s.server = &dns.Server{
+		Listener:          ln,
+		Handler:           dns.HandlerFunc(s.serveDNS),
+		NotifyStartedFunc: func() { close(started) },
+		MsgAcceptFunc:     acceptMsg,
+	}
+	if s.keyName != "." {
+		s.server.TsigSecret = map[string]string{s.keyName: s.secret}
+	}
+
+	go func() {
+		_ = s.server.ActivateAndServe()
+	}()
+	<-started
+
+	return ln.Addr().String(), nil
+}
+
+// Close stops serving the zone
+func (s *Server) Close() error {
+	return s.server.Shutdown()
+}
+
+// Fail makes the next requests fail with the response codes, one per request
+func (s *Server) Fail(rcodes ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = append(s.failures, rcodes...)
+}
+
+// Add adds records to the zone, e.g. ones which weren't created through dynamic updates
+func (s *Server) Add(records ...dns.RR) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rr := range records {
+		s.records = append(s.records, dns.Copy(rr))
+	}
+}
+
+// Records returns a copy of the records of the zone, ordered by name and type
+func (s *Server) Records() []dns.RR {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := make([]dns.RR, len(s.records))
+	for i, rr := range s.records {
+		records[i] = dns.Copy(rr)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Header().Name != records[j].Header().Name {
+			return records[i].Header().Name < records[j].Header().Name
+		}
+		return records[i].Header().Rrtype < records[j].Header().Rrtype
+	})
+
+	return records
+}
+
+// acceptMsg accepts dynamic updates in addition to the messages accepted by default
+func acceptMsg(dh dns.Header) dns.MsgAcceptAction {
+	isResponse := dh.Bits&(1<<15) != 0
+	if opcode := int(dh.Bits>>11) & 0xF; opcode == dns.OpcodeUpdate && !isResponse {
+		return dns.MsgAccept
+	}
+
+	return dns.DefaultMsgAcceptFunc(dh)
+}
+
+// failure returns the next injected response code, or false if there is none
+func (s *Server) failure() (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.failures) == 0 {
+		return 0, false
+	}
+	rcode := s.failures[0]
+	s.failures = s.failures[1:]
+
+	return rcode, true
+}
+
+func (s *Server) serveDNS(w dns.ResponseWriter, req *dns.Msg) {
+	res := &dns.Msg{}
+	res.SetReply(req)
+	res.Authoritative = true
+
+	switch {
+	case len(req.Question) != 1 || !dns.IsSubDomain(s.Zone, strings.ToLower(req.Question[0].Name)):
+		res.Rcode = dns.RcodeNotZone
+	case s.keyName != "." && (req.IsTsig() == nil || w.TsigStatus() != nil):
+		res.Rcode = dns.RcodeNotAuth
+	default:
+		if rcode, ok := s.failure(); ok {
+			res.Rcode = rcode
+			break
+		}
+
+		switch {
+		case req.Opcode == dns.OpcodeUpdate:
+			s.update(req.Ns)
+		case req.Question[0].Qtype == dns.TypeAXFR:
+			res.Answer = s.transfer()
+		default:
+			res.Answer, res.Rcode = s.query(req.Question[0])
+		}
+	}
+
+	if tsig := req.IsTsig(); tsig != nil && w.TsigStatus() == nil {
+		res.SetTsig(tsig.Hdr.Name, tsig.Algorithm, 300, time.Now().Unix())
+	}
+	_ = w.WriteMsg(res)
+}
+
+// update applies the update section of a dynamic update, see RFC 2136 section 3.4.2.
+// Prerequisites aren't checked.
+func (s *Server) update(updates []dns.RR) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, update := range updates {
+		h := update.Header()
+		switch h.Class {
+		case dns.ClassANY:
+			s.remove(func(rr dns.RR) bool {
+				return strings.EqualFold(rr.Header().Name, h.Name) && (h.Rrtype == dns.TypeANY || rr.Header().Rrtype == h.Rrtype)
+			})
+		case dns.ClassNONE:
+			s.remove(func(rr dns.RR) bool {
+				rr = dns.Copy(rr)
+				rr.Header().Class, rr.Header().Ttl = dns.ClassNONE, 0
+				return dns.IsDuplicate(rr, update)
+			})
+		default:
+			rr := dns.Copy(update)
+			s.remove(func(existing dns.RR) bool {
+				return dns.IsDuplicate(existing, rr)
+			})
+			s.records = append(s.records, rr)
+		}
+	}
+}
+
+// remove removes the records matching the function
+func (s *Server) remove(f func(rr dns.RR) bool) {
+	records := s.records[:0]
+	for _, rr := range s.records {
+		if !f(rr) {
+			records = append(records, rr)
+		}
+	}
+	s.records = records
+}
+
+// query returns the records of the question's name and type
+func (s *Server) query(q dns.Question) ([]dns.RR, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var answer []dns.RR
+	exists := false
+	for _, rr := range s.records {
+		if !strings.EqualFold(rr.Header().Name, q.Name) {
+			continue
+		}
+		exists = true
+		if rr.Header().Rrtype == q.Qtype || q.Qtype == dns.TypeANY {
+			answer = append(answer, dns.Copy(rr))
+		}
+	}
+
+	if !exists {
+		return nil, dns.RcodeNameError
+	}
+	return answer, dns.RcodeSuccess
+}
+
+// transfer returns the records of the zone, enclosed by its SOA record
+func (s *Server) transfer() []dns.RR {
+	soa := &dns.SOA{
+		Hdr:     dns.RR_Header{Name: s.Zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 3600},
+		Ns:      "ns." + s.Zone,
+		Mbox:    "hostmaster." + s.Zone,
+		Serial:  1,
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  60,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := []dns.RR{soa}
+	for _, rr := range s.records {
+		records = append(records, dns.Copy(rr))
+	}
+
+	return append(records, soa)
+}
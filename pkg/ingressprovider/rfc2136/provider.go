@@ -0,0 +1,471 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package rfc2136
+
+import (
+	"context"
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+	"innit.gg/singularity/pkg/ingressprovider"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// SRVPrefix prefixes the hostname of the SRV records Minecraft clients resolve
+	SRVPrefix = "_minecraft._tcp."
+	// TargetPrefix prefixes the names of the SRV targets, which are created below the hostname for each backend address
+	TargetPrefix = "ip-"
+	// MarkerText is the text of the TXT record next to the SRV records, which marks the hostname as an ingress,
+	// even if it has no backends
+	MarkerText = "heritage=singularity"
+
+	// DefaultTTL is the default TTL of the records
+	DefaultTTL = time.Minute
+	// drainingPriority is the SRV priority of draining backends, which clients only use if no other backend is reachable
+	drainingPriority = 1
+)
+
+var (
+	ErrorRecordsNotFound = errors.Wrap(ingressprovider.ErrorNotFound, "records not found")
+	// ErrorRecordsConflict is returned when a hostname has records, but isn't marked as an ingress
+	ErrorRecordsConflict = errors.New("records weren't created by singularity")
+
+	// supported are the features of the records, which point at the backends directly and only describe TCP services
+	supported = []ingressprovider.Feature{ingressprovider.FeatureWeights}
+)
+
+type provider struct {
+	server string
+	zone   string
+	ttl    uint32
+
+	keyName   string
+	algorithm string
+	client    *dns.Client
+	transfer  *dns.Transfer
+}
+
+// Option configures the provider
+type Option func(p *provider)
+
+// WithTSIG signs the updates, queries and zone transfers with the base64 encoded secret of the key
+func WithTSIG(keyName, secret, algorithm string) Option {
+	return func(p *provider) {
+		p.keyName = dns.Fqdn(strings.ToLower(keyName))
+		p.algorithm = dns.Fqdn(algorithm)
+		p.client.TsigSecret = map[string]string{p.keyName: secret}
+		p.transfer.TsigSecret = p.client.TsigSecret
+	}
+}
+
+// WithTTL changes the TTL of the records
+func WithTTL(ttl time.Duration) Option {
+	return func(p *provider) {
+		p.ttl = uint32(ttl.Seconds())
+	}
+}
+
+// CreateProvider creates a provider publishing A, AAAA and SRV records of hostnames in the zone,
+// through dynamic updates sent to the authoritative server at the address.
+// Ingresses are listed through zone transfers, which the server has to allow.
+func CreateProvider(server, zone string, opts ...Option) ingressprovider.Provider {
+	p := &provider{
+		server: server,
+		zone:   dns.Fqdn(strings.ToLower(zone)),
+		ttl:    uint32(DefaultTTL.Seconds()),
+		// Large record sets don't fit into UDP messages
+		client:   &dns.Client{Net: "tcp"},
+		transfer: &dns.Transfer{},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+func (p *provider) Create(ctx context.Context, hostName string, backendSet []*ingressprovider.Backend, opts ingressprovider.Options) (string, error) {
+	if err := ingressprovider.CheckFeatures(backendSet, opts, supported...); err != nil {
+		return "", err
+	}
+
+	name, err := p.name(hostName)
+	if err != nil {
+		return "", err
+	}
+
+	// Records left behind, e.g. by an ingress which was deleted without its finalizer, are replaced.
+	// Records which weren't created by the provider are never touched.
+	if err = p.checkUnclaimed(ctx, name); err != nil {
+		return "", err
+	}
+	if err = p.update(ctx, name, backendSet); err != nil {
+		return "", errors.Wrapf(err, "error creating records of %s", hostName)
+	}
+
+	return name, nil
+}
+
+func (p *provider) Update(ctx context.Context, hostName string, backendSet []*ingressprovider.Backend, opts ingressprovider.Options) error {
+	if err := ingressprovider.CheckFeatures(backendSet, opts, supported...); err != nil {
+		return err
+	}
+
+	name, err := p.name(hostName)
+	if err != nil {
+		return err
+	}
+
+	if _, err = p.Get(ctx, name); err != nil {
+		return err
+	}
+
+	if err = p.update(ctx, name, backendSet); err != nil {
+		return errors.Wrapf(err, "error updating records of %s", hostName)
+	}
+
+	return nil
+}
+
+// Delete deletes the records of the hostname, including the ones of the SRV targets
+func (p *provider) Delete(ctx context.Context, id string) error {
+	if err := p.exists(ctx, id); err != nil {
+		return err
+	}
+	records, err := p.query(ctx, SRVPrefix+id, dns.TypeSRV)
+	if err != nil {
+		return err
+	}
+
+	m := p.message()
+	m.RemoveName([]dns.RR{&dns.ANY{Hdr: dns.RR_Header{Name: SRVPrefix + id}}})
+	m.RemoveRRset(p.addressSets(id))
+	for _, target := range targets(records) {
+		m.RemoveName([]dns.RR{&dns.ANY{Hdr: dns.RR_Header{Name: target}}})
+	}
+	if err = p.exchange(ctx, m); err != nil {
+		return errors.Wrapf(err, "error deleting records of %s", id)
+	}
+
+	return nil
+}
+
+func (p *provider) Get(ctx context.Context, id string) (*ingressprovider.Ingress, error) {
+	if err := p.exists(ctx, id); err != nil {
+		return nil, err
+	}
+	records, err := p.query(ctx, SRVPrefix+id, dns.TypeSRV)
+	if err != nil {
+		return nil, err
+	}
+	records = append(records, p.marker(id))
+
+	for _, target := range targets(records) {
+		for _, t := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			addresses, err := p.query(ctx, target, t)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, addresses...)
+		}
+	}
+
+	return toIngresses(records)[id], nil
+}
+
+func (p *provider) List(ctx context.Context) ([]*ingressprovider.Ingress, error) {
+	m := &dns.Msg{}
+	m.SetAxfr(p.zone)
+	p.sign(m)
+
+	envelopes, err := p.transfer.In(m, p.server)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error transferring zone %s", p.zone)
+	}
+
+	var records []dns.RR
+	for envelope := range envelopes {
+		if envelope.Error != nil {
+			return nil, errors.Wrapf(envelope.Error, "error transferring zone %s", p.zone)
+		}
+		records = append(records, envelope.RR...)
+	}
+
+	byID := toIngresses(records)
+	ingresses := make([]*ingressprovider.Ingress, 0, len(byID))
+	for _, ingress := range byID {
+		ingresses = append(ingresses, ingress)
+	}
+
+	return ingresses, nil
+}
+
+// update replaces the records of the name with the ones of the backends in a single update
+func (p *provider) update(ctx context.Context, name string, backendSet []*ingressprovider.Backend) error {
+	current, err := p.query(ctx, SRVPrefix+name, dns.TypeSRV)
+	if err != nil {
+		return err
+	}
+
+	m := p.message()
+	m.RemoveName([]dns.RR{&dns.ANY{Hdr: dns.RR_Header{Name: SRVPrefix + name}}})
+	m.RemoveRRset(p.addressSets(name))
+	for _, target := range targets(current) {
+		m.RemoveName([]dns.RR{&dns.ANY{Hdr: dns.RR_Header{Name: target}}})
+	}
+	m.Insert(p.records(name, backendSet))
+
+	return p.exchange(ctx, m)
+}
+
+// records returns the marker and the records of the backends. The hostname resolves to the addresses of the backends
+// which aren't draining, while the SRV records point at a target for each address, as they can't point at addresses directly.
+func (p *provider) records(name string, backendSet []*ingressprovider.Backend) []dns.RR {
+	records := []dns.RR{p.marker(name)}
+	targets := make(map[string]bool)
+	addresses := make(map[string]bool)
+	for _, backend := range backendSet {
+		target := targetName(name, backend.IP)
+		srv := &dns.SRV{
+			Hdr:    dns.RR_Header{Name: SRVPrefix + name, Rrtype: dns.TypeSRV, Ttl: p.ttl},
+			Weight: backend.Weight,
+			Port:   backend.Port,
+			Target: target,
+		}
+		if backend.Draining {
+			srv.Priority, srv.Weight = drainingPriority, 0
+		}
+		records = append(records, srv)
+
+		if !targets[target] {
+			targets[target] = true
+			records = append(records, p.address(target, backend.IP))
+		}
+		if !backend.Draining && !addresses[target] {
+			addresses[target] = true
+			records = append(records, p.address(name, backend.IP))
+		}
+	}
+
+	return records
+}
+
+// marker returns the TXT record marking the name as an ingress
+func (p *provider) marker(name string) dns.RR {
+	return &dns.TXT{Hdr: dns.RR_Header{Name: SRVPrefix + name, Rrtype: dns.TypeTXT, Ttl: p.ttl}, Txt: []string{MarkerText}}
+}
+
+// exists returns ErrorRecordsNotFound if the name isn't marked as an ingress
+func (p *provider) exists(ctx context.Context, name string) error {
+	records, err := p.query(ctx, SRVPrefix+name, dns.TypeTXT)
+	if err != nil {
+		return err
+	}
+	for _, rr := range records {
+		if isMarker(rr) {
+			return nil
+		}
+	}
+
+	return ErrorRecordsNotFound
+}
+
+// checkUnclaimed returns ErrorRecordsConflict if the name has records, but isn't marked as an ingress.
+// Those weren't created by the provider, e.g. the addresses of a website, and would be replaced by the ingress.
+func (p *provider) checkUnclaimed(ctx context.Context, name string) error {
+	if err := p.exists(ctx, name); !errors.Is(err, ErrorRecordsNotFound) {
+		return err
+	}
+
+	for _, q := range []dns.Question{
+		{Name: name, Qtype: dns.TypeA},
+		{Name: name, Qtype: dns.TypeAAAA},
+		{Name: SRVPrefix + name, Qtype: dns.TypeSRV},
+		{Name: SRVPrefix + name, Qtype: dns.TypeTXT},
+	} {
+		records, err := p.query(ctx, q.Name, q.Qtype)
+		if err != nil {
+			return err
+		}
+		if len(records) > 0 {
+			return errors.Wrapf(ErrorRecordsConflict, "%s records of %s", dns.TypeToString[q.Qtype], q.Name)
+		}
+	}
+
+	return nil
+}
+
+// address returns the A or AAAA record of the name
+func (p *provider) address(name string, ip net.IP) dns.RR {
+	if ip4 := ip.To4(); ip4 != nil {
+		return &dns.A{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Ttl: p.ttl}, A: ip4}
+	}
+
+	return &dns.AAAA{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeAAAA, Ttl: p.ttl}, AAAA: ip}
+}
+
+// addressSets returns the A and AAAA record sets of the name, for removal
+func (p *provider) addressSets(name string) []dns.RR {
+	return []dns.RR{
+		&dns.ANY{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA}},
+		&dns.ANY{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeAAAA}},
+	}
+}
+
+// query returns the records of the name and type, none if the name doesn't exist
+func (p *provider) query(ctx context.Context, name string, t uint16) ([]dns.RR, error) {
+	m := &dns.Msg{}
+	m.SetQuestion(name, t)
+	p.sign(m)
+
+	res, _, err := p.client.ExchangeContext(ctx, m, p.server)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error querying %s", name)
+	}
+	if res.Rcode != dns.RcodeSuccess && res.Rcode != dns.RcodeNameError {
+		return nil, errors.Errorf("error querying %s: %s", name, dns.RcodeToString[res.Rcode])
+	}
+
+	var records []dns.RR
+	for _, rr := range res.Answer {
+		if rr.Header().Rrtype == t && strings.EqualFold(rr.Header().Name, name) {
+			records = append(records, rr)
+		}
+	}
+
+	return records, nil
+}
+
+// message returns an update of the zone
+func (p *provider) message() *dns.Msg {
+	m := &dns.Msg{}
+	m.SetUpdate(p.zone)
+
+	return m
+}
+
+// exchange sends the update, failing if the server didn't apply it
+func (p *provider) exchange(ctx context.Context, m *dns.Msg) error {
+	p.sign(m)
+
+	res, _, err := p.client.ExchangeContext(ctx, m, p.server)
+	if err != nil {
+		return err
+	}
+	if res.Rcode != dns.RcodeSuccess {
+		return errors.Errorf("update refused: %s", dns.RcodeToString[res.Rcode])
+	}
+
+	return nil
+}
+
+// sign adds a TSIG record to the message, if a key is configured
+func (p *provider) sign(m *dns.Msg) {
+	if p.keyName != "" {
+		m.SetTsig(p.keyName, p.algorithm, 300, time.Now().Unix())
+	}
+}
+
+// name returns the fully qualified name of the hostname, which has to be part of the zone
+func (p *provider) name(hostName string) (string, error) {
+	name := dns.Fqdn(strings.ToLower(hostName))
+	if _, ok := dns.IsDomainName(name); !ok || !dns.IsSubDomain(p.zone, name) {
+		return "", errors.Errorf("hostname %s is not part of zone %s", hostName, p.zone)
+	}
+
+	return name, nil
+}
+
+// targetName returns the name of the SRV target of the address below the name
+func targetName(name string, ip net.IP) string {
+	return TargetPrefix + strings.NewReplacer(".", "-", ":", "-").Replace(ip.String()) + "." + name
+}
+
+// targets returns the distinct targets of the SRV records
+func targets(records []dns.RR) []string {
+	var targets []string
+	seen := make(map[string]bool)
+	for _, rr := range records {
+		if srv, ok := rr.(*dns.SRV); ok && !seen[srv.Target] {
+			seen[srv.Target] = true
+			targets = append(targets, srv.Target)
+		}
+	}
+	sort.Strings(targets)
+
+	return targets
+}
+
+// isMarker returns whether the record is a TXT record marking its name as an ingress
+func isMarker(rr dns.RR) bool {
+	txt, ok := rr.(*dns.TXT)
+	return ok && strings.HasPrefix(strings.ToLower(txt.Hdr.Name), SRVPrefix) && len(txt.Txt) == 1 && txt.Txt[0] == MarkerText
+}
+
+// toIngresses returns the ingresses described by the markers, the SRV records and the addresses of their targets, by ID.
+// SRV records of names which aren't marked don't belong to an ingress and are ignored.
+func toIngresses(records []dns.RR) map[string]*ingressprovider.Ingress {
+	ingresses := make(map[string]*ingressprovider.Ingress)
+	addresses := make(map[string][]net.IP)
+	for _, rr := range records {
+		switch rr := rr.(type) {
+		case *dns.A:
+			addresses[strings.ToLower(rr.Hdr.Name)] = append(addresses[strings.ToLower(rr.Hdr.Name)], rr.A)
+		case *dns.AAAA:
+			addresses[strings.ToLower(rr.Hdr.Name)] = append(addresses[strings.ToLower(rr.Hdr.Name)], rr.AAAA)
+		case *dns.TXT:
+			if !isMarker(rr) {
+				continue
+			}
+			id := strings.TrimPrefix(strings.ToLower(rr.Hdr.Name), SRVPrefix)
+			ingresses[id] = &ingressprovider.Ingress{
+				ID:       id,
+				HostName: strings.TrimSuffix(id, "."),
+				// The zone is authoritative for the hostname
+				Verified: true,
+			}
+		}
+	}
+
+	for _, rr := range records {
+		srv, ok := rr.(*dns.SRV)
+		if !ok {
+			continue
+		}
+		ingress, ok := ingresses[strings.TrimPrefix(strings.ToLower(srv.Hdr.Name), SRVPrefix)]
+		if !ok {
+			continue
+		}
+
+		for _, ip := range addresses[strings.ToLower(srv.Target)] {
+			ingress.BackendSet = append(ingress.BackendSet, &ingressprovider.Backend{
+				IP:       ip,
+				Port:     srv.Port,
+				Protocol: ingressprovider.ProtocolTCP,
+				Weight:   srv.Weight,
+				Draining: srv.Priority >= drainingPriority,
+			})
+		}
+	}
+
+	return ingresses
+}
@@ -0,0 +1,372 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package rfc2136_test
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+	"innit.gg/singularity/pkg/ingressprovider"
+	"innit.gg/singularity/pkg/ingressprovider/rfc2136"
+	"innit.gg/singularity/pkg/ingressprovider/rfc2136/fake"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	zone    = "example.com"
+	keyName = "singularity"
+)
+
+var secret = base64.StdEncoding.EncodeToString([]byte("secret"))
+
+// startServer starts a fake server of the zone, which requires requests to be signed with the key
+func startServer(t *testing.T) (*fake.Server, string) {
+	t.Helper()
+
+	server := fake.NewServer(zone, keyName, secret)
+	addr, err := server.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = server.Close()
+	})
+
+	return server, addr
+}
+
+// newProvider returns a provider sending its signed requests to a new fake server
+func newProvider(t *testing.T) (ingressprovider.Provider, *fake.Server) {
+	t.Helper()
+
+	server, addr := startServer(t)
+	return rfc2136.CreateProvider(addr, zone, rfc2136.WithTSIG(keyName, secret, dns.HmacSHA256), rfc2136.WithTTL(30*time.Second)), server
+}
+
+func backend(ip string, port uint16) *ingressprovider.Backend {
+	return &ingressprovider.Backend{
+		IP:       net.ParseIP(ip),
+		Port:     port,
+		Protocol: ingressprovider.ProtocolTCP,
+		Weight:   ingressprovider.DefaultWeight,
+	}
+}
+
+// addresses returns the sorted addresses of the backends, with the draining ones suffixed
+func addresses(backendSet []*ingressprovider.Backend) []string {
+	var addresses []string
+	for _, backend := range backendSet {
+		address := backend.Address()
+		if backend.Draining {
+			address += " draining"
+		}
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	return addresses
+}
+
+// records returns the records of the server in presentation format, without their TTL
+func records(server *fake.Server) []string {
+	var records []string
+	for _, rr := range server.Records() {
+		rr.Header().Ttl = 0
+		records = append(records, rr.String())
+	}
+	sort.Strings(records)
+
+	return records
+}
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+	p, server := newProvider(t)
+
+	draining := backend("10.0.0.2", 25565)
+	draining.Draining = true
+	backendSet := []*ingressprovider.Backend{backend("10.0.0.1", 25565), backend("fd00::1", 25566), draining}
+
+	id, err := p.Create(ctx, "Play.Example.com", backendSet, ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "play.example.com." {
+		t.Errorf("id = %s, want play.example.com.", id)
+	}
+
+	// The hostname only resolves to the backends which aren't draining
+	want := []string{
+		"_minecraft._tcp.play.example.com.\t0\tIN\tSRV\t0 1 25565 ip-10-0-0-1.play.example.com.",
+		"_minecraft._tcp.play.example.com.\t0\tIN\tSRV\t0 1 25566 ip-fd00--1.play.example.com.",
+		"_minecraft._tcp.play.example.com.\t0\tIN\tSRV\t1 0 25565 ip-10-0-0-2.play.example.com.",
+		"_minecraft._tcp.play.example.com.\t0\tIN\tTXT\t\"" + rfc2136.MarkerText + "\"",
+		"ip-10-0-0-1.play.example.com.\t0\tIN\tA\t10.0.0.1",
+		"ip-10-0-0-2.play.example.com.\t0\tIN\tA\t10.0.0.2",
+		"ip-fd00--1.play.example.com.\t0\tIN\tAAAA\tfd00::1",
+		"play.example.com.\t0\tIN\tA\t10.0.0.1",
+		"play.example.com.\t0\tIN\tAAAA\tfd00::1",
+	}
+	if got := records(server); !reflect.DeepEqual(got, want) {
+		t.Errorf("records = %q, want %q", got, want)
+	}
+
+	ingress, err := p.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ingress.HostName != "play.example.com" || !ingress.Verified {
+		t.Errorf("ingress = %+v, want the verified play.example.com", ingress)
+	}
+	wantAddresses := []string{"10.0.0.1:25565", "10.0.0.2:25565 draining", "[fd00::1]:25566"}
+	if got := addresses(ingress.BackendSet); !reflect.DeepEqual(got, wantAddresses) {
+		t.Errorf("backends = %v, want %v", got, wantAddresses)
+	}
+}
+
+func TestCreateErrors(t *testing.T) {
+	ctx := context.Background()
+	p, server := newProvider(t)
+
+	if _, err := p.Create(ctx, "play.example.org", []*ingressprovider.Backend{backend("10.0.0.1", 25565)}, ingressprovider.Options{}); err == nil {
+		t.Error("Create() of a hostname outside of the zone succeeded")
+	}
+
+	udp := backend("10.0.0.1", 19132)
+	udp.Protocol = ingressprovider.ProtocolUDP
+	var unsupported *ingressprovider.UnsupportedError
+	if _, err := p.Create(ctx, "play.example.com", []*ingressprovider.Backend{udp}, ingressprovider.Options{}); !errors.As(err, &unsupported) ||
+		unsupported.Feature != ingressprovider.FeatureUDP {
+		t.Errorf("Create() of an UDP backend error = %v, want UDP to be unsupported", err)
+	}
+
+	server.Fail(dns.RcodeRefused)
+	if _, err := p.Create(ctx, "play.example.com", []*ingressprovider.Backend{backend("10.0.0.1", 25565)}, ingressprovider.Options{}); err == nil {
+		t.Error("Create() succeeded although the server refused it")
+	}
+	if got := records(server); len(got) != 0 {
+		t.Errorf("records = %q, want none", got)
+	}
+}
+
+func TestCreateExistingRecords(t *testing.T) {
+	ctx := context.Background()
+
+	website := &dns.A{Hdr: dns.RR_Header{Name: "play.example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET}, A: net.ParseIP("192.0.2.1")}
+	srv := &dns.SRV{Hdr: dns.RR_Header{Name: "_minecraft._tcp.play.example.com.", Rrtype: dns.TypeSRV, Class: dns.ClassINET}, Port: 25565, Target: "mc.example.net."}
+	for _, rr := range []dns.RR{website, srv} {
+		p, server := newProvider(t)
+		server.Add(rr)
+		want := records(server)
+
+		// Records which weren't created by the provider are kept
+		if _, err := p.Create(ctx, "play.example.com", []*ingressprovider.Backend{backend("10.0.0.1", 25565)}, ingressprovider.Options{}); !errors.Is(err, rfc2136.ErrorRecordsConflict) {
+			t.Errorf("Create() of a hostname with a foreign %s record error = %v, want %v", dns.TypeToString[rr.Header().Rrtype], err, rfc2136.ErrorRecordsConflict)
+		}
+		if got := records(server); !reflect.DeepEqual(got, want) {
+			t.Errorf("records = %q, want %q", got, want)
+		}
+	}
+
+	// Records left behind by an ingress are replaced
+	p, server := newProvider(t)
+	if _, err := p.Create(ctx, "play.example.com", []*ingressprovider.Backend{backend("10.0.0.1", 25565)}, ingressprovider.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Create(ctx, "play.example.com", []*ingressprovider.Backend{backend("10.0.0.2", 25565)}, ingressprovider.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, rr := range records(server) {
+		if strings.Contains(rr, "10.0.0.1") || strings.Contains(rr, "10-0-0-1") {
+			t.Errorf("record %q of the previous ingress is left behind", rr)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	p, server := newProvider(t)
+
+	id, err := p.Create(ctx, "play.example.com", []*ingressprovider.Backend{backend("10.0.0.1", 25565), backend("10.0.0.2", 25565)}, ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The records of removed backends are removed, including their targets
+	if err = p.Update(ctx, "play.example.com", []*ingressprovider.Backend{backend("10.0.0.2", 25566), backend("10.0.0.3", 25565)}, ingressprovider.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, rr := range records(server) {
+		if strings.Contains(rr, "10.0.0.1") || strings.Contains(rr, "10-0-0-1") {
+			t.Errorf("record %q of the removed backend is left behind", rr)
+		}
+	}
+	ingress, err := p.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.2:25566", "10.0.0.3:25565"}
+	if got := addresses(ingress.BackendSet); !reflect.DeepEqual(got, want) {
+		t.Errorf("backends = %v, want %v", got, want)
+	}
+
+	if err = p.Update(ctx, "lobby.example.com", nil, ingressprovider.Options{}); !errors.Is(err, ingressprovider.ErrorNotFound) {
+		t.Errorf("Update() of a missing ingress error = %v, want %v", err, ingressprovider.ErrorNotFound)
+	}
+}
+
+func TestEmptyBackendSet(t *testing.T) {
+	ctx := context.Background()
+	p, server := newProvider(t)
+
+	// Ingresses without backends are kept by their marker
+	id, err := p.Create(ctx, "play.example.com", nil, ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"_minecraft._tcp.play.example.com.\t0\tIN\tTXT\t\"" + rfc2136.MarkerText + "\""}
+	if got := records(server); !reflect.DeepEqual(got, want) {
+		t.Errorf("records = %q, want %q", got, want)
+	}
+
+	ingress, err := p.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ingress.BackendSet) != 0 {
+		t.Errorf("backends = %v, want none", addresses(ingress.BackendSet))
+	}
+
+	if err = p.Update(ctx, "play.example.com", []*ingressprovider.Backend{backend("10.0.0.1", 25565)}, ingressprovider.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Update(ctx, "play.example.com", nil, ingressprovider.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := records(server); !reflect.DeepEqual(got, want) {
+		t.Errorf("records after removing all backends = %q, want %q", got, want)
+	}
+
+	ingresses, err := p.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ingresses) != 1 || ingresses[0].ID != id || len(ingresses[0].BackendSet) != 0 {
+		t.Errorf("ingresses = %+v, want %s without backends", ingresses, id)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	p, server := newProvider(t)
+
+	id, err := p.Create(ctx, "play.example.com", []*ingressprovider.Backend{backend("10.0.0.1", 25565), backend("fd00::1", 25565)}, ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := p.Create(ctx, "lobby.example.com", []*ingressprovider.Backend{backend("10.0.0.2", 25565)}, ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.Delete(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	for _, rr := range records(server) {
+		if strings.Contains(rr, "play.example.com") {
+			t.Errorf("record %q of the deleted ingress is left behind", rr)
+		}
+	}
+	if _, err = p.Get(ctx, other); err != nil {
+		t.Errorf("Get() of the other ingress error = %v", err)
+	}
+
+	if err = p.Delete(ctx, id); !errors.Is(err, ingressprovider.ErrorNotFound) {
+		t.Errorf("Delete() of a deleted ingress error = %v, want %v", err, ingressprovider.ErrorNotFound)
+	}
+	if _, err = p.Get(ctx, id); !errors.Is(err, ingressprovider.ErrorNotFound) {
+		t.Errorf("Get() of a deleted ingress error = %v, want %v", err, ingressprovider.ErrorNotFound)
+	}
+}
+
+func TestTSIG(t *testing.T) {
+	ctx := context.Background()
+	server, addr := startServer(t)
+
+	tests := []struct {
+		name string
+		opts []rfc2136.Option
+	}{
+		{name: "unsigned"},
+		{name: "wrong secret", opts: []rfc2136.Option{rfc2136.WithTSIG(keyName, base64.StdEncoding.EncodeToString([]byte("wrong")), dns.HmacSHA256)}},
+		{name: "unknown key", opts: []rfc2136.Option{rfc2136.WithTSIG("other", secret, dns.HmacSHA256)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := rfc2136.CreateProvider(addr, zone, tt.opts...)
+
+			if _, err := p.Create(ctx, "play.example.com", []*ingressprovider.Backend{backend("10.0.0.1", 25565)}, ingressprovider.Options{}); err == nil {
+				t.Error("Create() succeeded")
+			}
+			if _, err := p.List(ctx); err == nil {
+				t.Error("List() succeeded")
+			}
+			if got := records(server); len(got) != 0 {
+				t.Errorf("records = %q, want none", got)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newProvider(t)
+
+	want := map[string][]string{
+		"play.example.com.":  {"10.0.0.1:25565", "[fd00::1]:25565"},
+		"lobby.example.com.": {"10.0.0.2:25566"},
+	}
+	for id, backendSet := range map[string][]*ingressprovider.Backend{
+		"play.example.com.":  {backend("10.0.0.1", 25565), backend("fd00::1", 25565)},
+		"lobby.example.com.": {backend("10.0.0.2", 25566)},
+	} {
+		if _, err := p.Create(ctx, strings.TrimSuffix(id, "."), backendSet, ingressprovider.Options{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ingresses, err := p.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][]string, len(ingresses))
+	for _, ingress := range ingresses {
+		got[ingress.ID] = addresses(ingress.BackendSet)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ingresses = %v, want %v", got, want)
+	}
+}
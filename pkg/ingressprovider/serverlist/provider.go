@@ -0,0 +1,260 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package serverlist
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"innit.gg/singularity/pkg/apis/singularity"
+	"innit.gg/singularity/pkg/ingressprovider"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// Role is the ConfigMap label value for singularity.RoleLabel
+	Role = "serverlist"
+	// HostNameAnnotation is the ConfigMap annotation containing the hostname of the server list
+	HostNameAnnotation = singularity.GroupName + "/hostname"
+	// DataKey is the ConfigMap key containing the server list
+	DataKey = "servers.json"
+	// ResourcePrefix prefixes the names of the ConfigMaps
+	ResourcePrefix = "serverlist-"
+)
+
+var (
+	ErrorServerListNotFound = errors.Wrap(ingressprovider.ErrorNotFound, "server list not found")
+	// ErrorServerListConflict is returned when the ConfigMap of a hostname contains the server list of another one
+	ErrorServerListConflict = errors.New("server list belongs to another hostname")
+)
+
+// ServerList is the server list consumed by proxy plugins, e.g. for Velocity or BungeeCord.
+// Plugins watch the ConfigMaps labelled with the serverlist role to register and unregister servers.
+type ServerList struct {
+	HostName      string    `json:"hostname"`
+	ProxyProtocol bool      `json:"proxyProtocol"`
+	Servers       []*Server `json:"servers"`
+}
+
+// Server is a single entry of a server list
+type Server struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Port    uint16 `json:"port"`
+	// Protocol is either TCP or UDP
+	Protocol ingressprovider.Protocol `json:"protocol"`
+	Weight   uint16                   `json:"weight"`
+	// Draining servers accept no new players, proxies shouldn't send players to them
+	Draining bool `json:"draining,omitempty"`
+	// Metadata describes the server, e.g. its map, capacity and players
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type provider struct {
+	client    client.Client
+	namespace string
+}
+
+// CreateProvider creates a provider publishing server lists as ConfigMaps in the namespace
+func CreateProvider(c client.Client, namespace string) ingressprovider.Provider {
+	return &provider{
+		client:    c,
+		namespace: namespace,
+	}
+}
+
+func (p *provider) Create(ctx context.Context, hostName string, backendSet []*ingressprovider.Backend, opts ingressprovider.Options) (string, error) {
+	cm, err := p.configMap(hostName, backendSet, opts)
+	if err != nil {
+		return "", err
+	}
+
+	if err = p.client.Create(ctx, cm); err != nil {
+		if !k8serrors.IsAlreadyExists(err) {
+			return "", errors.Wrapf(err, "error creating server list %s", hostName)
+		}
+
+		// The server list was left behind, e.g. by an ingress which was deleted without its finalizer.
+		// Update only adopts it if it has the same hostname.
+		if err = p.Update(ctx, hostName, backendSet, opts); err != nil {
+			return "", err
+		}
+	}
+
+	return cm.ObjectMeta.Name, nil
+}
+
+func (p *provider) Update(ctx context.Context, hostName string, backendSet []*ingressprovider.Backend, opts ingressprovider.Options) error {
+	desired, err := p.configMap(hostName, backendSet, opts)
+	if err != nil {
+		return err
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err = p.client.Get(ctx, client.ObjectKeyFromObject(desired), cm); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return ErrorServerListNotFound
+		}
+		return errors.Wrapf(err, "error getting server list %s", hostName)
+	}
+	if owner := cm.ObjectMeta.Annotations[HostNameAnnotation]; owner != hostName {
+		return errors.Wrapf(ErrorServerListConflict, "%s of %s", cm.ObjectMeta.Name, owner)
+	}
+
+	// Labels and annotations added by others, e.g. to configure the proxy plugins, are kept.
+	for k, v := range desired.ObjectMeta.Labels {
+		metav1.SetMetaDataLabel(&cm.ObjectMeta, k, v)
+	}
+	for k, v := range desired.ObjectMeta.Annotations {
+		metav1.SetMetaDataAnnotation(&cm.ObjectMeta, k, v)
+	}
+	cm.Data = desired.Data
+	if err = p.client.Update(ctx, cm); err != nil {
+		return errors.Wrapf(err, "error updating server list %s", hostName)
+	}
+
+	return nil
+}
+
+func (p *provider) Delete(ctx context.Context, id string) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: p.namespace,
+			Name:      id,
+		},
+	}
+	if err := p.client.Delete(ctx, cm); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return ErrorServerListNotFound
+		}
+		return errors.Wrapf(err, "error deleting server list %s", id)
+	}
+
+	return nil
+}
+
+func (p *provider) Get(ctx context.Context, id string) (*ingressprovider.Ingress, error) {
+	cm := &corev1.ConfigMap{}
+	if err := p.client.Get(ctx, client.ObjectKey{Namespace: p.namespace, Name: id}, cm); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, ErrorServerListNotFound
+		}
+		return nil, errors.Wrapf(err, "error getting server list %s", id)
+	}
+
+	return toIngress(cm)
+}
+
+func (p *provider) List(ctx context.Context) ([]*ingressprovider.Ingress, error) {
+	list := &corev1.ConfigMapList{}
+	if err := p.client.List(ctx, list, client.InNamespace(p.namespace), client.MatchingLabels{singularity.RoleLabel: Role}); err != nil {
+		return nil, errors.Wrap(err, "error listing server lists")
+	}
+
+	ingresses := make([]*ingressprovider.Ingress, 0, len(list.Items))
+	for i := range list.Items {
+		// A single server list which was edited by hand mustn't hide all others.
+		// It's left out, so drift detection recreates it from the GameServerIngress.
+		ingress, err := toIngress(&list.Items[i])
+		if err != nil {
+			log.FromContext(ctx).Error(err, "skipping undecodable server list", "configmap", list.Items[i].ObjectMeta.Name)
+			continue
+		}
+		ingresses = append(ingresses, ingress)
+	}
+
+	return ingresses, nil
+}
+
+// configMap returns the ConfigMap containing the server list of the hostname
+func (p *provider) configMap(hostName string, backendSet []*ingressprovider.Backend, opts ingressprovider.Options) (*corev1.ConfigMap, error) {
+	list := &ServerList{
+		HostName:      hostName,
+		ProxyProtocol: opts.ProxyProtocol,
+		Servers:       make([]*Server, 0, len(backendSet)),
+	}
+	for _, backend := range backendSet {
+		name := backend.Name
+		if name == "" {
+			name = backend.Address()
+		}
+		protocol := backend.Protocol
+		if protocol == "" {
+			protocol = ingressprovider.ProtocolTCP
+		}
+		list.Servers = append(list.Servers, &Server{
+			Name:     name,
+			Address:  backend.IP.String(),
+			Port:     backend.Port,
+			Protocol: protocol,
+			Weight:   backend.Weight,
+			Draining: backend.Draining,
+			Metadata: backend.Metadata,
+		})
+	}
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error encoding server list %s", hostName)
+	}
+
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   p.namespace,
+			Name:        ingressprovider.ResourceName(ResourcePrefix, hostName),
+			Labels:      map[string]string{singularity.RoleLabel: Role},
+			Annotations: map[string]string{HostNameAnnotation: hostName},
+		},
+		Data: map[string]string{DataKey: string(data)},
+	}, nil
+}
+
+// toIngress returns the ingress described by the ConfigMap
+func toIngress(cm *corev1.ConfigMap) (*ingressprovider.Ingress, error) {
+	list := &ServerList{}
+	if err := json.Unmarshal([]byte(cm.Data[DataKey]), list); err != nil {
+		return nil, errors.Wrapf(err, "error decoding server list %s", cm.ObjectMeta.Name)
+	}
+
+	ingress := &ingressprovider.Ingress{
+		ID:       cm.ObjectMeta.Name,
+		HostName: cm.ObjectMeta.Annotations[HostNameAnnotation],
+		Options:  ingressprovider.Options{ProxyProtocol: list.ProxyProtocol},
+		// The proxies pick up the server list as soon as it changes
+		Verified: true,
+	}
+	for _, server := range list.Servers {
+		ingress.BackendSet = append(ingress.BackendSet, &ingressprovider.Backend{
+			IP:       net.ParseIP(server.Address),
+			Port:     server.Port,
+			Protocol: server.Protocol,
+			Weight:   server.Weight,
+			Draining: server.Draining,
+			Name:     server.Name,
+			Metadata: server.Metadata,
+		})
+	}
+
+	return ingress, nil
+}
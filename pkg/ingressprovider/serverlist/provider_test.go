@@ -0,0 +1,242 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package serverlist
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"innit.gg/singularity/pkg/apis/singularity"
+	"innit.gg/singularity/pkg/ingressprovider"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"net"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+}
+
+func backend(name, ip string) *ingressprovider.Backend {
+	return &ingressprovider.Backend{
+		IP:       net.ParseIP(ip),
+		Port:     25565,
+		Protocol: ingressprovider.ProtocolTCP,
+		Weight:   ingressprovider.DefaultWeight,
+		Name:     name,
+	}
+}
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+	p := CreateProvider(fake.NewClientBuilder().WithScheme(scheme).Build(), "proxies")
+
+	id, err := p.Create(ctx, "*.example.com", []*ingressprovider.Backend{backend("lobby-abcde", "10.0.0.1")}, ingressprovider.Options{ProxyProtocol: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ingress, err := p.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ingress.HostName != "*.example.com" || !ingress.Options.ProxyProtocol {
+		t.Errorf("ingress = %s with %+v, want *.example.com with the PROXY protocol", ingress.HostName, ingress.Options)
+	}
+	if len(ingress.BackendSet) != 1 || ingress.BackendSet[0].Name != "lobby-abcde" || ingress.BackendSet[0].Address() != "10.0.0.1:25565" {
+		t.Errorf("backends = %v, want lobby-abcde at 10.0.0.1:25565", ingress.BackendSet)
+	}
+
+	list, err := p.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != id {
+		t.Errorf("List() = %v, want %s", list, id)
+	}
+}
+
+func TestCreateSimilarHostNames(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	p := CreateProvider(c, "proxies")
+
+	eu, err := p.Create(ctx, "play.eu.example.com", []*ingressprovider.Backend{backend("eu", "10.0.0.1")}, ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The hostname only differs in a character which isn't allowed in names, it mustn't overwrite the other list
+	euDash, err := p.Create(ctx, "play-eu.example.com", []*ingressprovider.Backend{backend("eu-dash", "10.0.0.2")}, ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eu == euDash {
+		t.Fatalf("both hostnames share the server list %s", eu)
+	}
+
+	ingress, err := p.Get(ctx, eu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ingress.HostName != "play.eu.example.com" || len(ingress.BackendSet) != 1 || ingress.BackendSet[0].Name != "eu" {
+		t.Errorf("ingress = %s with %v, want the unchanged play.eu.example.com", ingress.HostName, ingress.BackendSet)
+	}
+}
+
+func TestCreateLeftBehind(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	p := CreateProvider(c, "proxies")
+
+	id, err := p.Create(ctx, "play.example.com", []*ingressprovider.Backend{backend("old", "10.0.0.1")}, ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The server list of the same hostname is adopted
+	adopted, err := p.Create(ctx, "play.example.com", []*ingressprovider.Backend{backend("new", "10.0.0.2")}, ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ingress, err := p.Get(ctx, adopted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if adopted != id || len(ingress.BackendSet) != 1 || ingress.BackendSet[0].Name != "new" {
+		t.Errorf("ingress %s = %v, want %s with the new backends", adopted, ingress.BackendSet, id)
+	}
+
+	// The ConfigMap of another hostname isn't, even if it has the name of this one
+	cm := &corev1.ConfigMap{}
+	if err = c.Get(ctx, client.ObjectKey{Namespace: "proxies", Name: id}, cm); err != nil {
+		t.Fatal(err)
+	}
+	metav1.SetMetaDataAnnotation(&cm.ObjectMeta, HostNameAnnotation, "other.example.com")
+	if err = c.Update(ctx, cm); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.Create(ctx, "play.example.com", nil, ingressprovider.Options{}); !errors.Is(err, ErrorServerListConflict) {
+		t.Errorf("Create() error = %v, want %v", err, ErrorServerListConflict)
+	}
+	if err = p.Update(ctx, "play.example.com", nil, ingressprovider.Options{}); !errors.Is(err, ErrorServerListConflict) {
+		t.Errorf("Update() error = %v, want %v", err, ErrorServerListConflict)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	ctx := context.Background()
+	p := CreateProvider(fake.NewClientBuilder().WithScheme(scheme).Build(), "proxies")
+
+	if err := p.Update(ctx, "play.example.com", nil, ingressprovider.Options{}); !errors.Is(err, ingressprovider.ErrorNotFound) {
+		t.Errorf("Update() error = %v, want %v", err, ingressprovider.ErrorNotFound)
+	}
+	if _, err := p.Get(ctx, "serverlist-play"); !errors.Is(err, ingressprovider.ErrorNotFound) {
+		t.Errorf("Get() error = %v, want %v", err, ingressprovider.ErrorNotFound)
+	}
+	if err := p.Delete(ctx, "serverlist-play"); !errors.Is(err, ingressprovider.ErrorNotFound) {
+		t.Errorf("Delete() error = %v, want %v", err, ingressprovider.ErrorNotFound)
+	}
+}
+
+func TestUpdateKeepsMetadata(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	p := CreateProvider(c, "proxies")
+
+	id, err := p.Create(ctx, "play.example.com", nil, ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Metadata added by others, e.g. to configure a proxy plugin
+	cm := &corev1.ConfigMap{}
+	if err = c.Get(ctx, client.ObjectKey{Namespace: "proxies", Name: id}, cm); err != nil {
+		t.Fatal(err)
+	}
+	metav1.SetMetaDataLabel(&cm.ObjectMeta, "proxy", "velocity")
+	metav1.SetMetaDataAnnotation(&cm.ObjectMeta, "velocity/try", "lobby")
+	if err = c.Update(ctx, cm); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = p.Update(ctx, "play.example.com", []*ingressprovider.Backend{backend("lobby-abcde", "10.0.0.1")}, ingressprovider.Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Get(ctx, client.ObjectKey{Namespace: "proxies", Name: id}, cm); err != nil {
+		t.Fatal(err)
+	}
+	if cm.ObjectMeta.Labels["proxy"] != "velocity" || cm.ObjectMeta.Annotations["velocity/try"] != "lobby" {
+		t.Errorf("metadata = %v %v, want the foreign label and annotation to be kept", cm.ObjectMeta.Labels, cm.ObjectMeta.Annotations)
+	}
+	if cm.ObjectMeta.Labels[singularity.RoleLabel] != Role || cm.ObjectMeta.Annotations[HostNameAnnotation] != "play.example.com" {
+		t.Errorf("metadata = %v %v, want the role and hostname", cm.ObjectMeta.Labels, cm.ObjectMeta.Annotations)
+	}
+}
+
+func TestListUndecodable(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	p := CreateProvider(c, "proxies")
+
+	broken, err := p.Create(ctx, "broken.example.com", nil, ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := p.Create(ctx, "play.example.com", []*ingressprovider.Backend{backend("lobby-abcde", "10.0.0.1")}, ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err = c.Get(ctx, client.ObjectKey{Namespace: "proxies", Name: broken}, cm); err != nil {
+		t.Fatal(err)
+	}
+	cm.Data[DataKey] = "{"
+	if err = c.Update(ctx, cm); err != nil {
+		t.Fatal(err)
+	}
+
+	// The undecodable server list is left out, instead of failing the whole list
+	ingresses, err := p.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ingresses) != 1 || ingresses[0].ID != id {
+		t.Errorf("ingresses = %+v, want only %s", ingresses, id)
+	}
+	if _, err = p.Get(ctx, broken); err == nil {
+		t.Error("Get() of the undecodable server list succeeded")
+	}
+
+	// Recreating the ingress replaces it
+	if _, err = p.Create(ctx, "broken.example.com", nil, ingressprovider.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.Get(ctx, broken); err != nil {
+		t.Errorf("Get() of the recreated server list error = %v", err)
+	}
+}
@@ -0,0 +1,424 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"innit.gg/singularity/pkg/apis/singularity"
+	"innit.gg/singularity/pkg/ingressprovider"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sort"
+	"strings"
+)
+
+const (
+	// Role is the Service label value for singularity.RoleLabel
+	Role = "ingress"
+	// HostNameAnnotation is the Service annotation external-dns creates the records of the hostname from
+	HostNameAnnotation = "external-dns.alpha.kubernetes.io/hostname"
+	// ManagedBy is the EndpointSlice label value for discoveryv1.LabelManagedBy
+	ManagedBy = singularity.GroupName
+	// ResourcePrefix prefixes the names of the Services
+	ResourcePrefix = "ingress-"
+	// PortName is the name of the TCP Service port, which the EndpointSlice ports refer to
+	PortName = "minecraft"
+	// UDPPortName is the name of the UDP Service port, which is only added for UDP backends
+	UDPPortName = "minecraft-udp"
+
+	// DefaultPort is the default port of the Services
+	DefaultPort = 25565
+	// maxEndpointsPerSlice is the maximum amount of endpoints in a single EndpointSlice, the same as kube-controller-manager's default
+	maxEndpointsPerSlice = 100
+)
+
+var (
+	ErrorServiceNotFound = errors.Wrap(ingressprovider.ErrorNotFound, "service not found")
+	// ErrorServiceConflict is returned when the Service of a hostname isn't the ingress of that hostname
+	ErrorServiceConflict = errors.New("service belongs to another hostname")
+
+	// supported are the features of Services, which neither send the PROXY protocol header nor weight endpoints
+	supported = []ingressprovider.Feature{ingressprovider.FeatureUDP}
+)
+
+type provider struct {
+	client      client.Client
+	namespace   string
+	serviceType corev1.ServiceType
+	port        int32
+}
+
+// Option configures the provider
+type Option func(p *provider)
+
+// WithType changes the type of the Services, LoadBalancer by default
+func WithType(serviceType corev1.ServiceType) Option {
+	return func(p *provider) {
+		p.serviceType = serviceType
+	}
+}
+
+// WithPort changes the port the Services listen on
+func WithPort(port int32) Option {
+	return func(p *provider) {
+		p.port = port
+	}
+}
+
+// CreateProvider creates a provider routing hostnames through Services in the namespace.
+// The Services have no selector, their EndpointSlices point at the backends instead.
+func CreateProvider(c client.Client, namespace string, opts ...Option) ingressprovider.Provider {
+	p := &provider{
+		client:      c,
+		namespace:   namespace,
+		serviceType: corev1.ServiceTypeLoadBalancer,
+		port:        DefaultPort,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+func (p *provider) Create(ctx context.Context, hostName string, backendSet []*ingressprovider.Backend, opts ingressprovider.Options) (string, error) {
+	if err := ingressprovider.CheckFeatures(backendSet, opts, supported...); err != nil {
+		return "", err
+	}
+
+	svc := p.service(hostName, backendSet)
+	if err := p.client.Create(ctx, svc); err != nil {
+		if !k8serrors.IsAlreadyExists(err) {
+			return "", errors.Wrapf(err, "error creating service for %s", hostName)
+		}
+
+		// The Service was left behind, e.g. by an ingress which was deleted without its finalizer.
+		// Update only adopts it if it's the ingress of the same hostname.
+		if err = p.Update(ctx, hostName, backendSet, opts); err != nil {
+			return "", err
+		}
+		return svc.ObjectMeta.Name, nil
+	}
+
+	if err := p.updateEndpointSlices(ctx, svc, backendSet); err != nil {
+		return "", err
+	}
+
+	return svc.ObjectMeta.Name, nil
+}
+
+func (p *provider) Update(ctx context.Context, hostName string, backendSet []*ingressprovider.Backend, opts ingressprovider.Options) error {
+	if err := ingressprovider.CheckFeatures(backendSet, opts, supported...); err != nil {
+		return err
+	}
+
+	desired := p.service(hostName, backendSet)
+	svc := &corev1.Service{}
+	if err := p.client.Get(ctx, client.ObjectKeyFromObject(desired), svc); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return ErrorServiceNotFound
+		}
+		return errors.Wrapf(err, "error getting service for %s", hostName)
+	}
+	if svc.ObjectMeta.Labels[singularity.RoleLabel] != Role || svc.ObjectMeta.Annotations[HostNameAnnotation] != hostName {
+		return errors.Wrapf(ErrorServiceConflict, "%s of %s", svc.ObjectMeta.Name, svc.ObjectMeta.Annotations[HostNameAnnotation])
+	}
+
+	// Fields defaulted by the API server, e.g. the cluster IP and node ports, are kept.
+	for k, v := range desired.ObjectMeta.Labels {
+		metav1.SetMetaDataLabel(&svc.ObjectMeta, k, v)
+	}
+	for k, v := range desired.ObjectMeta.Annotations {
+		metav1.SetMetaDataAnnotation(&svc.ObjectMeta, k, v)
+	}
+	svc.Spec.Type = desired.Spec.Type
+	if !samePorts(svc.Spec.Ports, desired.Spec.Ports) {
+		svc.Spec.Ports = desired.Spec.Ports
+	}
+	if err := p.client.Update(ctx, svc); err != nil {
+		return errors.Wrapf(err, "error updating service for %s", hostName)
+	}
+
+	return p.updateEndpointSlices(ctx, svc, backendSet)
+}
+
+// Delete deletes the Service, its EndpointSlices are garbage collected along with it
+func (p *provider) Delete(ctx context.Context, id string) error {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: p.namespace,
+			Name:      id,
+		},
+	}
+	if err := p.client.Delete(ctx, svc); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return ErrorServiceNotFound
+		}
+		return errors.Wrapf(err, "error deleting service %s", id)
+	}
+
+	return nil
+}
+
+func (p *provider) Get(ctx context.Context, id string) (*ingressprovider.Ingress, error) {
+	svc := &corev1.Service{}
+	if err := p.client.Get(ctx, client.ObjectKey{Namespace: p.namespace, Name: id}, svc); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, ErrorServiceNotFound
+		}
+		return nil, errors.Wrapf(err, "error getting service %s", id)
+	}
+
+	slices, err := p.endpointSlices(ctx, svc.ObjectMeta.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return toIngress(svc, slices), nil
+}
+
+func (p *provider) List(ctx context.Context) ([]*ingressprovider.Ingress, error) {
+	list := &corev1.ServiceList{}
+	if err := p.client.List(ctx, list, client.InNamespace(p.namespace), client.MatchingLabels{singularity.RoleLabel: Role}); err != nil {
+		return nil, errors.Wrap(err, "error listing services")
+	}
+
+	ingresses := make([]*ingressprovider.Ingress, 0, len(list.Items))
+	for i := range list.Items {
+		svc := &list.Items[i]
+		slices, err := p.endpointSlices(ctx, svc.ObjectMeta.Name)
+		if err != nil {
+			return nil, err
+		}
+		ingresses = append(ingresses, toIngress(svc, slices))
+	}
+
+	return ingresses, nil
+}
+
+// service returns the Service of the hostname, with a port for each protocol of the backends
+func (p *provider) service(hostName string, backendSet []*ingressprovider.Backend) *corev1.Service {
+	ports := []corev1.ServicePort{{
+		Name:     PortName,
+		Protocol: corev1.ProtocolTCP,
+		Port:     p.port,
+	}}
+	for _, backend := range backendSet {
+		if backend.IsUDP() {
+			ports = append(ports, corev1.ServicePort{
+				Name:     UDPPortName,
+				Protocol: corev1.ProtocolUDP,
+				Port:     p.port,
+			})
+			break
+		}
+	}
+
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   p.namespace,
+			Name:        ingressprovider.ResourceName(ResourcePrefix, hostName),
+			Labels:      map[string]string{singularity.RoleLabel: Role},
+			Annotations: map[string]string{HostNameAnnotation: hostName},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  p.serviceType,
+			Ports: ports,
+		},
+	}
+}
+
+// endpointSlices returns the EndpointSlices of the Service
+func (p *provider) endpointSlices(ctx context.Context, name string) ([]discoveryv1.EndpointSlice, error) {
+	list := &discoveryv1.EndpointSliceList{}
+	err := p.client.List(ctx, list, client.InNamespace(p.namespace), client.MatchingLabels{
+		discoveryv1.LabelServiceName: name,
+		discoveryv1.LabelManagedBy:   ManagedBy,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing endpointslices of service %s", name)
+	}
+
+	return list.Items, nil
+}
+
+// updateEndpointSlices creates or updates the EndpointSlices of the backends, and deletes the ones not needed anymore
+func (p *provider) updateEndpointSlices(ctx context.Context, svc *corev1.Service, backendSet []*ingressprovider.Backend) error {
+	current, err := p.endpointSlices(ctx, svc.ObjectMeta.Name)
+	if err != nil {
+		return err
+	}
+
+	desired := desiredEndpointSlices(svc, backendSet)
+	for _, slice := range desired {
+		existing := &discoveryv1.EndpointSlice{ObjectMeta: metav1.ObjectMeta{Namespace: slice.ObjectMeta.Namespace, Name: slice.ObjectMeta.Name}}
+		_, err = controllerutil.CreateOrUpdate(ctx, p.client, existing, func() error {
+			existing.ObjectMeta.Labels = slice.ObjectMeta.Labels
+			existing.ObjectMeta.OwnerReferences = slice.ObjectMeta.OwnerReferences
+			existing.AddressType = slice.AddressType
+			existing.Endpoints = slice.Endpoints
+			existing.Ports = slice.Ports
+			return nil
+		})
+		if err != nil {
+			return errors.Wrapf(err, "error updating endpointslice %s", slice.ObjectMeta.Name)
+		}
+	}
+
+	for i := range current {
+		if _, ok := desired[current[i].ObjectMeta.Name]; ok {
+			continue
+		}
+		if err = p.client.Delete(ctx, &current[i]); err != nil && !k8serrors.IsNotFound(err) {
+			return errors.Wrapf(err, "error deleting endpointslice %s", current[i].ObjectMeta.Name)
+		}
+	}
+
+	return nil
+}
+
+// desiredEndpointSlices groups the backends into EndpointSlices by their address type, protocol and port.
+// Draining backends are terminating endpoints, which only receive new connections if no other endpoint is ready.
+func desiredEndpointSlices(svc *corev1.Service, backendSet []*ingressprovider.Backend) map[string]*discoveryv1.EndpointSlice {
+	type group struct {
+		addressType discoveryv1.AddressType
+		protocol    corev1.Protocol
+		port        int32
+	}
+
+	groups := make(map[group][]discoveryv1.Endpoint)
+	for _, backend := range backendSet {
+		g := group{addressType: discoveryv1.AddressTypeIPv6, protocol: corev1.ProtocolTCP, port: int32(backend.Port)}
+		if backend.IP.To4() != nil {
+			g.addressType = discoveryv1.AddressTypeIPv4
+		}
+		if backend.IsUDP() {
+			g.protocol = corev1.ProtocolUDP
+		}
+
+		ready, serving, terminating := !backend.Draining, true, backend.Draining
+		groups[g] = append(groups[g], discoveryv1.Endpoint{
+			Addresses: []string{backend.IP.String()},
+			Conditions: discoveryv1.EndpointConditions{
+				Ready:       &ready,
+				Serving:     &serving,
+				Terminating: &terminating,
+			},
+		})
+	}
+
+	ref := metav1.NewControllerRef(svc, corev1.SchemeGroupVersion.WithKind("Service"))
+	slices := make(map[string]*discoveryv1.EndpointSlice)
+	for g, endpoints := range groups {
+		sort.Slice(endpoints, func(i, j int) bool {
+			return endpoints[i].Addresses[0] < endpoints[j].Addresses[0]
+		})
+
+		name, protocol, port := PortName, g.protocol, g.port
+		if protocol == corev1.ProtocolUDP {
+			name = UDPPortName
+		}
+		for i := 0; i < len(endpoints); i += maxEndpointsPerSlice {
+			end := i + maxEndpointsPerSlice
+			if end > len(endpoints) {
+				end = len(endpoints)
+			}
+
+			slice := &discoveryv1.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: svc.ObjectMeta.Namespace,
+					Name: fmt.Sprintf("%s-%s-%s-%d-%d", svc.ObjectMeta.Name, strings.ToLower(string(g.addressType)),
+						strings.ToLower(string(g.protocol)), g.port, i/maxEndpointsPerSlice),
+					Labels: map[string]string{
+						discoveryv1.LabelServiceName: svc.ObjectMeta.Name,
+						discoveryv1.LabelManagedBy:   ManagedBy,
+					},
+					OwnerReferences: []metav1.OwnerReference{*ref},
+				},
+				AddressType: g.addressType,
+				Endpoints:   endpoints[i:end],
+				Ports: []discoveryv1.EndpointPort{{
+					Name:     &name,
+					Protocol: &protocol,
+					Port:     &port,
+				}},
+			}
+			slices[slice.ObjectMeta.Name] = slice
+		}
+	}
+
+	return slices
+}
+
+// samePorts returns whether the Service ports match the desired ones, ignoring fields defaulted by the API server
+func samePorts(ports, desired []corev1.ServicePort) bool {
+	if len(ports) != len(desired) {
+		return false
+	}
+	for i := range ports {
+		if ports[i].Name != desired[i].Name || ports[i].Protocol != desired[i].Protocol || ports[i].Port != desired[i].Port {
+			return false
+		}
+	}
+
+	return true
+}
+
+// toIngress returns the ingress described by the Service and its EndpointSlices
+func toIngress(svc *corev1.Service, slices []discoveryv1.EndpointSlice) *ingressprovider.Ingress {
+	ingress := &ingressprovider.Ingress{
+		ID:       svc.ObjectMeta.Name,
+		HostName: svc.ObjectMeta.Annotations[HostNameAnnotation],
+		// external-dns creates the records without verifying the hostname
+		Verified: true,
+	}
+	for i := range slices {
+		slice := &slices[i]
+		if len(slice.Ports) == 0 || slice.Ports[0].Port == nil {
+			continue
+		}
+
+		protocol := ingressprovider.ProtocolTCP
+		if slice.Ports[0].Protocol != nil && *slice.Ports[0].Protocol == corev1.ProtocolUDP {
+			protocol = ingressprovider.ProtocolUDP
+		}
+
+		for _, endpoint := range slice.Endpoints {
+			draining := endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready
+			for _, address := range endpoint.Addresses {
+				ingress.BackendSet = append(ingress.BackendSet, &ingressprovider.Backend{
+					IP:       net.ParseIP(address),
+					Port:     uint16(*slice.Ports[0].Port),
+					Protocol: protocol,
+					Weight:   ingressprovider.DefaultWeight,
+					Draining: draining,
+				})
+			}
+		}
+	}
+
+	return ingress
+}
@@ -0,0 +1,231 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"innit.gg/singularity/pkg/apis/singularity"
+	"innit.gg/singularity/pkg/ingressprovider"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+// backends returns n TCP backends with consecutive IPv4 addresses
+func backends(n int) []*ingressprovider.Backend {
+	set := make([]*ingressprovider.Backend, n)
+	for i := range set {
+		set[i] = &ingressprovider.Backend{
+			IP:       net.IPv4(10, 0, byte(i/250), byte(i%250+1)),
+			Port:     25565,
+			Protocol: ingressprovider.ProtocolTCP,
+			Weight:   ingressprovider.DefaultWeight,
+			Name:     fmt.Sprintf("lobby-%d", i),
+		}
+	}
+
+	return set
+}
+
+// TestEndpointSlices creates and updates a Service and its EndpointSlices on the envtest API server
+func TestEndpointSlices(t *testing.T) {
+	requireEnvtest(t)
+
+	ctx := context.Background()
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := CreateProvider(c, metav1.NamespaceDefault, WithType(corev1.ServiceTypeClusterIP))
+
+	set := backends(150)
+	set[0].Draining = true
+	id, err := p.Create(ctx, "play.example.com", set, ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc := &corev1.Service{}
+	if err = c.Get(ctx, client.ObjectKey{Namespace: metav1.NamespaceDefault, Name: id}, svc); err != nil {
+		t.Fatal(err)
+	}
+	if svc.ObjectMeta.Annotations[HostNameAnnotation] != "play.example.com" || svc.ObjectMeta.Labels[singularity.RoleLabel] != Role {
+		t.Errorf("service metadata = %v %v, want the hostname and role", svc.ObjectMeta.Labels, svc.ObjectMeta.Annotations)
+	}
+	if len(svc.Spec.Ports) != 1 || svc.Spec.Ports[0].Name != PortName || svc.Spec.Ports[0].Port != DefaultPort {
+		t.Errorf("service ports = %v, want %s on %d", svc.Spec.Ports, PortName, DefaultPort)
+	}
+
+	// Slices hold up to 100 endpoints, the rest is moved to a new slice
+	slices := endpointSliceSizes(ctx, t, c, id)
+	if len(slices) != 2 || slices[id+"-ipv4-tcp-25565-0"] != 100 || slices[id+"-ipv4-tcp-25565-1"] != 50 {
+		t.Errorf("endpointslices = %v, want 100 and 50 endpoints", slices)
+	}
+
+	// The draining backend is a terminating endpoint
+	ingress, err := p.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	draining := 0
+	for _, backend := range ingress.BackendSet {
+		if backend.Draining {
+			draining++
+			if !backend.IP.Equal(set[0].IP) {
+				t.Errorf("backend %s is draining, want %s", backend.IP, set[0].IP)
+			}
+		}
+	}
+	if len(ingress.BackendSet) != 150 || draining != 1 {
+		t.Errorf("got %d backends of which %d are draining, want 150 of which 1 is", len(ingress.BackendSet), draining)
+	}
+
+	// Slices which aren't needed anymore are deleted
+	if err = p.Update(ctx, "play.example.com", backends(50), ingressprovider.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	slices = endpointSliceSizes(ctx, t, c, id)
+	if len(slices) != 1 || slices[id+"-ipv4-tcp-25565-0"] != 50 {
+		t.Errorf("endpointslices = %v, want a single one with 50 endpoints", slices)
+	}
+}
+
+// endpointSliceSizes returns the amount of endpoints of the Service's EndpointSlices by their name
+func endpointSliceSizes(ctx context.Context, t *testing.T, c client.Client, name string) map[string]int {
+	t.Helper()
+
+	list := &discoveryv1.EndpointSliceList{}
+	if err := c.List(ctx, list, client.InNamespace(metav1.NamespaceDefault), client.MatchingLabels{discoveryv1.LabelServiceName: name}); err != nil {
+		t.Fatal(err)
+	}
+
+	sizes := make(map[string]int, len(list.Items))
+	for _, slice := range list.Items {
+		sizes[slice.ObjectMeta.Name] = len(slice.Endpoints)
+	}
+
+	return sizes
+}
+
+func TestCreateSimilarHostNames(t *testing.T) {
+	ctx := context.Background()
+	p := CreateProvider(fake.NewClientBuilder().WithScheme(scheme).Build(), "ingresses")
+
+	eu, err := p.Create(ctx, "play.eu.example.com", backends(1), ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The hostname only differs in a character which isn't allowed in names, it mustn't update the other Service
+	euDash, err := p.Create(ctx, "play-eu.example.com", backends(2), ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eu == euDash {
+		t.Fatalf("both hostnames share the service %s", eu)
+	}
+
+	ingress, err := p.Get(ctx, eu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ingress.HostName != "play.eu.example.com" || len(ingress.BackendSet) != 1 {
+		t.Errorf("ingress = %s with %d backends, want the unchanged play.eu.example.com", ingress.HostName, len(ingress.BackendSet))
+	}
+}
+
+func TestCreateLeftBehind(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	p := CreateProvider(c, "ingresses")
+
+	id, err := p.Create(ctx, "play.example.com", backends(1), ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The Service of the same hostname is adopted
+	adopted, err := p.Create(ctx, "play.example.com", backends(2), ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ingress, err := p.Get(ctx, adopted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if adopted != id || len(ingress.BackendSet) != 2 {
+		t.Errorf("ingress %s has %d backends, want %s with 2", adopted, len(ingress.BackendSet), id)
+	}
+
+	// The Service of another hostname isn't, even if it has the name of this one
+	svc := &corev1.Service{}
+	if err = c.Get(ctx, client.ObjectKey{Namespace: "ingresses", Name: id}, svc); err != nil {
+		t.Fatal(err)
+	}
+	metav1.SetMetaDataAnnotation(&svc.ObjectMeta, HostNameAnnotation, "other.example.com")
+	if err = c.Update(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.Create(ctx, "play.example.com", backends(3), ingressprovider.Options{}); !errors.Is(err, ErrorServiceConflict) {
+		t.Errorf("Create() error = %v, want %v", err, ErrorServiceConflict)
+	}
+	if ingress, err = p.Get(ctx, id); err != nil || len(ingress.BackendSet) != 2 {
+		t.Errorf("ingress has %d backends, want the 2 of the other hostname: %v", len(ingress.BackendSet), err)
+	}
+}
+
+func TestCreateUnsupported(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	p := CreateProvider(c, "ingresses")
+
+	weighted := backends(2)
+	weighted[1].Weight = 2
+	tests := []struct {
+		name       string
+		backendSet []*ingressprovider.Backend
+		opts       ingressprovider.Options
+		want       ingressprovider.Feature
+	}{
+		{name: "proxy protocol", backendSet: backends(1), opts: ingressprovider.Options{ProxyProtocol: true}, want: ingressprovider.FeatureProxyProtocol},
+		{name: "weights", backendSet: weighted, want: ingressprovider.FeatureWeights},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var unsupported *ingressprovider.UnsupportedError
+			if _, err := p.Create(ctx, "play.example.com", tt.backendSet, tt.opts); !errors.As(err, &unsupported) || unsupported.Feature != tt.want {
+				t.Errorf("Create() error = %v, want %s to be unsupported", err, tt.want)
+			}
+		})
+	}
+
+	list := &corev1.ServiceList{}
+	if err := c.List(ctx, list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 0 {
+		t.Errorf("got %d services, want none", len(list.Items))
+	}
+}
@@ -0,0 +1,67 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package service
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"testing"
+)
+
+var (
+	// cfg is the config of the envtest API server, nil if KUBEBUILDER_ASSETS isn't set
+	cfg    *rest.Config
+	scheme = runtime.NewScheme()
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+}
+
+func TestMain(m *testing.M) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		os.Exit(m.Run())
+	}
+
+	env := &envtest.Environment{}
+	var err error
+	if cfg, err = env.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, "error starting envtest:", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	if err = env.Stop(); err != nil {
+		fmt.Fprintln(os.Stderr, "error stopping envtest:", err)
+	}
+	os.Exit(code)
+}
+
+// requireEnvtest skips the test if the envtest API server isn't running
+func requireEnvtest(t *testing.T) {
+	t.Helper()
+	if cfg == nil {
+		t.Skip("KUBEBUILDER_ASSETS is not set")
+	}
+}
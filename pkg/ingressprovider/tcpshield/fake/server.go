@@ -0,0 +1,354 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package fake implements an in-memory TCPShield API, serving the routes used by the tcpshield provider
+package fake
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"innit.gg/singularity/pkg/ingressprovider/tcpshield"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Server is an in-memory TCPShield API for a single network
+type Server struct {
+	APIKey    string
+	NetworkId uint32
+
+	mu          sync.Mutex
+	nextId      uint32
+	domains     map[uint32]*tcpshield.Domain
+	backendSets map[uint32]*tcpshield.BackendSet
+	failures    []int
+
+	app *fiber.App
+}
+
+// NewServer returns a server accepting requests with the API key to the network
+func NewServer(apiKey string, networkId uint32) *Server {
+	s := &Server{
+		APIKey:      apiKey,
+		NetworkId:   networkId,
+		nextId:      1,
+		domains:     make(map[uint32]*tcpshield.Domain),
+		backendSets: make(map[uint32]*tcpshield.BackendSet),
+	}
+
+	s.app = fiber.New(fiber.Config{
+		DisableStartupMessage: true,
+		ErrorHandler:          errorHandler,
+	})
+	network := s.app.Group("/networks/:network", s.authenticate)
+	network.Get("/domains", s.listDomains)
+	network.Post("/domains", s.createDomain)
+	network.Patch("/domains/:id", s.updateDomain)
+	network.Delete("/domains/:id", s.deleteDomain)
+	network.Get("/backendSets", s.listBackendSets)
+	network.Post("/backendSets", s.createBackendSet)
+	network.Patch("/backendSets/:id", s.updateBackendSet)
+	network.Delete("/backendSets/:id", s.deleteBackendSet)
+
+	return s
+}
+
+// Start serves the API on a random local port, returning its base URL
+func (s *Server) Start() (string, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+
+	go func() {
+		_ = s.app.Listener(ln)
+	}()
+
+	return fmt.Sprintf("http://%s", ln.Addr()), nil
+}
+
+// Close stops serving the API
+func (s *Server) Close() error {
+	return s.app.Shutdown()
+}
+
+// Fail makes the next requests fail with the status codes, one per request.
+// Responses with 429 Too Many Requests ask to retry after a second.
+func (s *Server) Fail(codes ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = append(s.failures, codes...)
+}
+
+// Domains returns a copy of the domains, ordered by id
+func (s *Server) Domains() []tcpshield.Domain {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	domains := make([]tcpshield.Domain, 0, len(s.domains))
+	for _, domain := range s.domains {
+		domains = append(domains, *domain)
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		return domains[i].Id < domains[j].Id
+	})
+
+	return domains
+}
+
+// BackendSets returns a copy of the backend sets, ordered by id
+func (s *Server) BackendSets() []tcpshield.BackendSet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sets := make([]tcpshield.BackendSet, 0, len(s.backendSets))
+	for _, set := range s.backendSets {
+		set := *set
+		set.Backends = append([]string(nil), set.Backends...)
+		sets = append(sets, set)
+	}
+	sort.Slice(sets, func(i, j int) bool {
+		return sets[i].Id < sets[j].Id
+	})
+
+	return sets
+}
+
+// DeleteDomain removes a domain as if it was deleted outside the provider
+func (s *Server) DeleteDomain(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, domain := range s.domains {
+		if domain.Name == name {
+			delete(s.domains, id)
+		}
+	}
+}
+
+// authenticate rejects requests with the wrong API key or network, and injects failures
+func (s *Server) authenticate(c *fiber.Ctx) error {
+	if code := s.failure(); code != 0 {
+		if code == fiber.StatusTooManyRequests {
+			c.Set(fiber.HeaderRetryAfter, "1")
+		}
+		return fiber.NewError(code)
+	}
+
+	if c.Get("X-API-Key") != s.APIKey {
+		return fiber.ErrUnauthorized
+	}
+	if c.Params("network") != strconv.Itoa(int(s.NetworkId)) {
+		return fiber.ErrNotFound
+	}
+
+	return c.Next()
+}
+
+func (s *Server) listDomains(c *fiber.Ctx) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make(tcpshield.DomainList, 0, len(s.domains))
+	for _, domain := range s.domains {
+		list = append(list, domain)
+	}
+
+	return c.JSON(list)
+}
+
+func (s *Server) createDomain(c *fiber.Ctx) error {
+	descriptor := tcpshield.DomainDescriptor{}
+	if err := c.BodyParser(&descriptor); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, domain := range s.domains {
+		if domain.Name == descriptor.Name {
+			return fiber.NewError(fiber.StatusConflict, "domain already exists")
+		}
+	}
+	if _, ok := s.backendSets[descriptor.BackendSetId]; !ok {
+		return fiber.NewError(fiber.StatusBadRequest, "backend set not found")
+	}
+
+	now := time.Now()
+	domain := &tcpshield.Domain{
+		Id:               s.id(),
+		CreatedAt:        now,
+		UpdatedAt:        now,
+		DomainDescriptor: descriptor,
+	}
+	s.domains[domain.Id] = domain
+
+	return c.JSON(&tcpshield.DomainResponse{Data: domain})
+}
+
+func (s *Server) updateDomain(c *fiber.Ctx) error {
+	descriptor := tcpshield.DomainDescriptor{}
+	if err := c.BodyParser(&descriptor); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	domain, ok := s.domains[paramId(c)]
+	if !ok {
+		return fiber.ErrNotFound
+	}
+	if _, ok = s.backendSets[descriptor.BackendSetId]; !ok {
+		return fiber.NewError(fiber.StatusBadRequest, "backend set not found")
+	}
+
+	domain.DomainDescriptor = descriptor
+	domain.UpdatedAt = time.Now()
+
+	return c.JSON(&tcpshield.DomainResponse{Data: domain})
+}
+
+func (s *Server) deleteDomain(c *fiber.Ctx) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := paramId(c)
+	if _, ok := s.domains[id]; !ok {
+		return fiber.ErrNotFound
+	}
+	delete(s.domains, id)
+
+	return c.JSON(fiber.Map{})
+}
+
+func (s *Server) listBackendSets(c *fiber.Ctx) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make(tcpshield.BackendSetList, 0, len(s.backendSets))
+	for _, set := range s.backendSets {
+		list = append(list, set)
+	}
+
+	return c.JSON(list)
+}
+
+func (s *Server) createBackendSet(c *fiber.Ctx) error {
+	descriptor := tcpshield.BackendSetDescriptor{}
+	if err := c.BodyParser(&descriptor); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	set := &tcpshield.BackendSet{
+		Id:                   s.id(),
+		CreatedAt:            now,
+		UpdatedAt:            now,
+		BackendSetDescriptor: descriptor,
+	}
+	s.backendSets[set.Id] = set
+
+	res := &tcpshield.BackendSetResponse{}
+	res.Data = &struct {
+		Id uint32 `json:"id"`
+	}{Id: set.Id}
+
+	return c.JSON(res)
+}
+
+func (s *Server) updateBackendSet(c *fiber.Ctx) error {
+	descriptor := tcpshield.BackendSetDescriptor{}
+	if err := c.BodyParser(&descriptor); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	set, ok := s.backendSets[paramId(c)]
+	if !ok {
+		return fiber.ErrNotFound
+	}
+	set.BackendSetDescriptor = descriptor
+	set.UpdatedAt = time.Now()
+
+	return c.JSON(fiber.Map{})
+}
+
+func (s *Server) deleteBackendSet(c *fiber.Ctx) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := paramId(c)
+	if _, ok := s.backendSets[id]; !ok {
+		return fiber.ErrNotFound
+	}
+	for _, domain := range s.domains {
+		if domain.BackendSetId == id {
+			return fiber.NewError(fiber.StatusConflict, "backend set is in use")
+		}
+	}
+	delete(s.backendSets, id)
+
+	return c.JSON(fiber.Map{})
+}
+
+// errorHandler responds with the status code of the error and a JSON body describing it
+func errorHandler(c *fiber.Ctx, err error) error {
+	code := fiber.StatusInternalServerError
+	if e, ok := err.(*fiber.Error); ok {
+		code = e.Code
+	}
+
+	return c.Status(code).JSON(fiber.Map{"status": code, "error": err.Error()})
+}
+
+// failure returns the status code of the next injected failure, or zero
+func (s *Server) failure() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.failures) == 0 {
+		return 0
+	}
+	code := s.failures[0]
+	s.failures = s.failures[1:]
+
+	return code
+}
+
+// id returns the next free id, ids are unique across domains and backend sets
+func (s *Server) id() uint32 {
+	id := s.nextId
+	s.nextId++
+	return id
+}
+
+func paramId(c *fiber.Ctx) uint32 {
+	id, _ := strconv.ParseUint(c.Params("id"), 10, 32)
+	return uint32(id)
+}
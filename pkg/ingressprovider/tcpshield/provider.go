@@ -19,30 +19,79 @@
 package tcpshield
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/pkg/errors"
 	"innit.gg/singularity/pkg/ingressprovider"
+	"net"
 	"strconv"
+	"strings"
+	"time"
 )
 
 const (
+	// sweepGracePeriod is the minimum age of unused backend sets before they are swept,
+	// as backend sets are created before the domain using them
+	sweepGracePeriod = 10 * time.Minute
+
+	// Endpoint is the default base URL of the TCPShield API
 	Endpoint       = "https://api.tcpshield.com"
 	ResourcePrefix = "singularity-"
 )
 
 var (
-	ErrorDomainNotFound = errors.New("domain not found")
+	ErrorDomainNotFound = errors.Wrap(ingressprovider.ErrorNotFound, "domain not found")
+
+	// supported are the features of TCPShield, which only proxies Java edition TCP connections
+	supported = []ingressprovider.Feature{ingressprovider.FeatureProxyProtocol}
 )
 
 type provider struct {
 	apiKey    string
 	networkId uint32
+	endpoint  string
 	client    *fiber.Client
+	limiter   *ingressprovider.Limiter
+}
+
+// Extension contains the TCPShield specific options of an ingress, passed as an ingressprovider.Options extension
+type Extension struct {
+	// BAC enables Backend Access Control for the domain
+	BAC bool
+}
+
+// Option configures the provider
+type Option func(p *provider)
+
+// WithEndpoint changes the base URL of the API, e.g. to the one of a fake server
+func WithEndpoint(endpoint string) Option {
+	return func(p *provider) {
+		p.endpoint = endpoint
+	}
+}
+
+// WithClient changes the client requests are sent with
+func WithClient(client *fiber.Client) Option {
+	return func(p *provider) {
+		p.client = client
+	}
+}
+
+// WithLimiter changes the limiter requests are sent through, which is ingressprovider.DefaultLimits by default
+func WithLimiter(limiter *ingressprovider.Limiter) Option {
+	return func(p *provider) {
+		p.limiter = limiter
+	}
 }
 
-func (p *provider) Create(hostName string, backendSet []*ingressprovider.Backend) (string, error) {
-	backendSetId, err := p.updateBackendSet(hostName, backendSet)
+func (p *provider) Create(ctx context.Context, hostName string, backendSet []*ingressprovider.Backend, opts ingressprovider.Options) (string, error) {
+	if err := ingressprovider.CheckFeatures(backendSet, opts, supported...); err != nil {
+		return "", err
+	}
+
+	backendSetId, err := p.updateBackendSet(ctx, hostName, backendSet, opts)
 	if err != nil {
 		return "", err
 	}
@@ -50,20 +99,26 @@ func (p *provider) Create(hostName string, backendSet []*ingressprovider.Backend
 	descriptor := &DomainDescriptor{
 		Name:         hostName,
 		BackendSetId: backendSetId,
-		BAC:          false,
+		BAC:          extension(opts).BAC,
 	}
-	res := &DomainResponse{}
-	code, _, errs := p.client.Post(fmt.Sprintf("%s/networks/%d/domains", Endpoint, p.networkId)).
-		Add("X-API-Key", p.apiKey).
-		JSON(descriptor).
-		Struct(res)
 
-	if len(errs) != 0 {
-		return "", errs[0]
+	// A domain left behind, e.g. by a create whose ID couldn't be stored, is adopted instead of conflicting forever.
+	domain, err := p.findDomain(ctx, func(domain *Domain) bool {
+		return domain.Name == hostName
+	})
+	switch {
+	case err == nil:
+		if err = p.do(ctx, fiber.MethodPatch, p.url("/domains/%d", domain.Id), descriptor, nil); err != nil {
+			return "", errors.Wrapf(err, "error updating domain %s", hostName)
+		}
+		return strconv.Itoa(int(domain.Id)), nil
+	case !errors.Is(err, ErrorDomainNotFound):
+		return "", err
 	}
 
-	if code != 200 {
-		return "", errors.Errorf("unexpected status code: %d", code)
+	res := &DomainResponse{}
+	if err = p.do(ctx, fiber.MethodPost, p.url("/domains"), descriptor, res); err != nil {
+		return "", errors.Wrapf(err, "error creating domain %s", hostName)
 	}
 
 	if res.Data == nil {
@@ -73,33 +128,19 @@ func (p *provider) Create(hostName string, backendSet []*ingressprovider.Backend
 	return strconv.Itoa(int(res.Data.Id)), nil
 }
 
-func (p *provider) Update(hostName string, backendSet []*ingressprovider.Backend) error {
-	var list DomainList
-	code, _, errs := p.client.Get(fmt.Sprintf("%s/networks/%d/domains", Endpoint, p.networkId)).
-		Add("X-API-Key", p.apiKey).
-		Struct(&list)
-
-	if len(errs) != 0 {
-		return errs[0]
-	}
-
-	if code != 200 {
-		return errors.Errorf("unexpected status code: %d", code)
-	}
-
-	var id uint32
-	for _, domain := range list {
-		if domain.Name == hostName {
-			id = domain.Id
-			break
-		}
+func (p *provider) Update(ctx context.Context, hostName string, backendSet []*ingressprovider.Backend, opts ingressprovider.Options) error {
+	if err := ingressprovider.CheckFeatures(backendSet, opts, supported...); err != nil {
+		return err
 	}
 
-	if id == 0 {
-		return ErrorDomainNotFound
+	domain, err := p.findDomain(ctx, func(domain *Domain) bool {
+		return domain.Name == hostName
+	})
+	if err != nil {
+		return err
 	}
 
-	backendSetId, err := p.updateBackendSet(hostName, backendSet)
+	backendSetId, err := p.updateBackendSet(ctx, hostName, backendSet, opts)
 	if err != nil {
 		return err
 	}
@@ -107,62 +148,144 @@ func (p *provider) Update(hostName string, backendSet []*ingressprovider.Backend
 	descriptor := &DomainDescriptor{
 		Name:         hostName,
 		BackendSetId: backendSetId,
-		BAC:          false,
+		BAC:          extension(opts).BAC,
+	}
+	if err = p.do(ctx, fiber.MethodPatch, p.url("/domains/%d", domain.Id), descriptor, nil); err != nil {
+		return errors.Wrapf(err, "error updating domain %s", hostName)
 	}
 
-	code, _, errs = p.client.Patch(fmt.Sprintf("%s/networks/%d/domains/%d", Endpoint, p.networkId, id)).
-		Add("X-API-Key", p.apiKey).
-		JSON(descriptor).
-		Bytes()
+	return nil
+}
 
-	if len(errs) != 0 {
-		return errs[0]
+// Delete deletes the domain, along with its backend set if it was created by singularity
+func (p *provider) Delete(ctx context.Context, id string) error {
+	domain, err := p.findDomain(ctx, func(domain *Domain) bool {
+		return strconv.Itoa(int(domain.Id)) == id
+	})
+	if err != nil {
+		return err
 	}
 
-	if code != 200 {
-		return errors.Errorf("unexpected status code: %d", code)
+	if err = p.do(ctx, fiber.MethodDelete, p.url("/domains/%d", domain.Id), nil, nil); err != nil {
+		return errors.Wrapf(err, "error deleting domain %s", domain.Name)
+	}
+
+	// The backend set can only be deleted once no domain uses it anymore.
+	var list BackendSetList
+	if err = p.do(ctx, fiber.MethodGet, p.url("/backendSets"), nil, &list); err != nil {
+		return errors.Wrap(err, "error listing backend sets")
+	}
+	for _, set := range list {
+		if set.Id == domain.BackendSetId && strings.HasPrefix(set.Name, ResourcePrefix) {
+			if err = p.do(ctx, fiber.MethodDelete, p.url("/backendSets/%d", set.Id), nil, nil); err != nil {
+				return errors.Wrapf(err, "error deleting backend set %s", set.Name)
+			}
+		}
 	}
 
 	return nil
 }
 
-func (p *provider) Delete(id string) error {
-	// TODO: Delete backendset
-	code, _, errs := p.client.Delete(fmt.Sprintf("%s/networks/%d/domains/%s", Endpoint, p.networkId, id)).
-		Add("X-API-Key", p.apiKey).
-		Bytes()
+func (p *provider) Get(ctx context.Context, id string) (*ingressprovider.Ingress, error) {
+	ingresses, err := p.List(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	if len(errs) != 0 {
-		return errs[0]
+	for _, ingress := range ingresses {
+		if ingress.ID == id {
+			return ingress, nil
+		}
 	}
 
-	if code != 200 {
-		return errors.Errorf("unexpected status code: %d", code)
+	return nil, ErrorDomainNotFound
+}
+
+// List returns all domains of the network, along with the backends of their backend sets
+func (p *provider) List(ctx context.Context) ([]*ingressprovider.Ingress, error) {
+	var domains DomainList
+	if err := p.do(ctx, fiber.MethodGet, p.url("/domains"), nil, &domains); err != nil {
+		return nil, errors.Wrap(err, "error listing domains")
+	}
+	var sets BackendSetList
+	if err := p.do(ctx, fiber.MethodGet, p.url("/backendSets"), nil, &sets); err != nil {
+		return nil, errors.Wrap(err, "error listing backend sets")
 	}
 
-	return nil
+	byId := make(map[uint32]*BackendSet, len(sets))
+	for _, set := range sets {
+		byId[set.Id] = set
+	}
+
+	ingresses := make([]*ingressprovider.Ingress, len(domains))
+	for i, domain := range domains {
+		ingress := &ingressprovider.Ingress{
+			ID:       strconv.Itoa(int(domain.Id)),
+			HostName: domain.Name,
+			Options: ingressprovider.Options{
+				Extensions: []interface{}{&Extension{BAC: domain.BAC}},
+			},
+			Verified: domain.Verified,
+		}
+		if set, ok := byId[domain.BackendSetId]; ok {
+			ingress.BackendSet = parseBackendSet(set.Backends)
+			ingress.Options.ProxyProtocol = set.ProxyProtocol
+		}
+		ingresses[i] = ingress
+	}
+
+	return ingresses, nil
 }
 
-func CreateProvider(apiKey string, networkId uint32) ingressprovider.Provider {
-	return &provider{
+// Sweep deletes the backend sets created by singularity which aren't used by any domain anymore
+func (p *provider) Sweep(ctx context.Context) (int, error) {
+	var domains DomainList
+	if err := p.do(ctx, fiber.MethodGet, p.url("/domains"), nil, &domains); err != nil {
+		return 0, errors.Wrap(err, "error listing domains")
+	}
+	var sets BackendSetList
+	if err := p.do(ctx, fiber.MethodGet, p.url("/backendSets"), nil, &sets); err != nil {
+		return 0, errors.Wrap(err, "error listing backend sets")
+	}
+
+	used := make(map[uint32]bool, len(domains))
+	for _, domain := range domains {
+		used[domain.BackendSetId] = true
+	}
+
+	deleted := 0
+	for _, set := range sets {
+		if used[set.Id] || !strings.HasPrefix(set.Name, ResourcePrefix) || time.Since(set.UpdatedAt) < sweepGracePeriod {
+			continue
+		}
+		if err := p.do(ctx, fiber.MethodDelete, p.url("/backendSets/%d", set.Id), nil, nil); err != nil {
+			return deleted, errors.Wrapf(err, "error deleting backend set %s", set.Name)
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+func CreateProvider(apiKey string, networkId uint32, opts ...Option) ingressprovider.Provider {
+	p := &provider{
 		apiKey:    apiKey,
 		networkId: networkId,
+		endpoint:  Endpoint,
 		client:    fiber.AcquireClient(),
+		limiter:   ingressprovider.NewLimiter("tcpshield", ingressprovider.DefaultLimits),
+	}
+	for _, opt := range opts {
+		opt(p)
 	}
+
+	return p
 }
 
-func (p *provider) updateBackendSet(hostName string, backendSet []*ingressprovider.Backend) (uint32, error) {
+func (p *provider) updateBackendSet(ctx context.Context, hostName string, backendSet []*ingressprovider.Backend, opts ingressprovider.Options) (uint32, error) {
 	var list BackendSetList
-	code, _, errs := p.client.Get(fmt.Sprintf("%s/networks/%d/backendSets", Endpoint, p.networkId)).
-		Add("X-API-Key", p.apiKey).
-		Struct(&list)
-
-	if len(errs) != 0 {
-		return 0, errs[0]
-	}
-
-	if code != 200 {
-		return 0, errors.Errorf("unexpected status code: %d", code)
+	if err := p.do(ctx, fiber.MethodGet, p.url("/backendSets"), nil, &list); err != nil {
+		return 0, errors.Wrap(err, "error listing backend sets")
 	}
 
 	// Check if there is an existing backend set.
@@ -177,20 +300,15 @@ func (p *provider) updateBackendSet(hostName string, backendSet []*ingressprovid
 	backends := convertBackendSet(backendSet)
 	descriptor := &BackendSetDescriptor{
 		Name:          ResourcePrefix + hostName,
-		ProxyProtocol: false,
+		ProxyProtocol: opts.ProxyProtocol,
 		Backends:      backends,
 	}
 
 	if id == 0 {
 		// We need to create a new backend set.
 		res := &BackendSetResponse{}
-		code, _, errs = p.client.Post(fmt.Sprintf("%s/networks/%d/backendSets", Endpoint, p.networkId)).
-			Add("X-API-Key", p.apiKey).
-			JSON(descriptor).
-			Struct(res)
-
-		if code != 200 {
-			return 0, errors.Errorf("unexpected status code: %d", code)
+		if err := p.do(ctx, fiber.MethodPost, p.url("/backendSets"), descriptor, res); err != nil {
+			return 0, errors.Wrapf(err, "error creating backend set %s", descriptor.Name)
 		}
 
 		if res.Data == nil {
@@ -200,23 +318,138 @@ func (p *provider) updateBackendSet(hostName string, backendSet []*ingressprovid
 		id = res.Data.Id
 	} else {
 		// We can update an existing backend set.
-		code, _, errs = p.client.Patch(fmt.Sprintf("%s/networks/%d/backendSets/%d", Endpoint, p.networkId, id)).
-			Add("X-API-Key", p.apiKey).
-			JSON(descriptor).
-			Bytes()
-
-		if code != 200 {
-			return 0, errors.Errorf("unexpected status code: %d", code)
+		if err := p.do(ctx, fiber.MethodPatch, p.url("/backendSets/%d", id), descriptor, nil); err != nil {
+			return 0, errors.Wrapf(err, "error updating backend set %s", descriptor.Name)
 		}
 	}
 
 	return id, nil
 }
 
+// findDomain returns the first domain matching f, or ErrorDomainNotFound
+func (p *provider) findDomain(ctx context.Context, f func(domain *Domain) bool) (*Domain, error) {
+	var list DomainList
+	if err := p.do(ctx, fiber.MethodGet, p.url("/domains"), nil, &list); err != nil {
+		return nil, errors.Wrap(err, "error listing domains")
+	}
+
+	for _, domain := range list {
+		if f(domain) {
+			return domain, nil
+		}
+	}
+
+	return nil, ErrorDomainNotFound
+}
+
+// url returns the URL of the network resource at the formatted path
+func (p *provider) url(format string, args ...interface{}) string {
+	return fmt.Sprintf("%s/networks/%d", p.endpoint, p.networkId) + fmt.Sprintf(format, args...)
+}
+
+// do sends the authenticated request through the limiter, decoding the response into res unless it's nil.
+// The body is sent as JSON unless it's nil. Transport errors are returned as is, unexpected status codes as
+// ingressprovider.StatusError.
+func (p *provider) do(ctx context.Context, method, url string, body, res interface{}) error {
+	return p.limiter.Do(ctx, ingressprovider.Idempotent(method), func(ctx context.Context) error {
+		return p.send(ctx, p.agent(method, url), body, res)
+	})
+}
+
+// agent returns a new agent of the request, as agents are released once their request was sent
+func (p *provider) agent(method, url string) *fiber.Agent {
+	switch method {
+	case fiber.MethodPost:
+		return p.client.Post(url)
+	case fiber.MethodPatch:
+		return p.client.Patch(url)
+	case fiber.MethodDelete:
+		return p.client.Delete(url)
+	default:
+		return p.client.Get(url)
+	}
+}
+
+// send sends a single request with the agent.
+// The agent doesn't support cancellation, so the deadline of the context is used as its timeout.
+func (p *provider) send(ctx context.Context, agent *fiber.Agent, body, res interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		agent.Timeout(time.Until(deadline))
+	}
+	if body != nil {
+		agent.JSON(body)
+	}
+
+	resp := fiber.AcquireResponse()
+	defer fiber.ReleaseResponse(resp)
+
+	code, data, errs := agent.Add("X-API-Key", p.apiKey).SetResponse(resp).Bytes()
+	if len(errs) != 0 {
+		return errs[0]
+	}
+
+	if code != 200 {
+		return &ingressprovider.StatusError{
+			Code:       code,
+			RetryAfter: ingressprovider.ParseRetryAfter(string(resp.Header.Peek(fiber.HeaderRetryAfter))),
+		}
+	}
+
+	if res != nil {
+		if err := json.Unmarshal(data, res); err != nil {
+			return errors.Wrap(err, "error decoding response")
+		}
+	}
+
+	return nil
+}
+
+// convertBackendSet returns the addresses of the backends, without the draining ones as TCPShield can't drain them
 func convertBackendSet(set []*ingressprovider.Backend) []string {
-	newSet := make([]string, len(set))
-	for i, descriptor := range set {
-		newSet[i] = fmt.Sprintf("%s:%d", descriptor.IP, descriptor.Port)
+	newSet := make([]string, 0, len(set))
+	for _, descriptor := range set {
+		if !descriptor.Draining {
+			newSet = append(newSet, descriptor.Address())
+		}
 	}
 	return newSet
 }
+
+// extension returns the TCPShield extension of the options, or the default one
+func extension(opts ingressprovider.Options) Extension {
+	for _, e := range opts.Extensions {
+		if extension, ok := e.(*Extension); ok && extension != nil {
+			return *extension
+		}
+	}
+
+	return Extension{}
+}
+
+// parseBackendSet parses the backends of a backend set, skipping those which aren't an IP address and port
+func parseBackendSet(set []string) []*ingressprovider.Backend {
+	backends := make([]*ingressprovider.Backend, 0, len(set))
+	for _, backend := range set {
+		i := strings.LastIndex(backend, ":")
+		if i < 0 {
+			continue
+		}
+
+		ip := net.ParseIP(strings.Trim(backend[:i], "[]"))
+		port, err := strconv.ParseUint(backend[i+1:], 10, 16)
+		if ip == nil || err != nil {
+			continue
+		}
+		backends = append(backends, &ingressprovider.Backend{
+			IP:       ip,
+			Port:     uint16(port),
+			Protocol: ingressprovider.ProtocolTCP,
+			Weight:   ingressprovider.DefaultWeight,
+		})
+	}
+
+	return backends
+}
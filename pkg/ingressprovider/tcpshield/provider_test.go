@@ -0,0 +1,312 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package tcpshield_test
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+	"innit.gg/singularity/pkg/ingressprovider"
+	"innit.gg/singularity/pkg/ingressprovider/tcpshield"
+	"innit.gg/singularity/pkg/ingressprovider/tcpshield/fake"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const (
+	apiKey    = "key"
+	networkId = 42
+)
+
+// newProvider returns a provider sending its requests to a new fake server, without retrying them
+func newProvider(t *testing.T, key string) (ingressprovider.Provider, *fake.Server) {
+	t.Helper()
+	return newLimitedProvider(t, key, ingressprovider.Limits{})
+}
+
+// newLimitedProvider returns a provider sending its requests to a new fake server through a limiter with the limits
+func newLimitedProvider(t *testing.T, key string, limits ingressprovider.Limits) (ingressprovider.Provider, *fake.Server) {
+	t.Helper()
+
+	server := fake.NewServer(apiKey, networkId)
+	endpoint, err := server.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = server.Close()
+	})
+
+	limiter := ingressprovider.NewLimiter(t.Name(), limits)
+	return tcpshield.CreateProvider(key, networkId, tcpshield.WithEndpoint(endpoint), tcpshield.WithClient(fiber.AcquireClient()),
+		tcpshield.WithLimiter(limiter)), server
+}
+
+func backend(ip string, port uint16) *ingressprovider.Backend {
+	return &ingressprovider.Backend{
+		IP:       net.ParseIP(ip),
+		Port:     port,
+		Protocol: ingressprovider.ProtocolTCP,
+		Weight:   ingressprovider.DefaultWeight,
+	}
+}
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+	p, server := newProvider(t, apiKey)
+
+	backends := []*ingressprovider.Backend{backend("10.0.0.1", 25565), backend("fd00::1", 25566)}
+	draining := backend("10.0.0.2", 25565)
+	draining.Draining = true
+	opts := ingressprovider.Options{ProxyProtocol: true, Extensions: []interface{}{&tcpshield.Extension{BAC: true}}}
+
+	id, err := p.Create(ctx, "play.example.com", append(backends, draining), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	domains := server.Domains()
+	if len(domains) != 1 || strconv.Itoa(int(domains[0].Id)) != id {
+		t.Fatalf("domains = %v, want a single one with id %s", domains, id)
+	}
+	if domains[0].Name != "play.example.com" || !domains[0].BAC {
+		t.Errorf("domain = %+v, want play.example.com with BAC", domains[0].DomainDescriptor)
+	}
+
+	sets := server.BackendSets()
+	if len(sets) != 1 || sets[0].Id != domains[0].BackendSetId {
+		t.Fatalf("backend sets = %v, want the one of the domain", sets)
+	}
+	// TCPShield can't drain backends, so draining ones are removed
+	want := tcpshield.BackendSetDescriptor{
+		Name:          tcpshield.ResourcePrefix + "play.example.com",
+		ProxyProtocol: true,
+		Backends:      []string{"10.0.0.1:25565", "[fd00::1]:25566"},
+	}
+	if !reflect.DeepEqual(sets[0].BackendSetDescriptor, want) {
+		t.Errorf("backend set = %+v, want %+v", sets[0].BackendSetDescriptor, want)
+	}
+
+	ingress, err := p.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ingress.HostName != "play.example.com" || !opts.Matches(ingress.Options) {
+		t.Errorf("ingress = %s with %+v, want play.example.com with %+v", ingress.HostName, ingress.Options, opts)
+	}
+	if len(ingress.BackendSet) != 2 || !ingress.BackendSet[1].IP.Equal(net.ParseIP("fd00::1")) {
+		t.Errorf("ingress backends = %v, want those which aren't draining", ingress.BackendSet)
+	}
+}
+
+func TestCreateUnsupported(t *testing.T) {
+	p, server := newProvider(t, apiKey)
+
+	udp := backend("10.0.0.1", 19132)
+	udp.Protocol = ingressprovider.ProtocolUDP
+
+	_, err := p.Create(context.Background(), "play.example.com", []*ingressprovider.Backend{udp}, ingressprovider.Options{})
+	var unsupported *ingressprovider.UnsupportedError
+	if !errors.As(err, &unsupported) || unsupported.Feature != ingressprovider.FeatureUDP {
+		t.Errorf("Create() error = %v, want UDP to be unsupported", err)
+	}
+	if sets := server.BackendSets(); len(sets) != 0 {
+		t.Errorf("backend sets = %v, want none to be created", sets)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	p, server := newProvider(t, apiKey)
+
+	id, err := p.Create(ctx, "play.example.com", []*ingressprovider.Backend{backend("10.0.0.1", 25565)}, ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := ingressprovider.Options{Extensions: []interface{}{&tcpshield.Extension{BAC: true}}}
+	if err = p.Update(ctx, "play.example.com", []*ingressprovider.Backend{backend("10.0.0.2", 25565)}, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// The backend set is updated in place
+	sets := server.BackendSets()
+	if len(sets) != 1 || !reflect.DeepEqual(sets[0].Backends, []string{"10.0.0.2:25565"}) {
+		t.Errorf("backend sets = %v, want the updated one", sets)
+	}
+	domains := server.Domains()
+	if len(domains) != 1 || strconv.Itoa(int(domains[0].Id)) != id || !domains[0].BAC {
+		t.Errorf("domains = %v, want %s with BAC", domains, id)
+	}
+}
+
+func TestCreateLeftBehind(t *testing.T) {
+	ctx := context.Background()
+	p, server := newProvider(t, apiKey)
+
+	id, err := p.Create(ctx, "play.example.com", []*ingressprovider.Backend{backend("10.0.0.1", 25565)}, ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Creating the hostname again, e.g. because the ID of the first domain was lost, adopts the domain
+	opts := ingressprovider.Options{Extensions: []interface{}{&tcpshield.Extension{BAC: true}}}
+	adopted, err := p.Create(ctx, "play.example.com", []*ingressprovider.Backend{backend("10.0.0.2", 25565)}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if adopted != id {
+		t.Errorf("Create() = %s, want the domain %s", adopted, id)
+	}
+
+	domains := server.Domains()
+	if len(domains) != 1 || !domains[0].BAC {
+		t.Errorf("domains = %v, want the updated %s", domains, id)
+	}
+	sets := server.BackendSets()
+	if len(sets) != 1 || sets[0].Id != domains[0].BackendSetId || !reflect.DeepEqual(sets[0].Backends, []string{"10.0.0.2:25565"}) {
+		t.Errorf("backend sets = %v, want the updated one of the domain", sets)
+	}
+}
+
+func TestDomainNotFound(t *testing.T) {
+	ctx := context.Background()
+	p, server := newProvider(t, apiKey)
+
+	id, err := p.Create(ctx, "play.example.com", []*ingressprovider.Backend{backend("10.0.0.1", 25565)}, ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.DeleteDomain("play.example.com")
+
+	err = p.Update(ctx, "play.example.com", []*ingressprovider.Backend{backend("10.0.0.2", 25565)}, ingressprovider.Options{})
+	if !errors.Is(err, tcpshield.ErrorDomainNotFound) || !errors.Is(err, ingressprovider.ErrorNotFound) {
+		t.Errorf("Update() error = %v, want %v", err, tcpshield.ErrorDomainNotFound)
+	}
+	if _, err = p.Get(ctx, id); !errors.Is(err, ingressprovider.ErrorNotFound) {
+		t.Errorf("Get() error = %v, want %v", err, ingressprovider.ErrorNotFound)
+	}
+	if err = p.Delete(ctx, id); !errors.Is(err, ingressprovider.ErrorNotFound) {
+		t.Errorf("Delete() error = %v, want %v", err, ingressprovider.ErrorNotFound)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	p, server := newProvider(t, apiKey)
+
+	id, err := p.Create(ctx, "play.example.com", []*ingressprovider.Backend{backend("10.0.0.1", 25565)}, ingressprovider.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Delete(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	if domains := server.Domains(); len(domains) != 0 {
+		t.Errorf("domains = %v, want none", domains)
+	}
+	if sets := server.BackendSets(); len(sets) != 0 {
+		t.Errorf("backend sets = %v, want the one created for the domain to be deleted", sets)
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		name           string
+		key            string
+		fail           int
+		wantCode       int
+		wantRetryable  bool
+		wantRetryAfter time.Duration
+	}{
+		{name: "unauthorized", key: "wrong", wantCode: fiber.StatusUnauthorized},
+		{name: "server error", key: apiKey, fail: fiber.StatusInternalServerError, wantCode: fiber.StatusInternalServerError, wantRetryable: true},
+		{name: "too many requests", key: apiKey, fail: fiber.StatusTooManyRequests, wantCode: fiber.StatusTooManyRequests, wantRetryable: true, wantRetryAfter: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, server := newProvider(t, tt.key)
+			if tt.fail != 0 {
+				server.Fail(tt.fail)
+			}
+
+			_, err := p.List(context.Background())
+			var statusErr *ingressprovider.StatusError
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("List() error = %v, want a StatusError", err)
+			}
+			if statusErr.Code != tt.wantCode || statusErr.Retryable() != tt.wantRetryable || statusErr.RetryAfter != tt.wantRetryAfter {
+				t.Errorf("StatusError = %d retryable %v after %s, want %d retryable %v after %s", statusErr.Code,
+					statusErr.Retryable(), statusErr.RetryAfter, tt.wantCode, tt.wantRetryable, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name    string
+		fail    []int
+		wantErr bool
+	}{
+		// Creating a domain lists the backend sets, before creating one and then the domain
+		{name: "idempotent request failing", fail: []int{fiber.StatusInternalServerError}},
+		{name: "idempotent request failing too often", fail: []int{500, 500, 500}, wantErr: true},
+		{name: "request failing", fail: []int{0, fiber.StatusInternalServerError}, wantErr: true},
+		{name: "request rate limited", fail: []int{0, fiber.StatusTooManyRequests}},
+		{name: "request rejected", fail: []int{0, fiber.StatusBadRequest}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, server := newLimitedProvider(t, apiKey, ingressprovider.Limits{Retries: 2, MaxBackoff: time.Millisecond})
+			server.Fail(tt.fail...)
+
+			_, err := p.Create(context.Background(), "play.example.com", []*ingressprovider.Backend{backend("10.0.0.1", 25565)}, ingressprovider.Options{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Create() error = %v, want error %v", err, tt.wantErr)
+			}
+			if domains := server.Domains(); len(domains) != 1 && !tt.wantErr {
+				t.Errorf("domains = %v, want the created one", domains)
+			}
+		})
+	}
+}
+
+func TestSweep(t *testing.T) {
+	ctx := context.Background()
+	p, server := newProvider(t, apiKey)
+
+	if _, err := p.Create(ctx, "play.example.com", []*ingressprovider.Backend{backend("10.0.0.1", 25565)}, ingressprovider.Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The backend set is still used by the domain, and unused ones are only swept after a grace period
+	deleted, err := p.(ingressprovider.Sweeper).Sweep(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 0 || len(server.BackendSets()) != 1 {
+		t.Errorf("swept %d backend sets, want none", deleted)
+	}
+}
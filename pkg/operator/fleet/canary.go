@@ -0,0 +1,98 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package fleet
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
+)
+
+// handleCanary advances the canary steps of an ongoing rollout, and records the current step in the status.
+// Returns the duration after which the current step should be evaluated again.
+func (r *Reconciler) handleCanary(ctx context.Context, fleet *singularityv1.Fleet, active *singularityv1.GameServerSet, rest []*singularityv1.GameServerSet, status *singularityv1.FleetStatus) (time.Duration, error) {
+	steps := int32(len(fleet.Spec.CanarySteps))
+	if steps == 0 || len(nonEmptyGameServerSets(rest)) == 0 {
+		// There is no ongoing canary rollout.
+		return 0, nil
+	}
+
+	now := metav1.Now()
+	step, started := int32(0), now
+	var availableSince *metav1.Time
+	if fleet.Status.Revision == status.Revision && fleet.Status.CurrentStep != nil && fleet.Status.CurrentStepStartTime != nil {
+		// Continue the rollout of the same revision
+		step, started = *fleet.Status.CurrentStep, *fleet.Status.CurrentStepStartTime
+		availableSince = fleet.Status.CurrentStepAvailableTime
+	}
+
+	// The pause of a step only starts counting once its GameServers are available.
+	if availableSince == nil && canaryAvailable(fleet, active, step) {
+		availableSince = &now
+	}
+
+	if step < steps && !fleet.Spec.Paused {
+		_, promoted := fleet.ObjectMeta.Annotations[singularityv1.FleetPromoteAnnotation]
+		if promoted {
+			// A promotion only applies to a single step.
+			patch := client.MergeFrom(fleet.DeepCopy())
+			delete(fleet.ObjectMeta.Annotations, singularityv1.FleetPromoteAnnotation)
+			if err := r.Patch(ctx, fleet, patch); err != nil {
+				return 0, errors.Wrapf(err, "error removing promote annotation from fleet %s", fleet.ObjectMeta.Name)
+			}
+		}
+
+		pause := fleet.Spec.CanarySteps[step].Pause
+		elapsed := pause != nil && availableSince != nil && now.Sub(availableSince.Time) >= pause.Duration
+
+		if promoted || elapsed {
+			step++
+			started = now
+			availableSince = nil
+			if canaryAvailable(fleet, active, step) {
+				availableSince = &now
+			}
+			r.Recorder.Eventf(fleet, v1.EventTypeNormal, "CanaryStep", "Advancing rollout of revision %d to step %d/%d", status.Revision, step, steps)
+		}
+	}
+
+	status.CurrentStep = &step
+	status.CurrentStepStartTime = &started
+	status.CurrentStepAvailableTime = availableSince
+
+	if step < steps && availableSince != nil {
+		if pause := fleet.Spec.CanarySteps[step].Pause; pause != nil {
+			if remaining := pause.Duration - now.Sub(availableSince.Time); remaining > 0 {
+				return remaining, nil
+			}
+		}
+	}
+
+	return 0, nil
+}
+
+// canaryAvailable returns whether the replicas of the canary step are available in the active GameServerSet
+func canaryAvailable(fleet *singularityv1.Fleet, active *singularityv1.GameServerSet, step int32) bool {
+	canaryReplicas, canary := fleet.CanaryReplicas(&step)
+	return canary && active.Status.ReadyReplicas+active.Status.AllocatedReplicas+active.Status.ReservedReplicas >= canaryReplicas
+}
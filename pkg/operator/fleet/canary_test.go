@@ -0,0 +1,178 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package fleet
+
+import (
+	"context"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+	"time"
+)
+
+func TestHandleCanary(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		steps     []singularityv1.FleetCanaryStep
+		paused    bool
+		promoted  bool
+		revision  int64
+		step      int32
+		started   time.Time
+		available int32
+		// availableSince is when the replicas of the step became available, unset if zero
+		availableSince time.Time
+		wantStep       *int32
+		wantAfter      bool
+	}{
+		{
+			name:     "no canary steps",
+			revision: 2,
+		},
+		{
+			name:     "new rollout starts at the first step",
+			steps:    []singularityv1.FleetCanaryStep{{Weight: 10}},
+			revision: 3,
+			step:     1,
+			started:  now.Add(-time.Hour),
+			wantStep: int32Ptr(0),
+		},
+		{
+			name:     "held until promoted",
+			steps:    []singularityv1.FleetCanaryStep{{Weight: 10}},
+			revision: 2,
+			started:  now.Add(-time.Hour),
+			wantStep: int32Ptr(0),
+		},
+		{
+			name:     "promoted",
+			steps:    []singularityv1.FleetCanaryStep{{Weight: 10}, {Weight: 50}},
+			promoted: true,
+			revision: 2,
+			started:  now,
+			wantStep: int32Ptr(1),
+		},
+		{
+			name:     "paused ignores promotion",
+			steps:    []singularityv1.FleetCanaryStep{{Weight: 10}},
+			paused:   true,
+			promoted: true,
+			revision: 2,
+			started:  now,
+			wantStep: int32Ptr(0),
+		},
+		{
+			name:           "pause elapsed",
+			steps:          []singularityv1.FleetCanaryStep{{Weight: 10, Pause: &metav1.Duration{Duration: time.Minute}}},
+			revision:       2,
+			started:        now.Add(-2 * time.Minute),
+			available:      1,
+			availableSince: now.Add(-2 * time.Minute),
+			wantStep:       int32Ptr(1),
+		},
+		{
+			name:      "pause counted from availability",
+			steps:     []singularityv1.FleetCanaryStep{{Weight: 10, Pause: &metav1.Duration{Duration: time.Minute}}},
+			revision:  2,
+			started:   now.Add(-time.Hour),
+			available: 1,
+			wantStep:  int32Ptr(0),
+			wantAfter: true,
+		},
+		{
+			name:     "pause elapsed without available replicas",
+			steps:    []singularityv1.FleetCanaryStep{{Weight: 10, Pause: &metav1.Duration{Duration: time.Minute}}},
+			revision: 2,
+			started:  now.Add(-2 * time.Minute),
+			wantStep: int32Ptr(0),
+		},
+		{
+			name:           "pause ongoing",
+			steps:          []singularityv1.FleetCanaryStep{{Weight: 10, Pause: &metav1.Duration{Duration: time.Hour}}},
+			revision:       2,
+			started:        now.Add(-2 * time.Hour),
+			available:      1,
+			availableSince: now.Add(-time.Minute),
+			wantStep:       int32Ptr(0),
+			wantAfter:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := metav1.NewTime(tt.started)
+			fleet := &singularityv1.Fleet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "lobby"},
+				Spec: singularityv1.FleetSpec{
+					Replicas:    10,
+					Paused:      tt.paused,
+					CanarySteps: tt.steps,
+				},
+				Status: singularityv1.FleetStatus{
+					Revision:             2,
+					CurrentStep:          &tt.step,
+					CurrentStepStartTime: &started,
+				},
+			}
+			if !tt.availableSince.IsZero() {
+				availableSince := metav1.NewTime(tt.availableSince)
+				fleet.Status.CurrentStepAvailableTime = &availableSince
+			}
+			if tt.promoted {
+				fleet.ObjectMeta.Annotations = map[string]string{singularityv1.FleetPromoteAnnotation: ""}
+			}
+			active := gameServerSet("lobby-2", tt.revision, 1)
+			active.Status.ReadyReplicas = tt.available
+			rest := []*singularityv1.GameServerSet{gameServerSet("lobby-1", 1, 9)}
+
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(fleet.DeepCopy()).Build()
+			r := &Reconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+			status := &singularityv1.FleetStatus{Revision: tt.revision}
+
+			after, err := r.handleCanary(context.Background(), fleet, active, rest, status)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if (status.CurrentStep == nil) != (tt.wantStep == nil) ||
+				(status.CurrentStep != nil && *status.CurrentStep != *tt.wantStep) {
+				t.Errorf("step = %v, want %v", status.CurrentStep, tt.wantStep)
+			}
+			if available := status.CurrentStepAvailableTime != nil; status.CurrentStep != nil && *status.CurrentStep == 0 && available != (tt.available > 0) {
+				t.Errorf("step available since = %v, want it to be set %v", status.CurrentStepAvailableTime, tt.available > 0)
+			}
+			if (after > 0) != tt.wantAfter {
+				t.Errorf("requeue after = %s, want requeue %v", after, tt.wantAfter)
+			}
+
+			got := &singularityv1.Fleet{}
+			if err = c.Get(context.Background(), client.ObjectKeyFromObject(fleet), got); err != nil {
+				t.Fatal(err)
+			}
+			_, promoted := got.ObjectMeta.Annotations[singularityv1.FleetPromoteAnnotation]
+			if wantPromoted := tt.promoted && tt.paused; promoted != wantPromoted {
+				t.Errorf("promote annotation present = %v, want %v", promoted, wantPromoted)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package fleet
+
+import (
+	"context"
+	"fmt"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"time"
+)
+
+// Reasons of the Fleet conditions, modeled after the Deployment conditions.
+// https://github.com/kubernetes/kubernetes/blob/3aafe756986232ee9208681ee22b38f5c19424a2/pkg/controller/deployment/util/deployment_util.go#L93
+const (
+	reasonGameServerSetUpdated       = "GameServerSetUpdated"
+	reasonNewGameServerSetAvailable  = "NewGameServerSetAvailable"
+	reasonProgressDeadlineExceeded   = "ProgressDeadlineExceeded"
+	reasonFleetPaused                = "FleetPaused"
+	reasonCanaryStepHeld             = "CanaryStepHeld"
+	reasonMinimumReplicasAvailable   = "MinimumReplicasAvailable"
+	reasonMinimumReplicasUnavailable = "MinimumReplicasUnavailable"
+	reasonFailedUpsert               = "FailedUpsert"
+)
+
+// updateConditions computes the conditions of the Fleet based on the aggregated status.
+// Returns the duration after which the progress deadline is exceeded, if the rollout doesn't progress.
+func (r *Reconciler) updateConditions(fleet *singularityv1.Fleet, active *singularityv1.GameServerSet, list []*singularityv1.GameServerSet, status *singularityv1.FleetStatus) time.Duration {
+	now := metav1.Now()
+	for _, condition := range fleet.Status.Conditions {
+		status.Conditions = append(status.Conditions, *condition.DeepCopy())
+	}
+
+	// The GameServerSets were upserted successfully, otherwise we wouldn't be here.
+	meta.RemoveStatusCondition(&status.Conditions, singularityv1.FleetConditionReplicaFailure)
+
+	// Reserved GameServers are about to be allocated, like Allocated ones they count as available.
+	available := status.ReadyReplicas + status.AllocatedReplicas + status.ReservedReplicas
+	if available >= fleet.Spec.Replicas-maxUnavailable(fleet) {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    singularityv1.FleetConditionAvailable,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonMinimumReplicasAvailable,
+			Message: "Fleet has minimum availability.",
+		})
+	} else {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    singularityv1.FleetConditionAvailable,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonMinimumReplicasUnavailable,
+			Message: "Fleet does not have minimum availability.",
+		})
+	}
+
+	if fleet.Spec.Paused {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    singularityv1.FleetConditionProgressing,
+			Status:  metav1.ConditionUnknown,
+			Reason:  reasonFleetPaused,
+			Message: "Fleet is paused.",
+		})
+		return 0
+	}
+
+	// active is nil only when paused, which is handled above.
+	if isRolloutComplete(fleet, active, list, status) {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    singularityv1.FleetConditionProgressing,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonNewGameServerSetAvailable,
+			Message: fmt.Sprintf("GameServerSet %s has successfully progressed.", active.ObjectMeta.Name),
+		})
+		return 0
+	}
+
+	// Holding a canary step is not a lack of progress.
+	if canaryReplicas, canary := fleet.CanaryReplicas(status.CurrentStep); canary &&
+		active.Status.ReadyReplicas+active.Status.AllocatedReplicas+active.Status.ReservedReplicas >= canaryReplicas {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    singularityv1.FleetConditionProgressing,
+			Status:  metav1.ConditionUnknown,
+			Reason:  reasonCanaryStepHeld,
+			Message: fmt.Sprintf("Rollout is held at canary step %d.", *status.CurrentStep),
+		})
+		return 0
+	}
+
+	current := meta.FindStatusCondition(status.Conditions, singularityv1.FleetConditionProgressing)
+	progressed := fleet.Status.Revision != status.Revision ||
+		fleet.Status.Replicas != status.Replicas ||
+		fleet.Status.ReadyReplicas != status.ReadyReplicas ||
+		fleet.Status.UpdatedReplicas != status.UpdatedReplicas
+	started := current == nil || (current.Reason != reasonGameServerSetUpdated && current.Reason != reasonProgressDeadlineExceeded)
+
+	if started || progressed {
+		// The last transition time of the condition tracks the last time the rollout made progress.
+		meta.RemoveStatusCondition(&status.Conditions, singularityv1.FleetConditionProgressing)
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    singularityv1.FleetConditionProgressing,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonGameServerSetUpdated,
+			Message: fmt.Sprintf("GameServerSet %s is progressing.", active.ObjectMeta.Name),
+		})
+		return fleet.GetProgressDeadline()
+	}
+
+	if current.Reason == reasonProgressDeadlineExceeded {
+		return 0
+	}
+
+	remaining := current.LastTransitionTime.Add(fleet.GetProgressDeadline()).Sub(now.Time)
+	if remaining > 0 {
+		return remaining
+	}
+
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:    singularityv1.FleetConditionProgressing,
+		Status:  metav1.ConditionFalse,
+		Reason:  reasonProgressDeadlineExceeded,
+		Message: fmt.Sprintf("GameServerSet %s has timed out progressing.", active.ObjectMeta.Name),
+	})
+	r.Recorder.Eventf(fleet, v1.EventTypeWarning, reasonProgressDeadlineExceeded,
+		"Rollout of GameServerSet %s has not progressed for %s", active.ObjectMeta.Name, fleet.GetProgressDeadline())
+
+	return 0
+}
+
+// reportReplicaFailure records a failure to create or update a GameServerSet in the Fleet's status
+func (r *Reconciler) reportReplicaFailure(ctx context.Context, fleet *singularityv1.Fleet, err error) {
+	fleetCopy := fleet.DeepCopy()
+	meta.SetStatusCondition(&fleetCopy.Status.Conditions, metav1.Condition{
+		Type:    singularityv1.FleetConditionReplicaFailure,
+		Status:  metav1.ConditionTrue,
+		Reason:  reasonFailedUpsert,
+		Message: err.Error(),
+	})
+
+	if err := r.Status().Update(ctx, fleetCopy); err != nil {
+		log.FromContext(ctx).Error(err, "reconcile: unable to report replica failure", "fleet", fleet.ObjectMeta.Name)
+	}
+}
+
+// isRolloutComplete returns whether all GameServers run the current template and are available.
+// Allocated and Reserved GameServers of old GameServerSets are left alone by the rollout, so they don't block its completion.
+func isRolloutComplete(fleet *singularityv1.Fleet, active *singularityv1.GameServerSet, list []*singularityv1.GameServerSet, status *singularityv1.FleetStatus) bool {
+	if active == nil || active.UID == "" {
+		return false
+	}
+
+	var oldAllocated int32
+	for _, gsSet := range list {
+		if gsSet.UID != active.UID {
+			oldAllocated += gsSet.Status.AllocatedReplicas + gsSet.Status.ReservedReplicas
+		}
+	}
+
+	updatedAvailable := active.Status.ReadyReplicas + active.Status.AllocatedReplicas + active.Status.ReservedReplicas
+	return status.Replicas == fleet.Spec.Replicas &&
+		status.UpdatedReplicas+oldAllocated == status.Replicas &&
+		updatedAvailable == status.UpdatedReplicas
+}
+
+// maxUnavailable returns the amount of GameServers which may be unavailable during a rolling update
+func maxUnavailable(fleet *singularityv1.Fleet) int32 {
+	if fleet.Spec.Strategy.Type != appsv1.RollingUpdateDeploymentStrategyType || fleet.Spec.Strategy.RollingUpdate == nil {
+		return 0
+	}
+
+	unavailable, err := intstr.GetScaledValueFromIntOrPercent(fleet.Spec.Strategy.RollingUpdate.MaxUnavailable, int(fleet.Spec.Replicas), false)
+	if err != nil {
+		return 0
+	}
+
+	return int32(unavailable)
+}
@@ -0,0 +1,201 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package fleet
+
+import (
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"testing"
+	"time"
+)
+
+func TestUpdateConditions(t *testing.T) {
+	now := time.Now()
+	progressing := func(reason string, since time.Time) []metav1.Condition {
+		return []metav1.Condition{{
+			Type:               singularityv1.FleetConditionProgressing,
+			Status:             metav1.ConditionTrue,
+			Reason:             reason,
+			LastTransitionTime: metav1.NewTime(since),
+		}}
+	}
+
+	tests := []struct {
+		name          string
+		paused        bool
+		conditions    []metav1.Condition
+		oldReplicas   int32
+		oldReserved   int32
+		ready         int32
+		reserved      int32
+		step          *int32
+		progressed    bool
+		wantStatus    metav1.ConditionStatus
+		wantReason    string
+		wantAvailable metav1.ConditionStatus
+		wantAfter     time.Duration
+		wantEvent     string
+	}{
+		{
+			name:          "paused",
+			paused:        true,
+			oldReplicas:   4,
+			wantStatus:    metav1.ConditionUnknown,
+			wantReason:    reasonFleetPaused,
+			wantAvailable: metav1.ConditionFalse,
+		},
+		{
+			name:          "rollout complete",
+			ready:         4,
+			wantStatus:    metav1.ConditionTrue,
+			wantReason:    reasonNewGameServerSetAvailable,
+			wantAvailable: metav1.ConditionTrue,
+		},
+		{
+			name:          "rollout complete with reserved",
+			ready:         3,
+			reserved:      1,
+			wantStatus:    metav1.ConditionTrue,
+			wantReason:    reasonNewGameServerSetAvailable,
+			wantAvailable: metav1.ConditionTrue,
+		},
+		{
+			// Reserved GameServers of old GameServerSets are left alone, like Allocated ones
+			name:          "rollout complete with old reserved",
+			oldReplicas:   1,
+			oldReserved:   1,
+			ready:         3,
+			wantStatus:    metav1.ConditionTrue,
+			wantReason:    reasonNewGameServerSetAvailable,
+			wantAvailable: metav1.ConditionTrue,
+		},
+		{
+			name:          "rollout started",
+			oldReplicas:   4,
+			wantStatus:    metav1.ConditionTrue,
+			wantReason:    reasonGameServerSetUpdated,
+			wantAvailable: metav1.ConditionFalse,
+			wantAfter:     time.Minute,
+		},
+		{
+			name:          "rollout progressed",
+			conditions:    progressing(reasonGameServerSetUpdated, now.Add(-2*time.Minute)),
+			oldReplicas:   4,
+			progressed:    true,
+			wantStatus:    metav1.ConditionTrue,
+			wantReason:    reasonGameServerSetUpdated,
+			wantAvailable: metav1.ConditionFalse,
+			wantAfter:     time.Minute,
+		},
+		{
+			name:          "within progress deadline",
+			conditions:    progressing(reasonGameServerSetUpdated, now.Add(-30*time.Second)),
+			oldReplicas:   4,
+			wantStatus:    metav1.ConditionTrue,
+			wantReason:    reasonGameServerSetUpdated,
+			wantAvailable: metav1.ConditionFalse,
+			wantAfter:     30 * time.Second,
+		},
+		{
+			name:          "progress deadline exceeded",
+			conditions:    progressing(reasonGameServerSetUpdated, now.Add(-2*time.Minute)),
+			oldReplicas:   4,
+			wantStatus:    metav1.ConditionFalse,
+			wantReason:    reasonProgressDeadlineExceeded,
+			wantAvailable: metav1.ConditionFalse,
+			wantEvent:     reasonProgressDeadlineExceeded,
+		},
+		{
+			name:          "canary step held",
+			conditions:    progressing(reasonGameServerSetUpdated, now.Add(-2*time.Minute)),
+			oldReplicas:   3,
+			ready:         1,
+			step:          int32Ptr(0),
+			wantStatus:    metav1.ConditionUnknown,
+			wantReason:    reasonCanaryStepHeld,
+			wantAvailable: metav1.ConditionFalse,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fleet := &singularityv1.Fleet{
+				Spec: singularityv1.FleetSpec{
+					Replicas:                4,
+					Paused:                  tt.paused,
+					CanarySteps:             []singularityv1.FleetCanaryStep{{Weight: 25}},
+					ProgressDeadlineSeconds: int32Ptr(60),
+				},
+				Status: singularityv1.FleetStatus{Revision: 2, Conditions: tt.conditions},
+			}
+
+			active := gameServerSet("lobby-2", 2, 4-tt.oldReplicas)
+			active.UID = types.UID("lobby-2")
+			active.Status.ReadyReplicas = tt.ready
+			active.Status.ReservedReplicas = tt.reserved
+			old := gameServerSet("lobby-1", 1, tt.oldReplicas)
+			old.UID = types.UID("lobby-1")
+			old.Status.ReservedReplicas = tt.oldReserved
+			list := []*singularityv1.GameServerSet{old, active}
+			if tt.paused {
+				active = nil
+			}
+
+			status := &singularityv1.FleetStatus{
+				Revision:         2,
+				Replicas:         4,
+				ReadyReplicas:    tt.ready,
+				ReservedReplicas: tt.reserved + tt.oldReserved,
+				UpdatedReplicas:  4 - tt.oldReplicas,
+				CurrentStep:      tt.step,
+			}
+			if !tt.progressed {
+				fleet.Status.Replicas = status.Replicas
+				fleet.Status.ReadyReplicas = status.ReadyReplicas
+				fleet.Status.UpdatedReplicas = status.UpdatedReplicas
+			}
+
+			recorder := record.NewFakeRecorder(10)
+			r := &Reconciler{Recorder: recorder}
+			after := r.updateConditions(fleet, active, list, status)
+
+			condition := meta.FindStatusCondition(status.Conditions, singularityv1.FleetConditionProgressing)
+			if condition == nil || condition.Status != tt.wantStatus || condition.Reason != tt.wantReason {
+				t.Errorf("progressing = %+v, want %s %s", condition, tt.wantStatus, tt.wantReason)
+			}
+			available := meta.FindStatusCondition(status.Conditions, singularityv1.FleetConditionAvailable)
+			if available == nil || available.Status != tt.wantAvailable {
+				t.Errorf("available = %+v, want %s", available, tt.wantAvailable)
+			}
+			// Allow for the time passed since the conditions were built.
+			if after > tt.wantAfter || after < tt.wantAfter-time.Second {
+				t.Errorf("requeue after = %s, want %s", after, tt.wantAfter)
+			}
+
+			if tt.wantEvent != "" {
+				expectEvent(t, recorder, tt.wantEvent)
+			} else if len(recorder.Events) > 0 {
+				t.Errorf("unexpected event %q", <-recorder.Events)
+			}
+		})
+	}
+}
@@ -75,9 +75,32 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		}, err
 	}
 
+	// Restore a previous template first, the next cycle will roll it out.
+	if fleet.Spec.RollbackTo != nil {
+		if err = r.rollback(ctx, fleet, list); err != nil {
+			l.Error(err, "reconcile: rollback failed", "fleet", req.Name)
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{}, nil
+	}
+
 	// Find the active GameServerSet and return the rest
 	active, rest := r.filterActiveGameServerSet(fleet, list)
 	if active == nil {
+		if fleet.Spec.Paused {
+			// Don't start a new rollout while paused, only keep the status up to date.
+			l.Info("reconcile: paused, not creating GameServerSet", "fleet", req.Name)
+			status := singularityv1.FleetStatus{
+				Revision:                 fleet.Status.Revision,
+				CurrentStep:              fleet.Status.CurrentStep,
+				CurrentStepStartTime:     fleet.Status.CurrentStepStartTime,
+				CurrentStepAvailableTime: fleet.Status.CurrentStepAvailableTime,
+			}
+			requeueAfter, err := r.updateStatus(ctx, fleet, nil, list, status)
+			return ctrl.Result{RequeueAfter: requeueAfter}, err
+		}
+
 		l.Info("reconcile: creating GameServerSet", "fleet", req.Name)
 
 		// If there isn't an active GameServerSet, create one.
@@ -85,8 +108,24 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		active = fleet.GameServerSet()
 	}
 
+	// The active GameServerSet always has the newest revision
+	revision := active.Revision()
+	if maxRevision := singularityv1.MaxRevision(rest); revision <= maxRevision {
+		revision = maxRevision + 1
+	}
+	status := singularityv1.FleetStatus{
+		Revision: revision,
+	}
+
+	// Advance the canary steps of an ongoing rollout
+	requeueAfter, err := r.handleCanary(ctx, fleet, active, rest, &status)
+	if err != nil {
+		l.Error(err, "reconcile: canary cycle failed", "fleet", req.Name)
+		return ctrl.Result{}, err
+	}
+
 	// Run the deployment cycle
-	replicas, err := r.handleDeployment(ctx, fleet, active, rest)
+	replicas, err := r.handleDeployment(ctx, fleet, active, rest, status.CurrentStep)
 	if err != nil {
 		l.Error(err, "reconcile: deployment cycle failed", "fleet", req.Name)
 		return ctrl.Result{}, err
@@ -96,15 +135,24 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, err
 	}
 
-	if err = r.upsertGameServerSet(ctx, fleet, active, replicas); err != nil {
+	if err = r.upsertGameServerSet(ctx, fleet, active, replicas, revision); err != nil {
+		r.reportReplicaFailure(ctx, fleet, err)
 		return ctrl.Result{}, err
 	}
 
-	if err = r.updateStatus(ctx, fleet, list); err != nil {
+	progressRequeueAfter, err := r.updateStatus(ctx, fleet, active, list, status)
+	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	// Re-evaluate whichever comes first, the current canary step or the progress deadline
+	if requeueAfter == 0 || (progressRequeueAfter > 0 && progressRequeueAfter < requeueAfter) {
+		requeueAfter = progressRequeueAfter
+	}
+
+	return ctrl.Result{
+		RequeueAfter: requeueAfter,
+	}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -123,16 +171,24 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 // handleDeployment performs the deployment strategy
 // https://github.com/googleforgames/agones/blob/8d01f2ce9c34ffadfdf22ab2fb3b1bafae7e6389/pkg/fleets/controller.go#L356
-func (r *Reconciler) handleDeployment(ctx context.Context, fleet *singularityv1.Fleet, active *singularityv1.GameServerSet, rest []*singularityv1.GameServerSet) (int32, error) {
+func (r *Reconciler) handleDeployment(ctx context.Context, fleet *singularityv1.Fleet, active *singularityv1.GameServerSet, rest []*singularityv1.GameServerSet, step *int32) (int32, error) {
+	// Empty GameServerSets are only retained as revision history, they don't take part in the deployment.
+	rest = nonEmptyGameServerSets(rest)
+
 	if len(rest) == 0 {
 		// There is only one GameServerSet which matches the desired state.
 		// Further action is not required.
 		return fleet.Spec.Replicas, nil
 	}
 
+	if fleet.Spec.Paused {
+		// Hold the rollout where it is, neither the active nor the old GameServerSets are scaled.
+		return active.Spec.Replicas, nil
+	}
+
 	switch fleet.Spec.Strategy.Type {
 	case appsv1.RollingUpdateDeploymentStrategyType:
-		return r.handleRollingUpdateDeployment(ctx, fleet, active, rest)
+		return r.handleRollingUpdateDeployment(ctx, fleet, active, rest, step)
 	}
 
 	return 0, errors.Errorf("unexpected deployment strategy type: %s", fleet.Spec.Strategy.Type)
@@ -142,9 +198,12 @@ func (r *Reconciler) filterActiveGameServerSet(fleet *singularityv1.Fleet, list
 	var active *singularityv1.GameServerSet
 	var rest []*singularityv1.GameServerSet
 
+	hash := fleet.Spec.Template.Hash()
 	for _, gsSet := range list {
-		// If the actual state is equal to the desired state
-		if equality.Semantic.DeepEqual(gsSet.Spec.Template, fleet.Spec.Template) {
+		// If the actual state is equal to the desired state.
+		// GameServerSets created before the template hash label existed are compared directly.
+		value, ok := gsSet.ObjectMeta.Labels[singularityv1.TemplateHashLabel]
+		if (ok && value == hash) || (!ok && equality.Semantic.DeepEqual(gsSet.Spec.Template, fleet.Spec.Template)) {
 			active = gsSet
 		} else {
 			rest = append(rest, gsSet)
@@ -154,27 +213,56 @@ func (r *Reconciler) filterActiveGameServerSet(fleet *singularityv1.Fleet, list
 	return active, rest
 }
 
-// deleteEmptyGameServerSets deletes all GameServerSets with 0 replicas
+// deleteEmptyGameServerSets deletes GameServerSets with 0 replicas,
+// retaining the newest revisions up to the Fleet's revision history limit
 func (r *Reconciler) deleteEmptyGameServerSets(ctx context.Context, fleet *singularityv1.Fleet, list []*singularityv1.GameServerSet) error {
-	policy := client.PropagationPolicy(metav1.DeletePropagationBackground)
+	var empty []*singularityv1.GameServerSet
 	for _, gsSet := range list {
-		if gsSet.Status.Replicas == 0 && gsSet.Status.ShutdownReplicas == 0 {
-			if err := r.Delete(ctx, gsSet, policy); err != nil {
-				return errors.Wrapf(err, "error deleting gameserverset %s", gsSet.ObjectMeta.Name)
-			}
+		if isEmptyGameServerSet(gsSet) {
+			empty = append(empty, gsSet)
+		}
+	}
+
+	limit := int(fleet.GetRevisionHistoryLimit())
+	if len(empty) <= limit {
+		return nil
+	}
 
-			r.Recorder.Eventf(fleet, v1.EventTypeNormal, "DeletingGameServerSet", "Deleting inactive GameServerSet %s", gsSet.ObjectMeta.Name)
+	policy := client.PropagationPolicy(metav1.DeletePropagationBackground)
+	for _, gsSet := range singularityv1.SortByRevisionDescending(empty)[limit:] {
+		if err := r.Delete(ctx, gsSet, policy); err != nil {
+			return errors.Wrapf(err, "error deleting gameserverset %s", gsSet.ObjectMeta.Name)
 		}
+
+		r.Recorder.Eventf(fleet, v1.EventTypeNormal, "DeletingGameServerSet", "Deleting inactive GameServerSet %s", gsSet.ObjectMeta.Name)
 	}
 
 	return nil
 }
 
+// isEmptyGameServerSet returns whether the GameServerSet neither has nor wants any GameServers
+func isEmptyGameServerSet(gsSet *singularityv1.GameServerSet) bool {
+	return gsSet.Spec.Replicas == 0 && gsSet.Status.Replicas == 0 && gsSet.Status.ShutdownReplicas == 0
+}
+
+// nonEmptyGameServerSets filters out GameServerSets which are only retained as revision history
+func nonEmptyGameServerSets(list []*singularityv1.GameServerSet) []*singularityv1.GameServerSet {
+	var result []*singularityv1.GameServerSet
+	for _, gsSet := range list {
+		if !isEmptyGameServerSet(gsSet) {
+			result = append(result, gsSet)
+		}
+	}
+
+	return result
+}
+
 // upsertGameServerSet inserts the new GameServerSet (if required)
-// and updates the active GameServerSet to match the desired state
-func (r *Reconciler) upsertGameServerSet(ctx context.Context, fleet *singularityv1.Fleet, active *singularityv1.GameServerSet, replicas int32) error {
+// and updates the active GameServerSet to match the desired state and revision
+func (r *Reconciler) upsertGameServerSet(ctx context.Context, fleet *singularityv1.Fleet, active *singularityv1.GameServerSet, replicas int32, revision int64) error {
 	if active.UID == "" {
 		active.Spec.Replicas = replicas
+		active.SetRevision(revision)
 		if err := r.Create(ctx, active); err != nil {
 			return errors.Wrapf(err, "error creating gameserverset %s", active.ObjectMeta.Name)
 		}
@@ -183,6 +271,7 @@ func (r *Reconciler) upsertGameServerSet(ctx context.Context, fleet *singularity
 		gsSetCopy.Status.Replicas = 0
 		gsSetCopy.Status.ReadyReplicas = 0
 		gsSetCopy.Status.AllocatedReplicas = 0
+		gsSetCopy.Status.ReservedReplicas = 0
 		gsSetCopy.Status.ShutdownReplicas = 0
 		gsSetCopy.Status.Instances = 0
 		gsSetCopy.Status.ReadyInstances = 0
@@ -196,39 +285,66 @@ func (r *Reconciler) upsertGameServerSet(ctx context.Context, fleet *singularity
 		return nil
 	}
 
-	if replicas != active.Spec.Replicas || active.Spec.Scheduling != fleet.Spec.Scheduling {
+	// An older GameServerSet became active again, e.g. due to a rollback
+	revised := active.Revision() != revision
+	// GameServerSets created before the template hash label existed
+	_, labeled := active.ObjectMeta.Labels[singularityv1.TemplateHashLabel]
+
+	if replicas != active.Spec.Replicas || active.Spec.Scheduling != fleet.Spec.Scheduling || revised || !labeled {
 		gsSetCopy := active.DeepCopy()
 		gsSetCopy.Spec.Replicas = replicas
 		gsSetCopy.Spec.Scheduling = fleet.Spec.Scheduling
+		if revised {
+			gsSetCopy.SetRevision(revision)
+		}
+		if !labeled {
+			if gsSetCopy.ObjectMeta.Labels == nil {
+				gsSetCopy.ObjectMeta.Labels = make(map[string]string, 1)
+			}
+			gsSetCopy.ObjectMeta.Labels[singularityv1.TemplateHashLabel] = fleet.Spec.Template.Hash()
+		}
 		if err := r.Update(ctx, gsSetCopy); err != nil {
 			return errors.Wrapf(err, "error updating replicas for gameserverset %s", active.ObjectMeta.Name)
 		}
-		r.Recorder.Eventf(fleet, v1.EventTypeNormal, "ScalingGameServerSet",
-			"Scaling active GameServerSet %s from %d to %d", active.ObjectMeta.Name, active.Spec.Replicas, gsSetCopy.Spec.Replicas)
+		if replicas != active.Spec.Replicas {
+			r.Recorder.Eventf(fleet, v1.EventTypeNormal, "ScalingGameServerSet",
+				"Scaling active GameServerSet %s from %d to %d", active.ObjectMeta.Name, active.Spec.Replicas, gsSetCopy.Spec.Replicas)
+		}
 	}
 
 	return nil
 }
 
-func (r *Reconciler) updateStatus(ctx context.Context, fleet *singularityv1.Fleet, list []*singularityv1.GameServerSet) error {
-	var status singularityv1.FleetStatus
+// updateStatus aggregates the status of all GameServerSets on top of the given rollout status.
+// The active GameServerSet is nil if the Fleet's current template hasn't been rolled out yet.
+// Returns the duration after which the progress deadline should be checked again.
+func (r *Reconciler) updateStatus(ctx context.Context, fleet *singularityv1.Fleet, active *singularityv1.GameServerSet, list []*singularityv1.GameServerSet, status singularityv1.FleetStatus) (time.Duration, error) {
+	status.ObservedGeneration = fleet.Generation
+	status.LabelSelector = fleet.LabelSelector()
 
 	for _, gsSet := range list {
 		status.Replicas += gsSet.Status.Replicas
 		status.ReadyReplicas += gsSet.Status.ReadyReplicas
 		status.AllocatedReplicas += gsSet.Status.AllocatedReplicas
+		status.ReservedReplicas += gsSet.Status.ReservedReplicas
 		status.Instances += gsSet.Status.Instances
 		status.ReadyInstances += gsSet.Status.ReadyInstances
 		status.AllocatedInstances += gsSet.Status.AllocatedInstances
 	}
 
+	if active != nil {
+		status.UpdatedReplicas = active.Status.Replicas
+	}
+
+	requeueAfter := r.updateConditions(fleet, active, list, &status)
+
 	// TODO: Aggregate player status
-	if fleet.Status != status {
+	if !equality.Semantic.DeepEqual(fleet.Status, status) {
 		fleet.Status = status
 		if err := r.Status().Update(ctx, fleet); err != nil {
-			return errors.Wrapf(err, "error updating status")
+			return 0, errors.Wrapf(err, "error updating status")
 		}
 	}
 
-	return nil
+	return requeueAfter, nil
 }
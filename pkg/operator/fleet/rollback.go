@@ -0,0 +1,85 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package fleet
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// rollback replaces the Fleet's template with the template of the requested revision.
+// https://github.com/kubernetes/kubernetes/blob/3aafe756986232ee9208681ee22b38f5c19424a2/pkg/controller/deployment/rollback.go#L33
+func (r *Reconciler) rollback(ctx context.Context, fleet *singularityv1.Fleet, list []*singularityv1.GameServerSet) error {
+	revision := fleet.Spec.RollbackTo.Revision
+	if revision == 0 {
+		revision = previousRevision(list)
+	}
+
+	fleetCopy := fleet.DeepCopy()
+	fleetCopy.Spec.RollbackTo = nil
+
+	target := findRevision(list, revision)
+	if target == nil {
+		// The revision is unknown or has already been cleaned up, give up on the rollback.
+		r.Recorder.Eventf(fleet, v1.EventTypeWarning, "RollbackRevisionNotFound", "Unable to find revision %d", revision)
+	} else {
+		fleetCopy.Spec.Template = *target.Spec.Template.DeepCopy()
+		r.Recorder.Eventf(fleet, v1.EventTypeNormal, "RollbackDone", "Rolled back to revision %d (GameServerSet %s)", revision, target.ObjectMeta.Name)
+	}
+
+	// Updating the spec triggers another reconciliation, which rolls out the restored template.
+	if err := r.Update(ctx, fleetCopy); err != nil {
+		return errors.Wrapf(err, "error rolling back fleet %s to revision %d", fleet.ObjectMeta.Name, revision)
+	}
+
+	return nil
+}
+
+// previousRevision returns the second-highest revision in a list of GameServerSet, or 0 if there is none
+func previousRevision(list []*singularityv1.GameServerSet) int64 {
+	max, previous := int64(0), int64(0)
+	for _, gsSet := range list {
+		revision := gsSet.Revision()
+		if revision > max {
+			previous = max
+			max = revision
+		} else if revision > previous && revision < max {
+			previous = revision
+		}
+	}
+
+	return previous
+}
+
+// findRevision returns the GameServerSet with the given revision, or nil if it doesn't exist
+func findRevision(list []*singularityv1.GameServerSet, revision int64) *singularityv1.GameServerSet {
+	if revision <= 0 {
+		return nil
+	}
+
+	for _, gsSet := range list {
+		if gsSet.Revision() == revision {
+			return gsSet
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package fleet
+
+import (
+	"context"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"strings"
+	"testing"
+)
+
+// gameServerSet returns a GameServerSet of the "lobby" Fleet with the given revision and replicas
+func gameServerSet(name string, revision int64, replicas int32) *singularityv1.GameServerSet {
+	gsSet := &singularityv1.GameServerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      name,
+			Labels:    map[string]string{singularityv1.FleetNameLabel: "lobby"},
+		},
+		Spec: singularityv1.GameServerSetSpec{Replicas: replicas},
+	}
+	gsSet.Spec.Template.Spec.Template.Spec.Containers = []v1.Container{{Name: "server", Image: name}}
+	gsSet.Status.Replicas = replicas
+	gsSet.SetRevision(revision)
+	return gsSet
+}
+
+func TestPreviousRevision(t *testing.T) {
+	tests := []struct {
+		name      string
+		revisions []int64
+		want      int64
+	}{
+		{name: "no revisions", want: 0},
+		{name: "single revision", revisions: []int64{1}, want: 0},
+		{name: "ascending", revisions: []int64{1, 2, 3}, want: 2},
+		{name: "descending", revisions: []int64{3, 2, 1}, want: 2},
+		{name: "gaps", revisions: []int64{7, 2, 4}, want: 4},
+		{name: "unrevisioned", revisions: []int64{0, 5}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var list []*singularityv1.GameServerSet
+			for _, revision := range tt.revisions {
+				list = append(list, gameServerSet("lobby", revision, 0))
+			}
+
+			if got := previousRevision(list); got != tt.want {
+				t.Errorf("previousRevision() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRollback(t *testing.T) {
+	tests := []struct {
+		name      string
+		revision  int64
+		wantImage string
+		wantEvent string
+	}{
+		{name: "previous revision", revision: 0, wantImage: "lobby-2", wantEvent: "RollbackDone"},
+		{name: "explicit revision", revision: 1, wantImage: "lobby-1", wantEvent: "RollbackDone"},
+		{name: "pruned revision", revision: 4, wantImage: "lobby-3", wantEvent: "RollbackRevisionNotFound"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := []*singularityv1.GameServerSet{
+				gameServerSet("lobby-1", 1, 0),
+				gameServerSet("lobby-2", 2, 0),
+				gameServerSet("lobby-3", 3, 2),
+			}
+			fleet := &singularityv1.Fleet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "lobby"},
+				Spec: singularityv1.FleetSpec{
+					Replicas:   2,
+					Template:   list[2].Spec.Template,
+					RollbackTo: &singularityv1.FleetRollbackConfig{Revision: tt.revision},
+				},
+			}
+
+			recorder := record.NewFakeRecorder(10)
+			r := &Reconciler{
+				Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(fleet).Build(),
+				Recorder: recorder,
+			}
+			if err := r.rollback(context.Background(), fleet, list); err != nil {
+				t.Fatal(err)
+			}
+
+			got := &singularityv1.Fleet{}
+			if err := r.Get(context.Background(), client.ObjectKeyFromObject(fleet), got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Spec.RollbackTo != nil {
+				t.Errorf("rollbackTo = %v, want nil", got.Spec.RollbackTo)
+			}
+			if image := got.Spec.Template.Spec.Template.Spec.Containers[0].Image; image != tt.wantImage {
+				t.Errorf("image = %s, want %s", image, tt.wantImage)
+			}
+			expectEvent(t, recorder, tt.wantEvent)
+		})
+	}
+}
+
+func TestDeleteEmptyGameServerSets(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit *int32
+		want  []string
+	}{
+		{name: "default limit", want: []string{"lobby-1", "lobby-2", "lobby-3", "lobby-4"}},
+		{name: "limit of one", limit: int32Ptr(1), want: []string{"lobby-3", "lobby-4"}},
+		{name: "no history", limit: int32Ptr(0), want: []string{"lobby-4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := []*singularityv1.GameServerSet{
+				gameServerSet("lobby-2", 2, 0),
+				gameServerSet("lobby-1", 1, 0),
+				gameServerSet("lobby-3", 3, 0),
+				gameServerSet("lobby-4", 4, 2),
+			}
+			var objects []client.Object
+			for _, gsSet := range list {
+				objects = append(objects, gsSet)
+			}
+			fleet := &singularityv1.Fleet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "lobby"},
+				Spec:       singularityv1.FleetSpec{RevisionHistoryLimit: tt.limit},
+			}
+
+			r := &Reconciler{
+				Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+			if err := r.deleteEmptyGameServerSets(context.Background(), fleet, list); err != nil {
+				t.Fatal(err)
+			}
+
+			remaining := &singularityv1.GameServerSetList{}
+			if err := r.List(context.Background(), remaining); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, gsSet := range remaining.Items {
+				got = append(got, gsSet.ObjectMeta.Name)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("remaining = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("remaining = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+// expectEvent fails the test if the next recorded event doesn't have the given reason
+func expectEvent(t *testing.T, recorder *record.FakeRecorder, reason string) {
+	t.Helper()
+	select {
+	case event := <-recorder.Events:
+		if !containsReason(event, reason) {
+			t.Errorf("event = %q, want reason %s", event, reason)
+		}
+	default:
+		t.Errorf("no event recorded, want reason %s", reason)
+	}
+}
+
+// containsReason returns whether the FakeRecorder event string has the given reason
+func containsReason(event, reason string) bool {
+	// FakeRecorder formats events as "<type> <reason> <message>"
+	fields := strings.Fields(event)
+	return len(fields) >= 2 && fields[1] == reason
+}
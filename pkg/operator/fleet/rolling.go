@@ -26,16 +26,24 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/integer"
+	"sort"
 )
 
 // https://github.com/googleforgames/agones/blob/8d01f2ce9c34ffadfdf22ab2fb3b1bafae7e6389/pkg/fleets/controller.go#L415
-func (r *Reconciler) handleRollingUpdateDeployment(ctx context.Context, fleet *singularityv1.Fleet, active *singularityv1.GameServerSet, rest []*singularityv1.GameServerSet) (int32, error) {
+func (r *Reconciler) handleRollingUpdateDeployment(ctx context.Context, fleet *singularityv1.Fleet, active *singularityv1.GameServerSet, rest []*singularityv1.GameServerSet, step *int32) (int32, error) {
 	// First, start by rolling out update for the current active GameServerSet
 	replicas, err := r.handleRollingUpdateActive(fleet, active, rest)
 	if err != nil {
 		return 0, err
 	}
-	if err := r.handleRollingUpdateRest(ctx, fleet, active, rest); err != nil {
+
+	// Hold back the new template while a canary step is in effect
+	canaryReplicas, canary := fleet.CanaryReplicas(step)
+	if canary && replicas > canaryReplicas {
+		replicas = canaryReplicas
+	}
+
+	if err := r.handleRollingUpdateRest(ctx, fleet, active, rest, canary); err != nil {
 		return 0, err
 	}
 
@@ -46,8 +54,8 @@ func (r *Reconciler) handleRollingUpdateDeployment(ctx context.Context, fleet *s
 func (r *Reconciler) handleRollingUpdateActive(fleet *singularityv1.Fleet, active *singularityv1.GameServerSet, rest []*singularityv1.GameServerSet) (int32, error) {
 	desiredReplicas := active.Spec.Replicas
 
-	// Leave room for Allocated GameServers in old GameServerSets.
-	allocatedReplicas := singularityv1.CountStatusAllocatedReplicas(rest)
+	// Leave room for Allocated and Reserved GameServers in old GameServerSets.
+	allocatedReplicas := singularityv1.CountStatusAllocatedReplicas(rest) + singularityv1.CountStatusReservedReplicas(rest)
 
 	// If the state doesn't match the desired replicas, ignore.
 	// This means we're in the middle of a rolling update, and we should wait.
@@ -94,7 +102,7 @@ func (r *Reconciler) handleRollingUpdateActive(fleet *singularityv1.Fleet, activ
 
 // https://github.com/googleforgames/agones/blob/8d01f2ce9c34ffadfdf22ab2fb3b1bafae7e6389/pkg/fleets/controller.go#L514
 // https://github.com/kubernetes/kubernetes/blob/3aafe756986232ee9208681ee22b38f5c19424a2/pkg/controller/deployment/rolling.go#L87
-func (r *Reconciler) handleRollingUpdateRest(ctx context.Context, fleet *singularityv1.Fleet, active *singularityv1.GameServerSet, rest []*singularityv1.GameServerSet) error {
+func (r *Reconciler) handleRollingUpdateRest(ctx context.Context, fleet *singularityv1.Fleet, active *singularityv1.GameServerSet, rest []*singularityv1.GameServerSet, canary bool) error {
 	// https://github.com/kubernetes/kubernetes/blob/3ffdfbe286ebcea5d75617da6accaf67f815e0cf/staging/src/k8s.io/kubectl/pkg/util/deployment/deployment.go#L238
 	ur, err := intstr.GetScaledValueFromIntOrPercent(fleet.Spec.Strategy.RollingUpdate.MaxUnavailable, int(fleet.Spec.Replicas), false)
 	if err != nil {
@@ -115,6 +123,10 @@ func (r *Reconciler) handleRollingUpdateRest(ctx context.Context, fleet *singula
 	gsSets := rest
 	gsSets = append(gsSets, active)
 	minAvailable := fleet.Spec.Replicas - unavailable
+	if canary {
+		// During a canary step, old GameServers are only replaced by available canary GameServers.
+		minAvailable = fleet.Spec.Replicas
+	}
 
 	desiredReplicas := singularityv1.CountSpecReplicas(gsSets)
 	unavailableGSCount := active.Spec.Replicas - active.Status.ReadyReplicas - active.Status.AllocatedReplicas
@@ -131,11 +143,61 @@ func (r *Reconciler) handleRollingUpdateRest(ctx context.Context, fleet *singula
 		// And this set in sync with reconcileOldReplicaSets() Kubernetes code
 		return nil
 	}
-	// TODO: scale down
+
+	// Scale down old GameServerSets, as long as enough GameServers remain available.
+	totalScaleDown := singularityv1.CountStatusReadyReplicas(gsSets) - minAvailable
+	if totalScaleDown <= 0 {
+		return nil
+	}
+
+	if _, err = r.scaleDownOldGameServerSets(ctx, rest, fleet, totalScaleDown); err != nil {
+		return err
+	}
 
 	return nil
 }
 
+// scaleDownOldGameServerSets scales down old GameServerSets, starting with the oldest revision
+func (r *Reconciler) scaleDownOldGameServerSets(ctx context.Context, rest []*singularityv1.GameServerSet,
+	fleet *singularityv1.Fleet, maxScaleDownCount int32) (int32, error) {
+
+	oldest := make([]*singularityv1.GameServerSet, len(rest))
+	copy(oldest, rest)
+	sort.SliceStable(oldest, func(i, j int) bool {
+		return oldest[i].Revision() < oldest[j].Revision()
+	})
+
+	totalScaledDown := int32(0)
+	for _, gsSet := range oldest {
+		if totalScaledDown >= maxScaleDownCount {
+			// We have scaled down enough.
+			break
+		}
+		if gsSet.Spec.Replicas == 0 {
+			// Cannot scale down this replica set.
+			continue
+		}
+
+		scaledDownCount := int32(integer.IntMin(int(gsSet.Spec.Replicas), int(maxScaleDownCount-totalScaledDown)))
+		newReplicasCount := gsSet.Spec.Replicas - scaledDownCount
+		if newReplicasCount > gsSet.Spec.Replicas {
+			return 0, fmt.Errorf("invalid scale down request for gameserverset %s: %d -> %d", gsSet.Name, gsSet.Spec.Replicas, newReplicasCount)
+		}
+
+		gsSetCopy := gsSet.DeepCopy()
+		gsSetCopy.Spec.Replicas = newReplicasCount
+		totalScaledDown += scaledDownCount
+		if err := r.Update(ctx, gsSetCopy); err != nil {
+			return totalScaledDown, errors.Wrapf(err, "error updating gameserverset %s/%s", gsSetCopy.Namespace, gsSetCopy.ObjectMeta.Name)
+		}
+
+		r.Recorder.Eventf(fleet, v1.EventTypeNormal, "ScalingGameServerSet",
+			"Scaling inactive GameServerSet %s from %d to %d", gsSetCopy.ObjectMeta.Name, gsSet.Spec.Replicas, gsSetCopy.Spec.Replicas)
+	}
+
+	return totalScaledDown, nil
+}
+
 func (r *Reconciler) cleanupUnhealthyReplicas(ctx context.Context, rest []*singularityv1.GameServerSet,
 	fleet *singularityv1.Fleet, maxCleanupCount int32) (int32, error) {
 
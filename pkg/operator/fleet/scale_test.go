@@ -0,0 +1,143 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package fleet
+
+import (
+	"context"
+	"innit.gg/singularity/pkg/apis"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"testing"
+	"time"
+)
+
+// TestScaleSubresource scales a Fleet through its scale subresource, the way a HorizontalPodAutoscaler does
+func TestScaleSubresource(t *testing.T) {
+	requireEnvtest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{Scheme: scheme, MetricsBindAddress: "0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Reconciler{
+		Client:   mgr.GetClient(),
+		Recorder: record.NewFakeRecorder(100),
+		Log:      ctrl.Log.WithName("fleet"),
+	}
+	if err = r.SetupWithManager(mgr); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = mgr.Start(ctx)
+	}()
+
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fleet := &singularityv1.Fleet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "lobby"},
+		Spec: singularityv1.FleetSpec{
+			Replicas:   2,
+			Strategy:   appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+			Scheduling: apis.Packed,
+			Template: singularityv1.GameServerTemplate{
+				Spec: singularityv1.GameServerSpec{
+					Scheduling: apis.Packed,
+					Ports:      []singularityv1.GameServerPort{},
+					Template: v1.PodTemplateSpec{
+						Spec: v1.PodSpec{Containers: []v1.Container{{Name: "server", Image: "server"}}},
+					},
+				},
+			},
+		},
+	}
+	if err = c.Create(ctx, fleet); err != nil {
+		t.Fatal(err)
+	}
+
+	gvr := singularityv1.GroupVersion.WithResource("fleets")
+	scales := dynamic.NewForConfigOrDie(cfg).Resource(gvr).Namespace(fleet.ObjectMeta.Namespace)
+
+	var scale *unstructured.Unstructured
+	eventually(t, func() bool {
+		scale, err = scales.Get(ctx, fleet.ObjectMeta.Name, metav1.GetOptions{}, "scale")
+		if err != nil {
+			return false
+		}
+		selector, _, _ := unstructured.NestedString(scale.Object, "status", "selector")
+		return selector != ""
+	})
+
+	selector, _, _ := unstructured.NestedString(scale.Object, "status", "selector")
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		t.Fatalf("invalid selector %q: %v", selector, err)
+	}
+	if !parsed.Matches(labels.Set{singularityv1.FleetNameLabel: fleet.ObjectMeta.Name}) {
+		t.Errorf("selector %q doesn't match the fleet's servers", selector)
+	}
+	if replicas, _, _ := unstructured.NestedInt64(scale.Object, "spec", "replicas"); replicas != 2 {
+		t.Errorf("spec.replicas = %d, want 2", replicas)
+	}
+
+	if err = unstructured.SetNestedField(scale.Object, int64(5), "spec", "replicas"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = scales.Update(ctx, scale, metav1.UpdateOptions{}, "scale"); err != nil {
+		t.Fatal(err)
+	}
+
+	eventually(t, func() bool {
+		if err := c.Get(ctx, client.ObjectKeyFromObject(fleet), fleet); err != nil {
+			return false
+		}
+		return fleet.Spec.Replicas == 5
+	})
+
+	// The scaled replicas are rolled out to the GameServerSet
+	eventually(t, func() bool {
+		list, err := fleet.ListGameServerSet(ctx, c)
+		return err == nil && len(list) == 1 && list[0].Spec.Replicas == 5
+	})
+}
+
+// eventually fails the test if the condition isn't met within 10 seconds
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 10s")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
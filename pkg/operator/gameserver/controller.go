@@ -33,6 +33,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"time"
 )
 
 // Reconciler reconciles a GameServer object
@@ -83,6 +84,12 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, err
 	}
 
+	healthyFor, healthy, err := r.reconcileGameServerHealth(ctx, gs)
+	if err != nil || !healthy {
+		return ctrl.Result{}, err
+	}
+
+	var requeueAfter time.Duration
 	switch gs.Status.State {
 	case singularityv1.GameServerStateCreating:
 		if gs.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -98,6 +105,19 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 			return ctrl.Result{}, err
 		}
 		break
+	case singularityv1.GameServerStateReady:
+		if gs.IsRetired(time.Now()) {
+			// Allocated servers are only retired once they request to be Ready again
+			return ctrl.Result{}, r.retireGameServer(ctx, gs)
+		}
+		requeueAfter = gs.RetiresIn(time.Now())
+		break
+	case singularityv1.GameServerStateReserved:
+		var err error
+		if requeueAfter, err = r.reconcileGameServerReserved(ctx, gs); err != nil {
+			return ctrl.Result{}, err
+		}
+		break
 	case singularityv1.GameServerStateShutdown:
 		if err := r.reconcileGameServerShutdown(ctx, gs); err != nil {
 			return ctrl.Result{}, err
@@ -110,11 +130,27 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		break
 	}
 
+	if err := r.reconcileGameServerLegacyLabels(ctx, gs); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	if err := r.reconcileGameServerInstances(ctx, gs); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	if err := r.reconcileGameServerPodMetadata(ctx, gs); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.reconcileGameServerInstanceStatus(ctx, gs); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if healthyFor > 0 && (requeueAfter == 0 || healthyFor < requeueAfter) {
+		requeueAfter = healthyFor
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -176,17 +212,136 @@ func (r *Reconciler) reconcileGameServerCreating(ctx context.Context, gs *singul
 	return nil
 }
 
+// reconcileGameServerRequestReady moves a starting, previously allocated or reserved server into the Ready state.
+// Servers which still have allocated instances stay Allocated, retired servers are shut down instead.
+// Requests from any other state are rejected, restoring the previous state if it is known.
 func (r *Reconciler) reconcileGameServerRequestReady(ctx context.Context, gs *singularityv1.GameServer) error {
 	// TODO: Track ready container ID, etc
 
+	from := gs.Status.ReadyRequestedFrom
+	if !singularityv1.CanRequestReady(from) {
+		if from == "" {
+			r.Recorder.Event(gs, v1.EventTypeWarning, string(gs.Status.State), "Rejected RequestReady, the previous state is unknown")
+			return nil
+		}
+
+		gsCopy := gs.DeepCopy()
+		gsCopy.Status.State = from
+		gsCopy.Status.ReadyRequestedFrom = ""
+		if err := r.Status().Update(ctx, gsCopy); err != nil {
+			return errors.Wrapf(err, "error updating GameServer %s back to %s state", gs.Name, from)
+		}
+
+		r.Recorder.Eventf(gs, v1.EventTypeWarning, string(gsCopy.Status.State), "Rejected RequestReady from state %s", from)
+		return nil
+	}
+
+	if gs.Status.AllocatedInstances > 0 {
+		gsCopy := gs.DeepCopy()
+		gsCopy.Status.State = singularityv1.GameServerStateAllocated
+		gsCopy.Status.ReadyRequestedFrom = ""
+		if err := r.Status().Update(ctx, gsCopy); err != nil {
+			return errors.Wrapf(err, "error updating GameServer %s back to Allocated state", gs.Name)
+		}
+
+		r.Recorder.Eventf(gs, v1.EventTypeWarning, string(gsCopy.Status.State), "Rejected RequestReady, %d instances are still allocated", gs.Status.AllocatedInstances)
+		return nil
+	}
+
+	if gs.IsRetired(time.Now()) {
+		return r.retireGameServer(ctx, gs)
+	}
+
+	// The server can only request to be Ready from within its Pod, so the Pod is already running.
+	pod, err := r.getGameServerPod(ctx, gs)
+	if err != nil {
+		return errors.Wrapf(err, "error retrieving Pod for GameServer %s", gs.Name)
+	}
+
 	gsCopy := gs.DeepCopy()
 	gsCopy.Status.State = singularityv1.GameServerStateReady
+	gsCopy.Status.ReadyRequestedFrom = ""
+	gsCopy.Status.Address = pod.Status.PodIP
+	gsCopy.Status.NodeName = pod.Spec.NodeName
+	gsCopy.Status.Ports = gs.StatusPorts(pod)
+	if gsCopy.Status.FirstReady == nil {
+		now := metav1.Now()
+		gsCopy.Status.FirstReady = &now
+	}
 	if err := r.Status().Update(ctx, gsCopy); err != nil {
 		return errors.Wrapf(err, "error updating GameServer %s to Ready state", gs.Name)
 	}
 	return nil
 }
 
+// reconcileGameServerHealth marks the server Unhealthy once it missed its health checks.
+// Returns the duration until the next health check is due, or zero if health checks are disabled,
+// and whether the server is still healthy.
+func (r *Reconciler) reconcileGameServerHealth(ctx context.Context, gs *singularityv1.GameServer) (time.Duration, bool, error) {
+	switch gs.Status.State {
+	case singularityv1.GameServerStateUnhealthy:
+		return 0, false, nil
+	case "", singularityv1.GameServerStateCreating, singularityv1.GameServerStateShutdown, singularityv1.GameServerStateError:
+		return 0, true, nil
+	}
+
+	deadline, ok := gs.HealthDeadline()
+	if !ok {
+		return 0, true, nil
+	}
+
+	if remaining := time.Until(deadline); remaining > 0 {
+		return remaining, true, nil
+	}
+
+	gsCopy := gs.DeepCopy()
+	gsCopy.Status.State = singularityv1.GameServerStateUnhealthy
+	if err := r.Status().Update(ctx, gsCopy); err != nil {
+		return 0, false, errors.Wrapf(err, "error updating GameServer %s to Unhealthy state", gs.Name)
+	}
+
+	r.Recorder.Event(gs, v1.EventTypeWarning, string(gsCopy.Status.State), "Health check missed")
+
+	return 0, false, nil
+}
+
+// reconcileGameServerReserved returns the server to Ready once its reservation expired.
+// Returns the duration until the reservation expires otherwise.
+func (r *Reconciler) reconcileGameServerReserved(ctx context.Context, gs *singularityv1.GameServer) (time.Duration, error) {
+	if gs.Status.ReservedUntil == nil {
+		// Reserved until changed by the server
+		return 0, nil
+	}
+
+	if remaining := time.Until(gs.Status.ReservedUntil.Time); remaining > 0 {
+		return remaining, nil
+	}
+
+	gsCopy := gs.DeepCopy()
+	gsCopy.Status.State = singularityv1.GameServerStateReady
+	gsCopy.Status.ReservedUntil = nil
+	if err := r.Status().Update(ctx, gsCopy); err != nil {
+		return 0, errors.Wrapf(err, "error updating GameServer %s from Reserved to Ready state", gs.Name)
+	}
+
+	r.Recorder.Event(gs, v1.EventTypeNormal, string(gsCopy.Status.State), "Reservation expired")
+
+	return 0, nil
+}
+
+// retireGameServer shuts the server down, as its RecyclePolicy doesn't allow it to be reused
+func (r *Reconciler) retireGameServer(ctx context.Context, gs *singularityv1.GameServer) error {
+	gsCopy := gs.DeepCopy()
+	gsCopy.Status.State = singularityv1.GameServerStateShutdown
+	if err := r.Status().Update(ctx, gsCopy); err != nil {
+		return errors.Wrapf(err, "error updating GameServer %s to Shutdown state", gs.Name)
+	}
+
+	r.Recorder.Eventf(gs, v1.EventTypeNormal, string(gsCopy.Status.State), "Retired after %d allocations", gs.Status.Allocations)
+
+	return nil
+}
+
 func (r *Reconciler) reconcileGameServerShutdown(ctx context.Context, gs *singularityv1.GameServer) error {
 	if err := r.Delete(ctx, gs, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
 		return errors.Wrapf(err, "error deleting GameServer %s", gs.Name)
@@ -288,7 +443,7 @@ func (r *Reconciler) getGameServerInstance(ctx context.Context, gs *singularityv
 	var gsInstance singularityv1.GameServerInstance
 	key := client.ObjectKey{
 		Namespace: gs.ObjectMeta.Namespace,
-		Name:      fmt.Sprintf("%s-%d", gs.ObjectMeta.Name, id),
+		Name:      singularityv1.GameServerInstanceName(gs.ObjectMeta.Name, id),
 	}
 	if err := r.Get(ctx, key, &gsInstance); err != nil {
 		// The GameServerInstance is not found
@@ -327,3 +482,170 @@ func (r *Reconciler) reconcileGameServerInstances(ctx context.Context, gs *singu
 
 	return nil
 }
+
+// reconcileGameServerLegacyLabels relabels the Pod and GameServerInstances created before GameServerNameLabel was renamed,
+// so they are found by the new key again
+func (r *Reconciler) reconcileGameServerLegacyLabels(ctx context.Context, gs *singularityv1.GameServer) error {
+	var objects []client.Object
+
+	list := &singularityv1.GameServerInstanceList{}
+	if err := r.List(ctx, list, client.InNamespace(gs.ObjectMeta.Namespace), client.MatchingLabels{singularityv1.LegacyGameServerNameLabel: gs.ObjectMeta.Name}); err != nil {
+		return errors.Wrapf(err, "error listing GameServerInstances for GameServer %s", gs.Name)
+	}
+	for i := range list.Items {
+		objects = append(objects, &list.Items[i])
+	}
+
+	pod, err := r.getGameServerPod(ctx, gs)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	if pod != nil {
+		objects = append(objects, pod)
+	}
+
+	for _, obj := range objects {
+		if !metav1.IsControlledBy(obj, gs) {
+			continue
+		}
+
+		original := obj.DeepCopyObject().(client.Object)
+		if !gs.RelabelLegacy(obj) {
+			continue
+		}
+		if err = r.Patch(ctx, obj, client.MergeFrom(original)); err != nil {
+			return errors.Wrapf(err, "error relabelling %s", obj.GetName())
+		}
+	}
+
+	return nil
+}
+
+// reconcileGameServerPodMetadata propagates the labels and annotations of the GameServer to its Pod,
+// so that metadata applied on allocation is visible to the game through the downward API volume.
+// The metadata of its GameServerInstances is exposed in per-instance annotations, see GameServerInstanceMetadataDomain.
+func (r *Reconciler) reconcileGameServerPodMetadata(ctx context.Context, gs *singularityv1.GameServer) error {
+	switch gs.Status.State {
+	case singularityv1.GameServerStateReady, singularityv1.GameServerStateReserved, singularityv1.GameServerStateAllocated:
+	default:
+		return nil
+	}
+
+	pod, err := r.getGameServerPod(ctx, gs)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	list := &singularityv1.GameServerInstanceList{}
+	if err = r.List(ctx, list, client.InNamespace(gs.ObjectMeta.Namespace), client.MatchingLabels{singularityv1.GameServerNameLabel: gs.ObjectMeta.Name}); err != nil {
+		return errors.Wrapf(err, "error listing GameServerInstances for GameServer %s", gs.Name)
+	}
+
+	annotations := make(map[string]string, len(gs.ObjectMeta.Annotations)+2*len(list.Items))
+	for k, v := range gs.ObjectMeta.Annotations {
+		annotations[k] = v
+	}
+	for i := range list.Items {
+		if gsInstance := &list.Items[i]; metav1.IsControlledBy(gsInstance, gs) {
+			for k, v := range gsInstance.MetadataAnnotations() {
+				annotations[k] = v
+			}
+		}
+	}
+
+	podCopy := pod.DeepCopy()
+	changed := false
+	for k := range podCopy.ObjectMeta.Annotations {
+		// Remove the metadata of deleted instances
+		if _, ok := annotations[k]; !ok && singularityv1.IsInstanceMetadataAnnotation(k) {
+			delete(podCopy.ObjectMeta.Annotations, k)
+			changed = true
+		}
+	}
+	for k, v := range gs.ObjectMeta.Labels {
+		if podCopy.ObjectMeta.Labels[k] != v {
+			podCopy.ObjectMeta.Labels[k] = v
+			changed = true
+		}
+	}
+	for k, v := range annotations {
+		if podCopy.ObjectMeta.Annotations == nil {
+			podCopy.ObjectMeta.Annotations = make(map[string]string, len(annotations))
+		}
+		if podCopy.ObjectMeta.Annotations[k] != v {
+			podCopy.ObjectMeta.Annotations[k] = v
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	if err = r.Patch(ctx, podCopy, client.MergeFrom(pod)); err != nil {
+		return errors.Wrapf(err, "error updating metadata of Pod %s", pod.ObjectMeta.Name)
+	}
+
+	return nil
+}
+
+// reconcileGameServerInstanceStatus counts the instances of the GameServer by state.
+// A Ready GameServer becomes Allocated once one of its instances is allocated, and Ready again once none are.
+func (r *Reconciler) reconcileGameServerInstanceStatus(ctx context.Context, gs *singularityv1.GameServer) error {
+	// Other states are owned by the branches above, which already changed the resource
+	if gs.Status.State != singularityv1.GameServerStateReady && gs.Status.State != singularityv1.GameServerStateAllocated {
+		return nil
+	}
+
+	list := &singularityv1.GameServerInstanceList{}
+	if err := r.List(ctx, list, client.InNamespace(gs.ObjectMeta.Namespace), client.MatchingLabels{singularityv1.GameServerNameLabel: gs.ObjectMeta.Name}); err != nil {
+		return errors.Wrapf(err, "error listing GameServerInstances for GameServer %s", gs.Name)
+	}
+
+	gsCopy := gs.DeepCopy()
+	gsCopy.Status.Instances = 0
+	gsCopy.Status.ReadyInstances = 0
+	gsCopy.Status.AllocatedInstances = 0
+	for i := range list.Items {
+		gsInstance := &list.Items[i]
+		if !metav1.IsControlledBy(gsInstance, gs) {
+			continue
+		}
+
+		gsCopy.Status.Instances++
+		switch gsInstance.Status.State {
+		case singularityv1.GameServerInstanceStateReady:
+			gsCopy.Status.ReadyInstances++
+		case singularityv1.GameServerInstanceStateAllocated:
+			gsCopy.Status.AllocatedInstances++
+		}
+	}
+
+	switch {
+	case gs.Status.State == singularityv1.GameServerStateReady && gsCopy.Status.AllocatedInstances > 0:
+		gsCopy.Allocate()
+	case gs.Status.State == singularityv1.GameServerStateAllocated && gs.Status.AllocatedInstances > 0 && gsCopy.Status.AllocatedInstances == 0:
+		// Only servers allocated through their instances are released, whole server allocations are kept
+		gsCopy.Status.State = singularityv1.GameServerStateReady
+		if gsCopy.IsRetired(time.Now()) {
+			gsCopy.Status.State = singularityv1.GameServerStateShutdown
+		}
+	}
+
+	if gsCopy.Status.Instances == gs.Status.Instances &&
+		gsCopy.Status.ReadyInstances == gs.Status.ReadyInstances &&
+		gsCopy.Status.AllocatedInstances == gs.Status.AllocatedInstances &&
+		gsCopy.Status.State == gs.Status.State {
+		return nil
+	}
+
+	if err := r.Status().Update(ctx, gsCopy); err != nil {
+		return errors.Wrapf(err, "error updating instance status of GameServer %s", gs.Name)
+	}
+
+	if gsCopy.Status.State != gs.Status.State {
+		r.Recorder.Eventf(gs, v1.EventTypeNormal, string(gsCopy.Status.State), "%d of %d instances allocated", gsCopy.Status.AllocatedInstances, gsCopy.Status.Instances)
+	}
+
+	return nil
+}
@@ -0,0 +1,269 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package gameserver
+
+import (
+	"context"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+	"time"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(singularityv1.AddToScheme(scheme))
+}
+
+// newReconciler returns a Reconciler backed by a fake client containing the GameServer
+func newReconciler(gs *singularityv1.GameServer) (*Reconciler, *record.FakeRecorder) {
+	recorder := record.NewFakeRecorder(10)
+	return &Reconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs).Build(),
+		Recorder: recorder,
+	}, recorder
+}
+
+func TestReconcileGameServerReserved(t *testing.T) {
+	tests := []struct {
+		name      string
+		duration  time.Duration
+		elapsed   bool
+		wantState singularityv1.GameServerState
+		wantAfter bool
+	}{
+		{name: "reserved indefinitely", wantState: singularityv1.GameServerStateReserved},
+		{name: "reservation ongoing", duration: time.Hour, wantState: singularityv1.GameServerStateReserved, wantAfter: true},
+		{name: "reservation expired", duration: time.Minute, elapsed: true, wantState: singularityv1.GameServerStateReady},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := &singularityv1.GameServer{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "lobby"}}
+			gs.Reserve(tt.duration)
+			if tt.elapsed {
+				expired := metav1.NewTime(time.Now().Add(-time.Second))
+				gs.Status.ReservedUntil = &expired
+			}
+			r, _ := newReconciler(gs)
+
+			after, err := r.reconcileGameServerReserved(context.Background(), gs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (after > 0) != tt.wantAfter || after > tt.duration {
+				t.Errorf("requeue after = %s, want requeue %v", after, tt.wantAfter)
+			}
+
+			got := &singularityv1.GameServer{}
+			if err = r.Get(context.Background(), client.ObjectKeyFromObject(gs), got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.State != tt.wantState {
+				t.Errorf("state = %s, want %s", got.Status.State, tt.wantState)
+			}
+			if got.Status.State == singularityv1.GameServerStateReady && got.Status.ReservedUntil != nil {
+				t.Errorf("reservedUntil = %s, want nil", got.Status.ReservedUntil)
+			}
+		})
+	}
+}
+
+func TestReconcileGameServerRequestReady(t *testing.T) {
+	tests := []struct {
+		name      string
+		from      singularityv1.GameServerState
+		wantState singularityv1.GameServerState
+		wantEvent bool
+	}{
+		{name: "starting", from: singularityv1.GameServerStateStarting, wantState: singularityv1.GameServerStateReady},
+		{name: "allocated", from: singularityv1.GameServerStateAllocated, wantState: singularityv1.GameServerStateReady},
+		{name: "reserved", from: singularityv1.GameServerStateReserved, wantState: singularityv1.GameServerStateReady},
+		{name: "shutdown", from: singularityv1.GameServerStateShutdown, wantState: singularityv1.GameServerStateShutdown, wantEvent: true},
+		{name: "unhealthy", from: singularityv1.GameServerStateUnhealthy, wantState: singularityv1.GameServerStateUnhealthy, wantEvent: true},
+		{name: "unknown", wantState: singularityv1.GameServerStateRequestReady, wantEvent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := &singularityv1.GameServer{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "lobby"},
+				Status: singularityv1.GameServerStatus{
+					State:              singularityv1.GameServerStateRequestReady,
+					ReadyRequestedFrom: tt.from,
+				},
+			}
+			pod := gs.Pod()
+			pod.Status.PodIP = "10.0.0.1"
+			r, recorder := newReconciler(gs)
+			if err := r.Create(context.Background(), pod); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := r.reconcileGameServerRequestReady(context.Background(), gs); err != nil {
+				t.Fatal(err)
+			}
+
+			got := &singularityv1.GameServer{}
+			if err := r.Get(context.Background(), client.ObjectKeyFromObject(gs), got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.State != tt.wantState {
+				t.Errorf("state = %s, want %s", got.Status.State, tt.wantState)
+			}
+			if got.Status.State == singularityv1.GameServerStateReady && got.Status.Address != "10.0.0.1" {
+				t.Errorf("address = %q, want 10.0.0.1", got.Status.Address)
+			}
+			if (got.Status.FirstReady != nil) != (got.Status.State == singularityv1.GameServerStateReady) {
+				t.Errorf("firstReady = %v in state %s, want it to be set once Ready", got.Status.FirstReady, got.Status.State)
+			}
+			if event := len(recorder.Events) > 0; event != tt.wantEvent {
+				t.Errorf("event recorded = %v, want %v", event, tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestReconcileGameServerPodMetadata(t *testing.T) {
+	gs := &singularityv1.GameServer{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "lobby",
+			UID:         "lobby",
+			Annotations: map[string]string{"match": "ranked"},
+		},
+		Status: singularityv1.GameServerStatus{State: singularityv1.GameServerStateAllocated},
+	}
+	pod := gs.Pod()
+	pod.ObjectMeta.Annotations = map[string]string{
+		"deleted.instances.singularity.innit.gg/labels": "{}",
+	}
+	instance := func(id int, annotations map[string]string) *singularityv1.GameServerInstance {
+		gsInstance := gs.GameServerInstance(id)
+		gsInstance.ObjectMeta.Annotations = annotations
+		return gsInstance
+	}
+
+	r, _ := newReconciler(gs)
+	for _, obj := range []client.Object{
+		pod,
+		instance(0, map[string]string{"team": "red"}),
+		instance(1, nil),
+	} {
+		if err := r.Create(context.Background(), obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := r.reconcileGameServerPodMetadata(context.Background(), gs); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Get(context.Background(), client.ObjectKeyFromObject(pod), pod); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"match": "ranked",
+		"lobby-0.instances.singularity.innit.gg/annotations": `{"team":"red"}`,
+		"lobby-1.instances.singularity.innit.gg/annotations": `{}`,
+	}
+	for k, v := range want {
+		if got := pod.ObjectMeta.Annotations[k]; got != v {
+			t.Errorf("annotation %s = %q, want %q", k, got, v)
+		}
+	}
+	for _, k := range []string{"lobby-0.instances.singularity.innit.gg/labels", "lobby-1.instances.singularity.innit.gg/labels"} {
+		if _, ok := pod.ObjectMeta.Annotations[k]; !ok {
+			t.Errorf("annotation %s missing", k)
+		}
+	}
+	if _, ok := pod.ObjectMeta.Annotations["deleted.instances.singularity.innit.gg/labels"]; ok {
+		t.Error("annotation of deleted instance was not removed")
+	}
+}
+
+func TestReconcileGameServerHealth(t *testing.T) {
+	created := metav1.NewTime(time.Now().Add(-time.Hour))
+	missed := metav1.NewTime(time.Now().Add(-time.Minute))
+	recent := metav1.Now()
+
+	tests := []struct {
+		name        string
+		state       singularityv1.GameServerState
+		firstReady  *metav1.Time
+		lastHealthy *metav1.Time
+		wantState   singularityv1.GameServerState
+		wantHealthy bool
+	}{
+		// Starting servers haven't been Ready yet, however long they take
+		{name: "starting", state: singularityv1.GameServerStateStarting, wantState: singularityv1.GameServerStateStarting, wantHealthy: true},
+		{name: "scheduled", state: singularityv1.GameServerStateScheduled, wantState: singularityv1.GameServerStateScheduled, wantHealthy: true},
+		{
+			name:        "ready and reporting",
+			state:       singularityv1.GameServerStateReady,
+			firstReady:  &missed,
+			lastHealthy: &recent,
+			wantState:   singularityv1.GameServerStateReady,
+			wantHealthy: true,
+		},
+		{name: "ready and missed", state: singularityv1.GameServerStateReady, firstReady: &missed, wantState: singularityv1.GameServerStateUnhealthy},
+		{
+			name:        "allocated and missed",
+			state:       singularityv1.GameServerStateAllocated,
+			firstReady:  &missed,
+			lastHealthy: &missed,
+			wantState:   singularityv1.GameServerStateUnhealthy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := &singularityv1.GameServer{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "lobby", CreationTimestamp: created},
+				Spec:       singularityv1.GameServerSpec{Health: &singularityv1.GameServerHealth{PeriodSeconds: 5, FailureThreshold: 3}},
+				Status:     singularityv1.GameServerStatus{State: tt.state, FirstReady: tt.firstReady, LastHealthy: tt.lastHealthy},
+			}
+			r, _ := newReconciler(gs)
+
+			_, healthy, err := r.reconcileGameServerHealth(context.Background(), gs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if healthy != tt.wantHealthy {
+				t.Errorf("healthy = %v, want %v", healthy, tt.wantHealthy)
+			}
+
+			got := &singularityv1.GameServer{}
+			if err = r.Get(context.Background(), client.ObjectKeyFromObject(gs), got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.State != tt.wantState {
+				t.Errorf("state = %s, want %s", got.Status.State, tt.wantState)
+			}
+		})
+	}
+}
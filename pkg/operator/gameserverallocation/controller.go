@@ -0,0 +1,193 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package gameserverallocation
+
+import (
+	"context"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"innit.gg/singularity/pkg/allocator"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"time"
+)
+
+const (
+	// unallocatedRetention is how long a GameServerAllocation which didn't allocate anything is kept.
+	// Allocated ones are owned by their GameServer, and are garbage collected along with it.
+	unallocatedRetention = 10 * time.Minute
+)
+
+// Reconciler reconciles a GameServerAllocation object
+type Reconciler struct {
+	client.Client
+	Recorder  record.EventRecorder
+	Log       logr.Logger
+	Allocator *allocator.Allocator
+}
+
+//+kubebuilder:rbac:groups=singularity.innit.gg,resources=gameserverallocations,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=singularity.innit.gg,resources=gameserverallocations/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=singularity.innit.gg,resources=gameserverinstances/status,verbs=get;update;patch
+
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	l := log.FromContext(ctx)
+	l.Info("reconcile")
+
+	// Retrieve the GameServerAllocation resource from the cluster, ignoring if it was deleted
+	gsa := &singularityv1.GameServerAllocation{}
+	if err := r.Get(ctx, req.NamespacedName, gsa); err != nil {
+		l.Info("reconcile: resource deleted", "gsa", req.Name)
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// If GameServerAllocation is marked for deletion, don't do anything.
+	if !gsa.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	var allocation *allocator.Allocation
+	var err error
+	switch gsa.Status.State {
+	case "":
+		// Record that the allocation started first, so a failure to record its result doesn't allocate twice.
+		gsaPending := gsa.DeepCopy()
+		gsaPending.Status.State = singularityv1.GameServerAllocationStatePending
+		if err = r.Status().Update(ctx, gsaPending); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "error updating status for gameserverallocation %s", gsa.ObjectMeta.Name)
+		}
+
+		gsa = gsaPending
+		allocation, err = r.Allocator.Allocate(ctx, gsa)
+	case singularityv1.GameServerAllocationStatePending:
+		// A previous attempt failed, but may have allocated a GameServer already.
+		allocation, err = r.Allocator.Find(ctx, gsa)
+		if err == nil && allocation == nil {
+			allocation, err = r.Allocator.Allocate(ctx, gsa)
+		}
+	default:
+		// An allocation is only processed once.
+		return r.reconcileRetention(ctx, gsa)
+	}
+
+	gsaCopy := gsa.DeepCopy()
+	switch {
+	case errors.Is(err, allocator.ErrorNoGameServerReady):
+		gsaCopy.Status.State = singularityv1.GameServerAllocationStateUnAllocated
+	case errors.Is(err, allocator.ErrorContention):
+		gsaCopy.Status.State = singularityv1.GameServerAllocationStateContention
+	case err != nil:
+		l.Error(err, "reconcile: allocation failed", "gsa", req.Name)
+		return ctrl.Result{}, err
+	default:
+		gsaCopy.Status = allocation.Status()
+		if allocation.Instance != nil {
+			r.Recorder.Eventf(allocation.Instance, v1.EventTypeNormal, string(allocation.Instance.Status.State), "Allocated by GameServerAllocation %s", gsa.ObjectMeta.Name)
+		} else {
+			r.Recorder.Eventf(allocation.GameServer, v1.EventTypeNormal, string(allocation.GameServer.Status.State), "Allocated by GameServerAllocation %s", gsa.ObjectMeta.Name)
+		}
+	}
+
+	if err = r.Status().Update(ctx, gsaCopy); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "error updating status for gameserverallocation %s", gsa.ObjectMeta.Name)
+	}
+
+	r.Recorder.Eventf(gsa, v1.EventTypeNormal, string(gsaCopy.Status.State), "Allocation finished with state %s", gsaCopy.Status.State)
+
+	if allocation != nil {
+		// If this fails the allocation is already recorded, the owner is added by reconcileRetention instead.
+		return ctrl.Result{}, r.setOwner(ctx, gsaCopy, allocation.GameServer)
+	}
+
+	return ctrl.Result{RequeueAfter: unallocatedRetention}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&singularityv1.GameServerAllocation{}).
+		WithLogConstructor(func(req *reconcile.Request) logr.Logger {
+			if req != nil {
+				return r.Log.WithValues("req", req)
+			}
+			return r.Log
+		}).
+		Complete(r)
+}
+
+// reconcileRetention deletes processed GameServerAllocations which don't have an allocated GameServer,
+// and makes sure allocated ones are owned by their GameServer.
+func (r *Reconciler) reconcileRetention(ctx context.Context, gsa *singularityv1.GameServerAllocation) (ctrl.Result, error) {
+	if gsa.Status.State == singularityv1.GameServerAllocationStateAllocated {
+		return ctrl.Result{}, r.reconcileOwner(ctx, gsa)
+	}
+
+	remaining := gsa.ObjectMeta.CreationTimestamp.Add(unallocatedRetention).Sub(time.Now())
+	if remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	if err := r.Delete(ctx, gsa); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// reconcileOwner adds the allocated GameServer as owner of the GameServerAllocation, if it wasn't added after allocating.
+// The allocation is deleted if the GameServer is already gone, as it would have been garbage collected along with it.
+func (r *Reconciler) reconcileOwner(ctx context.Context, gsa *singularityv1.GameServerAllocation) error {
+	for _, ref := range gsa.ObjectMeta.OwnerReferences {
+		if ref.Kind == "GameServer" && ref.Name == gsa.Status.GameServerName {
+			return nil
+		}
+	}
+
+	gs := &singularityv1.GameServer{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: gsa.ObjectMeta.Namespace, Name: gsa.Status.GameServerName}, gs); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return client.IgnoreNotFound(r.Delete(ctx, gsa))
+		}
+		return errors.Wrapf(err, "error getting gameserver of gameserverallocation %s", gsa.ObjectMeta.Name)
+	}
+
+	return r.setOwner(ctx, gsa.DeepCopy(), gs)
+}
+
+// setOwner ties the lifetime of the allocation to the allocated GameServer
+func (r *Reconciler) setOwner(ctx context.Context, gsa *singularityv1.GameServerAllocation, gs *singularityv1.GameServer) error {
+	gsa.ObjectMeta.OwnerReferences = append(gsa.ObjectMeta.OwnerReferences, metav1.OwnerReference{
+		APIVersion: singularityv1.GroupVersion.String(),
+		Kind:       "GameServer",
+		Name:       gs.ObjectMeta.Name,
+		UID:        gs.ObjectMeta.UID,
+	})
+	if err := r.Update(ctx, gsa); err != nil {
+		return errors.Wrapf(err, "error updating owner of gameserverallocation %s", gsa.ObjectMeta.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package gameserverallocation
+
+import (
+	"context"
+	"innit.gg/singularity/pkg/allocator"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestReconcileIdempotent(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(singularityv1.AddToScheme(scheme))
+
+	gameServer := func(name string, state singularityv1.GameServerState, allocation string) *singularityv1.GameServer {
+		gs := &singularityv1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Status:     singularityv1.GameServerStatus{State: state},
+		}
+		if allocation != "" {
+			gs.ObjectMeta.Labels = map[string]string{singularityv1.GameServerAllocationLabel: allocation}
+		}
+		return gs
+	}
+
+	tests := []struct {
+		name      string
+		state     singularityv1.GameServerAllocationState
+		objects   []client.Object
+		want      string
+		wantReady string
+	}{
+		{
+			name:  "new allocation",
+			state: "",
+			objects: []client.Object{
+				gameServer("lobby-a", singularityv1.GameServerStateReady, ""),
+				gameServer("lobby-b", singularityv1.GameServerStateReady, ""),
+			},
+			want:      "lobby-a",
+			wantReady: "lobby-b",
+		},
+		{
+			name:  "pending allocation which already allocated",
+			state: singularityv1.GameServerAllocationStatePending,
+			objects: []client.Object{
+				gameServer("lobby-a", singularityv1.GameServerStateReady, ""),
+				gameServer("lobby-b", singularityv1.GameServerStateAllocated, "gsa-uid"),
+			},
+			want:      "lobby-b",
+			wantReady: "lobby-a",
+		},
+		{
+			name:  "pending allocation which didn't allocate yet",
+			state: singularityv1.GameServerAllocationStatePending,
+			objects: []client.Object{
+				gameServer("lobby-a", singularityv1.GameServerStateAllocated, "other-uid"),
+				gameServer("lobby-b", singularityv1.GameServerStateReady, ""),
+			},
+			want: "lobby-b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gsa := &singularityv1.GameServerAllocation{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "gsa", UID: types.UID("gsa-uid")},
+				Status:     singularityv1.GameServerAllocationStatus{State: tt.state},
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(tt.objects, gsa)...).Build()
+			r := &Reconciler{
+				Client:    c,
+				Recorder:  record.NewFakeRecorder(10),
+				Allocator: &allocator.Allocator{Client: c},
+			}
+
+			if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gsa)}); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := c.Get(context.Background(), client.ObjectKeyFromObject(gsa), gsa); err != nil {
+				t.Fatal(err)
+			}
+			if gsa.Status.State != singularityv1.GameServerAllocationStateAllocated || gsa.Status.GameServerName != tt.want {
+				t.Errorf("status = %s %s, want %s %s",
+					gsa.Status.State, gsa.Status.GameServerName, singularityv1.GameServerAllocationStateAllocated, tt.want)
+			}
+
+			list := &singularityv1.GameServerList{}
+			if err := c.List(context.Background(), list); err != nil {
+				t.Fatal(err)
+			}
+			for _, gs := range list.Items {
+				allocated := gs.ObjectMeta.Labels[singularityv1.GameServerAllocationLabel] == "gsa-uid"
+				if allocated != (gs.ObjectMeta.Name == tt.want) {
+					t.Errorf("gameserver %s labelled as allocated by gsa = %v", gs.ObjectMeta.Name, allocated)
+				}
+				if ready := gs.Status.State == singularityv1.GameServerStateReady; ready != (gs.ObjectMeta.Name == tt.wantReady) {
+					t.Errorf("gameserver %s ready = %v", gs.ObjectMeta.Name, ready)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileOwner(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(singularityv1.AddToScheme(scheme))
+
+	gs := &singularityv1.GameServer{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "lobby-a", UID: types.UID("lobby-a-uid")},
+		Status:     singularityv1.GameServerStatus{State: singularityv1.GameServerStateAllocated},
+	}
+	owner := metav1.OwnerReference{
+		APIVersion: singularityv1.GroupVersion.String(),
+		Kind:       "GameServer",
+		Name:       "lobby-a",
+		UID:        "lobby-a-uid",
+	}
+
+	tests := []struct {
+		name        string
+		owners      []metav1.OwnerReference
+		objects     []client.Object
+		wantDeleted bool
+	}{
+		// Adding the owner failed after the allocation was recorded
+		{name: "owner missing", objects: []client.Object{gs}},
+		{name: "owner set", owners: []metav1.OwnerReference{owner}, objects: []client.Object{gs}},
+		{name: "gameserver deleted", wantDeleted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gsa := &singularityv1.GameServerAllocation{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "gsa", UID: types.UID("gsa-uid"), OwnerReferences: tt.owners},
+				Status: singularityv1.GameServerAllocationStatus{
+					State:          singularityv1.GameServerAllocationStateAllocated,
+					GameServerName: "lobby-a",
+				},
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(tt.objects, gsa)...).Build()
+			r := &Reconciler{
+				Client:    c,
+				Recorder:  record.NewFakeRecorder(10),
+				Allocator: &allocator.Allocator{Client: c},
+			}
+
+			if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(gsa)}); err != nil {
+				t.Fatal(err)
+			}
+
+			err := c.Get(context.Background(), client.ObjectKeyFromObject(gsa), gsa)
+			if deleted := k8serrors.IsNotFound(err); deleted != tt.wantDeleted {
+				t.Fatalf("gameserverallocation deleted = %v, want %v (%v)", deleted, tt.wantDeleted, err)
+			}
+			if tt.wantDeleted {
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(gsa.ObjectMeta.OwnerReferences) != 1 || gsa.ObjectMeta.OwnerReferences[0] != owner {
+				t.Errorf("owner references = %v, want %v", gsa.ObjectMeta.OwnerReferences, owner)
+			}
+		})
+	}
+}
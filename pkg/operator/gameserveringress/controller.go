@@ -0,0 +1,457 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package gameserveringress
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"hash/fnv"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	"innit.gg/singularity/pkg/ingressprovider"
+	"innit.gg/singularity/pkg/ingressprovider/tcpshield"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/tools/record"
+	"net"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// sweepInterval is the interval leaked provider resources are swept in
+const sweepInterval = 30 * time.Minute
+
+// Reconciler reconciles a GameServerIngress object
+type Reconciler struct {
+	client.Client
+	Recorder record.EventRecorder
+	Log      logr.Logger
+	// Providers are the configured ingress providers by name
+	Providers map[string]ingressprovider.Provider
+}
+
+//+kubebuilder:rbac:groups=singularity.innit.gg,resources=gameserveringresses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=singularity.innit.gg,resources=gameserveringresses/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=singularity.innit.gg,resources=gameserveringresses/finalizers,verbs=update
+//+kubebuilder:rbac:groups=singularity.innit.gg,resources=gameserverinstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=discovery.k8s.io,resources=endpointslices,verbs=get;list;watch;create;update;patch;delete
+
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	l := log.FromContext(ctx)
+	l.Info("reconcile")
+
+	// Retrieve the GameServerIngress resource from the cluster, ignoring if it was deleted
+	ingress := &singularityv1.GameServerIngress{}
+	if err := r.Get(ctx, req.NamespacedName, ingress); err != nil {
+		l.Info("reconcile: resource deleted", "ingress", req.Name)
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	provider, ok := r.Providers[ingress.Spec.Provider]
+
+	if !ingress.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.reconcileDeletion(ctx, ingress, provider)
+	}
+
+	if !ok {
+		r.Recorder.Eventf(ingress, v1.EventTypeWarning, "ProviderNotFound", "Ingress provider %s is not configured", ingress.Spec.Provider)
+		return ctrl.Result{}, nil
+	}
+
+	if !controllerutil.ContainsFinalizer(ingress, singularityv1.GameServerIngressFinalizer) {
+		controllerutil.AddFinalizer(ingress, singularityv1.GameServerIngressFinalizer)
+		if err := r.Update(ctx, ingress); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "error adding finalizer to gameserveringress %s", ingress.ObjectMeta.Name)
+		}
+	}
+
+	backends, err := r.backends(ctx, ingress)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	hash := hashBackends(backends)
+	// Providers decline ingresses requiring features they don't support, which is only resolved by changing the ingress
+	var unsupported *ingressprovider.UnsupportedError
+
+	ingressCopy := ingress.DeepCopy()
+	status := &ingressCopy.Status
+	opts := options(ingress)
+
+	// The provider only updates ingresses by hostname, so a changed hostname requires a new ingress.
+	if status.ID != "" && status.Hostname != ingress.Spec.Hostname {
+		if err = provider.Delete(ctx, status.ID); err != nil && !errors.Is(err, ingressprovider.ErrorNotFound) {
+			return ctrl.Result{}, errors.Wrapf(err, "error deleting ingress %s of %s", status.ID, status.Hostname)
+		}
+		r.Recorder.Eventf(ingress, v1.EventTypeNormal, "Deleted", "Deleted ingress %s of %s", status.ID, status.Hostname)
+		status.ID = ""
+		// Forget the deleted ingress before creating the new one, which may fail and leave the status behind otherwise
+		if err = r.updateStatus(ctx, ingress, ingressCopy); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if status.ID != "" && (status.BackendsHash != hash || status.ObservedGeneration != ingress.Generation) {
+		err = provider.Update(ctx, ingress.Spec.Hostname, backends, opts)
+		switch {
+		case errors.Is(err, ingressprovider.ErrorNotFound):
+			// The ingress was removed outside the cluster, create it again.
+			r.Recorder.Eventf(ingress, v1.EventTypeWarning, "NotFound", "Ingress %s of %s not found", status.ID, status.Hostname)
+			status.ID = ""
+		case errors.As(err, &unsupported):
+			r.Recorder.Eventf(ingress, v1.EventTypeWarning, "Unsupported", "Provider %s can't update ingress %s: %v", ingress.Spec.Provider, ingress.Spec.Hostname, err)
+			return ctrl.Result{}, r.updateStatus(ctx, ingress, ingressCopy)
+		case err != nil:
+			return ctrl.Result{}, errors.Wrapf(err, "error updating ingress %s", ingress.Spec.Hostname)
+		default:
+			r.Recorder.Eventf(ingress, v1.EventTypeNormal, "Updated", "Updated ingress %s with %d backends", ingress.Spec.Hostname, len(backends))
+		}
+	}
+
+	if status.ID == "" {
+		id, err := provider.Create(ctx, ingress.Spec.Hostname, backends, opts)
+		if errors.As(err, &unsupported) {
+			r.Recorder.Eventf(ingress, v1.EventTypeWarning, "Unsupported", "Provider %s can't create ingress %s: %v", ingress.Spec.Provider, ingress.Spec.Hostname, err)
+			return ctrl.Result{}, r.updateStatus(ctx, ingress, ingressCopy)
+		}
+		if err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "error creating ingress %s", ingress.Spec.Hostname)
+		}
+		r.Recorder.Eventf(ingress, v1.EventTypeNormal, "Created", "Created ingress %s of %s with %d backends", id, ingress.Spec.Hostname, len(backends))
+		status.ID = id
+		status.Verified = false
+	}
+
+	status.ObservedGeneration = ingress.Generation
+	status.Hostname = ingress.Spec.Hostname
+	status.Backends = addresses(backends)
+	status.BackendsHash = hash
+	status.Replicas = int32(len(backends))
+
+	return ctrl.Result{}, r.updateStatus(ctx, ingress, ingressCopy)
+}
+
+// updateStatus updates the status of the ingress to the one of the copy, if it changed
+func (r *Reconciler) updateStatus(ctx context.Context, ingress, ingressCopy *singularityv1.GameServerIngress) error {
+	if equality.Semantic.DeepEqual(ingress.Status, ingressCopy.Status) {
+		return nil
+	}
+
+	if err := r.Status().Update(ctx, ingressCopy); err != nil {
+		return errors.Wrapf(err, "error updating status for gameserveringress %s", ingress.ObjectMeta.Name)
+	}
+
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Runnables without leader election preferences only run on the leader.
+	if err := mgr.Add(manager.RunnableFunc(r.sweep)); err != nil {
+		return err
+	}
+	if err := mgr.Add(manager.RunnableFunc(r.detectDrift)); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&singularityv1.GameServerIngress{}).
+		Watches(&source.Kind{Type: &singularityv1.GameServer{}}, handler.EnqueueRequestsFromMapFunc(r.ingressesOf)).
+		Watches(&source.Kind{Type: &singularityv1.GameServerInstance{}}, handler.EnqueueRequestsFromMapFunc(r.instanceIngressesOf)).
+		WithLogConstructor(func(req *reconcile.Request) logr.Logger {
+			if req != nil {
+				return r.Log.WithValues("req", req)
+			}
+			return r.Log
+		}).
+		Complete(r)
+}
+
+// sweep periodically removes the resources leaked by providers implementing ingressprovider.Sweeper
+func (r *Reconciler) sweep(ctx context.Context) error {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		for name, provider := range r.Providers {
+			sweeper, ok := provider.(ingressprovider.Sweeper)
+			if !ok {
+				continue
+			}
+
+			deleted, err := sweeper.Sweep(ctx)
+			if err != nil {
+				r.Log.Error(err, "error sweeping ingress provider", "provider", name)
+			} else if deleted > 0 {
+				r.Log.Info("swept ingress provider", "provider", name, "deleted", deleted)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcileDeletion deletes the ingress from its provider, before removing the finalizer
+func (r *Reconciler) reconcileDeletion(ctx context.Context, ingress *singularityv1.GameServerIngress, provider ingressprovider.Provider) error {
+	if !controllerutil.ContainsFinalizer(ingress, singularityv1.GameServerIngressFinalizer) {
+		return nil
+	}
+
+	switch {
+	case ingress.Status.ID == "":
+	case provider == nil:
+		// Nothing can delete the ingress anymore, so it is left to be removed manually instead of blocking the deletion
+		r.Recorder.Eventf(ingress, v1.EventTypeWarning, "ProviderNotFound", "Ingress provider %s is not configured, ingress %s of %s is not deleted",
+			ingress.Spec.Provider, ingress.Status.ID, ingress.Status.Hostname)
+	default:
+		if err := provider.Delete(ctx, ingress.Status.ID); err != nil && !errors.Is(err, ingressprovider.ErrorNotFound) {
+			return errors.Wrapf(err, "error deleting ingress %s of %s", ingress.Status.ID, ingress.Status.Hostname)
+		}
+	}
+
+	controllerutil.RemoveFinalizer(ingress, singularityv1.GameServerIngressFinalizer)
+	if err := r.Update(ctx, ingress); err != nil {
+		return errors.Wrapf(err, "error removing finalizer from gameserveringress %s", ingress.ObjectMeta.Name)
+	}
+
+	return nil
+}
+
+// backends returns the backends of the Ready GameServers, or GameServerInstances, selected by the ingress
+func (r *Reconciler) backends(ctx context.Context, ingress *singularityv1.GameServerIngress) ([]*ingressprovider.Backend, error) {
+	opts, err := ingress.ListOptions()
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing ingress selector")
+	}
+
+	list := &singularityv1.GameServerList{}
+	if err = r.List(ctx, list, opts...); err != nil {
+		return nil, errors.Wrap(err, "error listing gameservers")
+	}
+
+	if ingress.Spec.Instances {
+		return r.instanceBackends(ctx, ingress, list)
+	}
+
+	var backends []*ingressprovider.Backend
+	for i := range list.Items {
+		gs := &list.Items[i]
+		// Draining servers remain backends, so players aren't cut off by providers which can drain them
+		draining := gs.Status.State == singularityv1.GameServerStateDrain
+		if (gs.Status.State != singularityv1.GameServerStateReady && !draining) || gs.IsBeingDeleted() {
+			continue
+		}
+
+		if backend, ok := gameServerBackend(gs, ingress.Spec); ok {
+			backends = append(backends, backend)
+		}
+	}
+
+	return backends, nil
+}
+
+// instanceBackends returns the backends of the Ready GameServerInstances of the GameServers.
+// Instances of servers which are already allocated may still be joined, those of draining servers are draining.
+func (r *Reconciler) instanceBackends(ctx context.Context, ingress *singularityv1.GameServerIngress, list *singularityv1.GameServerList) ([]*ingressprovider.Backend, error) {
+	parents := make(map[string]*singularityv1.GameServer, len(list.Items))
+	for i := range list.Items {
+		gs := &list.Items[i]
+		if (gs.Status.State == singularityv1.GameServerStateReady || gs.Status.State == singularityv1.GameServerStateAllocated ||
+			gs.Status.State == singularityv1.GameServerStateDrain) && !gs.IsBeingDeleted() {
+			parents[gs.ObjectMeta.Name] = gs
+		}
+	}
+
+	instances := &singularityv1.GameServerInstanceList{}
+	if err := r.List(ctx, instances, client.InNamespace(ingress.ObjectMeta.Namespace)); err != nil {
+		return nil, errors.Wrap(err, "error listing gameserverinstances")
+	}
+
+	var backends []*ingressprovider.Backend
+	for i := range instances.Items {
+		gsInstance := &instances.Items[i]
+		parent, ok := parents[gsInstance.ObjectMeta.Labels[singularityv1.GameServerNameLabel]]
+		if !ok || gsInstance.Status.State != singularityv1.GameServerInstanceStateReady {
+			continue
+		}
+
+		backend, ok := gameServerBackend(parent, ingress.Spec)
+		if !ok {
+			continue
+		}
+		backend.Name = gsInstance.ObjectMeta.Name
+		backend.Metadata = map[string]string{
+			"gameserver": parent.ObjectMeta.Name,
+			"map":        gsInstance.Spec.Map,
+			"capacity":   strconv.Itoa(int(gsInstance.Spec.Capacity)),
+			"players":    strconv.Itoa(len(gsInstance.Status.Players)),
+		}
+		backends = append(backends, backend)
+	}
+
+	return backends, nil
+}
+
+// ingressesOf maps a GameServer to the GameServerIngresses selecting it
+func (r *Reconciler) ingressesOf(obj client.Object) []reconcile.Request {
+	gs, ok := obj.(*singularityv1.GameServer)
+	if !ok {
+		return nil
+	}
+
+	list := &singularityv1.GameServerIngressList{}
+	if err := r.List(context.Background(), list, client.InNamespace(gs.ObjectMeta.Namespace)); err != nil {
+		r.Log.Error(err, "error listing gameserveringresses")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range list.Items {
+		if list.Items[i].Selects(gs) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+		}
+	}
+
+	return requests
+}
+
+// instanceIngressesOf maps a GameServerInstance to the GameServerIngresses selecting its GameServer
+func (r *Reconciler) instanceIngressesOf(obj client.Object) []reconcile.Request {
+	name, ok := obj.GetLabels()[singularityv1.GameServerNameLabel]
+	if !ok {
+		return nil
+	}
+
+	gs := &singularityv1.GameServer{}
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: obj.GetNamespace(), Name: name}, gs); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			r.Log.Error(err, "error getting gameserver")
+		}
+		return nil
+	}
+
+	return r.ingressesOf(gs)
+}
+
+// options returns the provider options of the ingress
+func options(ingress *singularityv1.GameServerIngress) ingressprovider.Options {
+	opts := ingressprovider.Options{
+		ProxyProtocol: ingress.Spec.ProxyProtocol,
+	}
+	if ingress.Spec.TCPShield != nil {
+		opts.Extensions = append(opts.Extensions, &tcpshield.Extension{BAC: ingress.Spec.TCPShield.BAC})
+	}
+
+	return opts
+}
+
+// gameServerBackend returns the backend of the GameServer's port, or false if it doesn't have an address or the port
+func gameServerBackend(gs *singularityv1.GameServer, spec singularityv1.GameServerIngressSpec) (*ingressprovider.Backend, bool) {
+	ip := net.ParseIP(gs.Status.Address)
+	number, ok := statusPort(gs, spec.Port)
+	if ip == nil || !ok {
+		return nil, false
+	}
+
+	backend := &ingressprovider.Backend{
+		IP:       ip,
+		Port:     uint16(number),
+		Protocol: ingressprovider.ProtocolTCP,
+		Weight:   ingressprovider.DefaultWeight,
+		Draining: gs.Status.State == singularityv1.GameServerStateDrain,
+		Name:     gs.ObjectMeta.Name,
+	}
+	if spec.Protocol == v1.ProtocolUDP {
+		backend.Protocol = ingressprovider.ProtocolUDP
+	}
+	if weight, err := strconv.ParseUint(gs.ObjectMeta.Annotations[singularityv1.GameServerIngressWeightAnnotation], 10, 16); err == nil {
+		backend.Weight = uint16(weight)
+	}
+
+	return backend, true
+}
+
+// hashBackends returns a stable hash of the backends, including their metadata
+func hashBackends(backends []*ingressprovider.Backend) string {
+	sorted := make([]*ingressprovider.Backend, len(backends))
+	copy(sorted, backends)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	hasher := fnv.New32a()
+	_ = json.NewEncoder(hasher).Encode(sorted)
+
+	return rand.SafeEncodeString(fmt.Sprint(hasher.Sum32()))
+}
+
+// statusPort returns the named port of the GameServer, or its first one if the name is empty
+func statusPort(gs *singularityv1.GameServer, name string) (int32, bool) {
+	for _, port := range gs.Status.Ports {
+		if name == "" || port.Name == name {
+			return port.Port, true
+		}
+	}
+
+	return 0, false
+}
+
+// addresses returns the sorted addresses of the backends which aren't draining
+func addresses(backends []*ingressprovider.Backend) []string {
+	addresses := make([]string, 0, len(backends))
+	for _, backend := range backends {
+		if !backend.Draining {
+			addresses = append(addresses, backend.Address())
+		}
+	}
+	sort.Strings(addresses)
+
+	return addresses
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
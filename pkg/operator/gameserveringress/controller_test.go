@@ -0,0 +1,328 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package gameserveringress
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	"innit.gg/singularity/pkg/ingressprovider"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"net"
+	"reflect"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"strings"
+	"testing"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(singularityv1.AddToScheme(scheme))
+}
+
+// fakeProvider records the calls of the controller, failing them with the configured errors
+type fakeProvider struct {
+	ingresses []*ingressprovider.Ingress
+	created   []string
+	deleted   []string
+	createErr error
+	updateErr error
+	deleteErr error
+}
+
+func (p *fakeProvider) Create(_ context.Context, hostName string, _ []*ingressprovider.Backend, _ ingressprovider.Options) (string, error) {
+	if p.createErr != nil {
+		return "", p.createErr
+	}
+	p.created = append(p.created, hostName)
+	return "id-" + hostName, nil
+}
+
+func (p *fakeProvider) Update(context.Context, string, []*ingressprovider.Backend, ingressprovider.Options) error {
+	return p.updateErr
+}
+
+func (p *fakeProvider) Delete(_ context.Context, id string) error {
+	p.deleted = append(p.deleted, id)
+	return p.deleteErr
+}
+
+func (p *fakeProvider) Get(context.Context, string) (*ingressprovider.Ingress, error) {
+	return nil, ingressprovider.ErrorNotFound
+}
+
+func (p *fakeProvider) List(context.Context) ([]*ingressprovider.Ingress, error) {
+	return p.ingresses, nil
+}
+
+// newReconciler returns a Reconciler backed by a fake client containing the ingress
+func newReconciler(ingress *singularityv1.GameServerIngress, providers map[string]ingressprovider.Provider) (*Reconciler, *record.FakeRecorder) {
+	recorder := record.NewFakeRecorder(10)
+	return &Reconciler{
+		Client:    fake.NewClientBuilder().WithScheme(scheme).WithObjects(ingress).Build(),
+		Recorder:  recorder,
+		Providers: providers,
+	}, recorder
+}
+
+func TestReconcileHostnameChange(t *testing.T) {
+	ctx := context.Background()
+	ingress := &singularityv1.GameServerIngress{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:  "default",
+			Name:       "lobby",
+			Finalizers: []string{singularityv1.GameServerIngressFinalizer},
+		},
+		Spec:   singularityv1.GameServerIngressSpec{Hostname: "new.example.com", Provider: "fake"},
+		Status: singularityv1.GameServerIngressStatus{ID: "id-old.example.com", Hostname: "old.example.com"},
+	}
+	// The old ingress was already removed outside the cluster, and creating the new one fails at first
+	provider := &fakeProvider{deleteErr: ingressprovider.ErrorNotFound, createErr: errors.New("unavailable")}
+	r, _ := newReconciler(ingress, map[string]ingressprovider.Provider{"fake": provider})
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ingress)}
+
+	if _, err := r.Reconcile(ctx, req); err == nil {
+		t.Fatal("Reconcile() error = nil, want the create error")
+	}
+	if err := r.Get(ctx, req.NamespacedName, ingress); err != nil {
+		t.Fatal(err)
+	}
+	if ingress.Status.ID != "" {
+		t.Errorf("status id = %q after the deletion, want it to be forgotten", ingress.Status.ID)
+	}
+
+	provider.createErr = nil
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Get(ctx, req.NamespacedName, ingress); err != nil {
+		t.Fatal(err)
+	}
+	if ingress.Status.ID != "id-new.example.com" || ingress.Status.Hostname != "new.example.com" {
+		t.Errorf("status = %s of %s, want id-new.example.com of new.example.com", ingress.Status.ID, ingress.Status.Hostname)
+	}
+	if len(provider.deleted) != 1 {
+		t.Errorf("deleted %v, want the old ingress to be deleted once", provider.deleted)
+	}
+}
+
+func TestReconcileDeletion(t *testing.T) {
+	tests := []struct {
+		name        string
+		providers   map[string]ingressprovider.Provider
+		wantDeleted bool
+		wantErr     bool
+		wantEvent   string
+	}{
+		{name: "deleted", providers: map[string]ingressprovider.Provider{"fake": &fakeProvider{}}, wantDeleted: true},
+		{
+			name:        "already deleted",
+			providers:   map[string]ingressprovider.Provider{"fake": &fakeProvider{deleteErr: ingressprovider.ErrorNotFound}},
+			wantDeleted: true,
+		},
+		{
+			name:      "delete failed",
+			providers: map[string]ingressprovider.Provider{"fake": &fakeProvider{deleteErr: errors.New("unavailable")}},
+			wantErr:   true,
+		},
+		{name: "provider not configured", wantDeleted: true, wantEvent: "ProviderNotFound"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			now := metav1.Now()
+			ingress := &singularityv1.GameServerIngress{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:         "default",
+					Name:              "lobby",
+					Finalizers:        []string{singularityv1.GameServerIngressFinalizer},
+					DeletionTimestamp: &now,
+				},
+				Spec:   singularityv1.GameServerIngressSpec{Hostname: "play.example.com", Provider: "fake"},
+				Status: singularityv1.GameServerIngressStatus{ID: "id-play.example.com", Hostname: "play.example.com"},
+			}
+			r, recorder := newReconciler(ingress, tt.providers)
+
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ingress)})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Reconcile() error = %v, want error %v", err, tt.wantErr)
+			}
+
+			err = r.Get(ctx, client.ObjectKeyFromObject(ingress), ingress)
+			if err != nil && !k8serrors.IsNotFound(err) {
+				t.Fatal(err)
+			}
+			finalized := err != nil || !controllerutil.ContainsFinalizer(ingress, singularityv1.GameServerIngressFinalizer)
+			if finalized != tt.wantDeleted {
+				t.Errorf("finalizer removed = %v, want %v", finalized, tt.wantDeleted)
+			}
+
+			if tt.wantEvent != "" {
+				expectEvent(t, recorder, v1.EventTypeWarning, tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestReconcileUnsupported(t *testing.T) {
+	unsupported := &ingressprovider.UnsupportedError{Feature: ingressprovider.FeatureUDP}
+	tests := []struct {
+		name     string
+		status   singularityv1.GameServerIngressStatus
+		provider *fakeProvider
+	}{
+		{name: "create", provider: &fakeProvider{createErr: unsupported}},
+		{
+			name:     "update",
+			status:   singularityv1.GameServerIngressStatus{ID: "id-play.example.com", Hostname: "play.example.com", BackendsHash: "outdated"},
+			provider: &fakeProvider{updateErr: errors.Wrap(unsupported, "error updating")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			ingress := &singularityv1.GameServerIngress{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "lobby"},
+				Spec:       singularityv1.GameServerIngressSpec{Hostname: "play.example.com", Provider: "fake", Protocol: v1.ProtocolUDP},
+				Status:     tt.status,
+			}
+			r, recorder := newReconciler(ingress, map[string]ingressprovider.Provider{"fake": tt.provider})
+
+			// Retrying doesn't help, the ingress has to be changed
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ingress)}); err != nil {
+				t.Fatalf("Reconcile() error = %v, want nil", err)
+			}
+			expectEvent(t, recorder, v1.EventTypeWarning, "Unsupported")
+
+			if err := r.Get(ctx, client.ObjectKeyFromObject(ingress), ingress); err != nil {
+				t.Fatal(err)
+			}
+			if ingress.Status.ID != tt.status.ID || ingress.Status.BackendsHash != tt.status.BackendsHash {
+				t.Errorf("status = %s with hash %s, want it to be unchanged", ingress.Status.ID, ingress.Status.BackendsHash)
+			}
+		})
+	}
+}
+
+func TestGameServerBackend(t *testing.T) {
+	tests := []struct {
+		name        string
+		state       singularityv1.GameServerState
+		address     string
+		annotations map[string]string
+		spec        singularityv1.GameServerIngressSpec
+		want        *ingressprovider.Backend
+	}{
+		{
+			name:    "ready",
+			state:   singularityv1.GameServerStateReady,
+			address: "10.0.0.1",
+			want:    &ingressprovider.Backend{IP: net.ParseIP("10.0.0.1"), Port: 25565, Protocol: ingressprovider.ProtocolTCP, Weight: ingressprovider.DefaultWeight},
+		},
+		{
+			name:    "IPv6",
+			state:   singularityv1.GameServerStateReady,
+			address: "fd00::1",
+			want:    &ingressprovider.Backend{IP: net.ParseIP("fd00::1"), Port: 25565, Protocol: ingressprovider.ProtocolTCP, Weight: ingressprovider.DefaultWeight},
+		},
+		{
+			name:    "draining",
+			state:   singularityv1.GameServerStateDrain,
+			address: "10.0.0.1",
+			want:    &ingressprovider.Backend{IP: net.ParseIP("10.0.0.1"), Port: 25565, Protocol: ingressprovider.ProtocolTCP, Weight: ingressprovider.DefaultWeight, Draining: true},
+		},
+		{
+			name:    "UDP port",
+			state:   singularityv1.GameServerStateReady,
+			address: "10.0.0.1",
+			spec:    singularityv1.GameServerIngressSpec{Port: "bedrock", Protocol: v1.ProtocolUDP},
+			want:    &ingressprovider.Backend{IP: net.ParseIP("10.0.0.1"), Port: 19132, Protocol: ingressprovider.ProtocolUDP, Weight: ingressprovider.DefaultWeight},
+		},
+		{
+			name:        "weighted",
+			state:       singularityv1.GameServerStateReady,
+			address:     "10.0.0.1",
+			annotations: map[string]string{singularityv1.GameServerIngressWeightAnnotation: "5"},
+			want:        &ingressprovider.Backend{IP: net.ParseIP("10.0.0.1"), Port: 25565, Protocol: ingressprovider.ProtocolTCP, Weight: 5},
+		},
+		{
+			name:        "invalid weight",
+			state:       singularityv1.GameServerStateReady,
+			address:     "10.0.0.1",
+			annotations: map[string]string{singularityv1.GameServerIngressWeightAnnotation: "-1"},
+			want:        &ingressprovider.Backend{IP: net.ParseIP("10.0.0.1"), Port: 25565, Protocol: ingressprovider.ProtocolTCP, Weight: ingressprovider.DefaultWeight},
+		},
+		{name: "without address", state: singularityv1.GameServerStateReady},
+		{name: "without port", state: singularityv1.GameServerStateReady, address: "10.0.0.1", spec: singularityv1.GameServerIngressSpec{Port: "query"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := &singularityv1.GameServer{
+				ObjectMeta: metav1.ObjectMeta{Name: "lobby-abcde", Annotations: tt.annotations},
+				Status: singularityv1.GameServerStatus{
+					State:   tt.state,
+					Address: tt.address,
+					Ports:   []singularityv1.GameServerStatusPort{{Name: "minecraft", Port: 25565}, {Name: "bedrock", Port: 19132}},
+				},
+			}
+			if tt.want != nil {
+				tt.want.Name = gs.ObjectMeta.Name
+			}
+
+			backend, ok := gameServerBackend(gs, tt.spec)
+			if ok != (tt.want != nil) {
+				t.Fatalf("gameServerBackend() = %v, want %v", ok, tt.want != nil)
+			}
+			if !reflect.DeepEqual(backend, tt.want) {
+				t.Errorf("gameServerBackend() = %+v, want %+v", backend, tt.want)
+			}
+		})
+	}
+}
+
+// expectEvent fails the test unless the recorder received an event of the type and reason
+func expectEvent(t *testing.T, recorder *record.FakeRecorder, eventType, reason string) {
+	t.Helper()
+
+	for {
+		select {
+		case event := <-recorder.Events:
+			if fields := strings.Fields(event); len(fields) >= 2 && fields[0] == eventType && fields[1] == reason {
+				return
+			}
+		default:
+			t.Errorf("no %s event %s recorded", eventType, reason)
+			return
+		}
+	}
+}
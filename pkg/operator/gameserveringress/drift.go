@@ -0,0 +1,115 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package gameserveringress
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	"innit.gg/singularity/pkg/ingressprovider"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"time"
+)
+
+// driftInterval is the interval the ingresses of the providers are compared with the GameServerIngresses in
+const driftInterval = 5 * time.Minute
+
+// detectDrift periodically detects ingresses which were changed outside the cluster
+func (r *Reconciler) detectDrift(ctx context.Context) error {
+	ticker := time.NewTicker(driftInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		for name, provider := range r.Providers {
+			if err := r.reconcileDrift(ctx, name, provider); err != nil {
+				r.Log.Error(err, "error detecting ingress drift", "provider", name)
+			}
+		}
+	}
+}
+
+// reconcileDrift compares the ingresses of the provider with their GameServerIngresses.
+// Differences are reported through events and written to the status, which makes the next reconciliation correct them.
+// The verification of hostnames is only updated here, as it happens outside the cluster.
+func (r *Reconciler) reconcileDrift(ctx context.Context, name string, provider ingressprovider.Provider) error {
+	list := &singularityv1.GameServerIngressList{}
+	if err := r.List(ctx, list); err != nil {
+		return errors.Wrap(err, "error listing gameserveringresses")
+	}
+
+	actual, err := provider.List(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error listing ingresses")
+	}
+	byID := make(map[string]*ingressprovider.Ingress, len(actual))
+	for _, ingress := range actual {
+		byID[ingress.ID] = ingress
+	}
+
+	for i := range list.Items {
+		ingress := &list.Items[i]
+		if ingress.Spec.Provider != name || ingress.Status.ID == "" || !ingress.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		ingressCopy := ingress.DeepCopy()
+		status := &ingressCopy.Status
+
+		current, ok := byID[ingress.Status.ID]
+		switch {
+		case !ok:
+			r.Recorder.Eventf(ingress, v1.EventTypeWarning, "Drift", "Ingress %s of %s was deleted outside the cluster", status.ID, status.Hostname)
+			status.ID = ""
+		case current.HostName != status.Hostname:
+			r.Recorder.Eventf(ingress, v1.EventTypeWarning, "Drift", "Hostname of ingress %s was changed to %s outside the cluster", status.ID, current.HostName)
+			status.Hostname = current.HostName
+		case !equal(addresses(current.BackendSet), status.Backends):
+			r.Recorder.Eventf(ingress, v1.EventTypeWarning, "Drift", "Backends of ingress %s were changed outside the cluster", status.ID)
+			status.Backends = addresses(current.BackendSet)
+			status.BackendsHash = ""
+		case status.ObservedGeneration == ingress.Generation && !options(ingress).Matches(current.Options):
+			r.Recorder.Eventf(ingress, v1.EventTypeWarning, "Drift", "Options of ingress %s were changed outside the cluster", status.ID)
+			status.ObservedGeneration = 0
+		}
+
+		if ok && current.Verified != status.Verified {
+			if current.Verified {
+				r.Recorder.Eventf(ingress, v1.EventTypeNormal, "Verified", "Hostname %s was verified", current.HostName)
+			}
+			status.Verified = current.Verified
+		}
+
+		if equality.Semantic.DeepEqual(ingress.Status, ingressCopy.Status) {
+			continue
+		}
+
+		if err = r.Status().Update(ctx, ingressCopy); err != nil {
+			return errors.Wrapf(err, "error updating status for gameserveringress %s", ingress.ObjectMeta.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package gameserveringress
+
+import (
+	"context"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	"innit.gg/singularity/pkg/ingressprovider"
+	"innit.gg/singularity/pkg/ingressprovider/tcpshield"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"testing"
+)
+
+func TestReconcileDrift(t *testing.T) {
+	backends := []*ingressprovider.Backend{{IP: net.ParseIP("10.0.0.1"), Port: 25565}}
+	inSync := func() *ingressprovider.Ingress {
+		return &ingressprovider.Ingress{ID: "1", HostName: "play.example.com", BackendSet: backends}
+	}
+
+	tests := []struct {
+		name       string
+		provider   string
+		generation int64
+		current    func(ingress *ingressprovider.Ingress) *ingressprovider.Ingress
+		want       singularityv1.GameServerIngressStatus
+		wantEvent  string
+	}{
+		{
+			name:    "in sync",
+			current: func(ingress *ingressprovider.Ingress) *ingressprovider.Ingress { return ingress },
+			want:    singularityv1.GameServerIngressStatus{ID: "1", Hostname: "play.example.com", Backends: []string{"10.0.0.1:25565"}, BackendsHash: "hash", ObservedGeneration: 1},
+		},
+		{
+			name:      "deleted",
+			current:   func(*ingressprovider.Ingress) *ingressprovider.Ingress { return nil },
+			want:      singularityv1.GameServerIngressStatus{Hostname: "play.example.com", Backends: []string{"10.0.0.1:25565"}, BackendsHash: "hash", ObservedGeneration: 1},
+			wantEvent: "Drift",
+		},
+		{
+			name: "hostname changed",
+			current: func(ingress *ingressprovider.Ingress) *ingressprovider.Ingress {
+				ingress.HostName = "other.example.com"
+				return ingress
+			},
+			want:      singularityv1.GameServerIngressStatus{ID: "1", Hostname: "other.example.com", Backends: []string{"10.0.0.1:25565"}, BackendsHash: "hash", ObservedGeneration: 1},
+			wantEvent: "Drift",
+		},
+		{
+			name: "backends changed",
+			current: func(ingress *ingressprovider.Ingress) *ingressprovider.Ingress {
+				ingress.BackendSet = []*ingressprovider.Backend{{IP: net.ParseIP("10.0.0.2"), Port: 25565}}
+				return ingress
+			},
+			want:      singularityv1.GameServerIngressStatus{ID: "1", Hostname: "play.example.com", Backends: []string{"10.0.0.2:25565"}, ObservedGeneration: 1},
+			wantEvent: "Drift",
+		},
+		{
+			name: "options changed",
+			current: func(ingress *ingressprovider.Ingress) *ingressprovider.Ingress {
+				ingress.Options = ingressprovider.Options{Extensions: []interface{}{&tcpshield.Extension{BAC: true}}}
+				return ingress
+			},
+			want:      singularityv1.GameServerIngressStatus{ID: "1", Hostname: "play.example.com", Backends: []string{"10.0.0.1:25565"}, BackendsHash: "hash"},
+			wantEvent: "Drift",
+		},
+		{
+			// The options of the new generation weren't applied yet, so they're expected to differ
+			name:       "options of a new generation",
+			generation: 2,
+			current: func(ingress *ingressprovider.Ingress) *ingressprovider.Ingress {
+				ingress.Options = ingressprovider.Options{ProxyProtocol: true}
+				return ingress
+			},
+			want: singularityv1.GameServerIngressStatus{ID: "1", Hostname: "play.example.com", Backends: []string{"10.0.0.1:25565"}, BackendsHash: "hash", ObservedGeneration: 1},
+		},
+		{
+			name: "verified",
+			current: func(ingress *ingressprovider.Ingress) *ingressprovider.Ingress {
+				ingress.Verified = true
+				return ingress
+			},
+			want:      singularityv1.GameServerIngressStatus{ID: "1", Hostname: "play.example.com", Backends: []string{"10.0.0.1:25565"}, BackendsHash: "hash", ObservedGeneration: 1, Verified: true},
+			wantEvent: "Verified",
+		},
+		{
+			name:     "ingress of another provider",
+			provider: "other",
+			current:  func(*ingressprovider.Ingress) *ingressprovider.Ingress { return nil },
+			want:     singularityv1.GameServerIngressStatus{ID: "1", Hostname: "play.example.com", Backends: []string{"10.0.0.1:25565"}, BackendsHash: "hash", ObservedGeneration: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			generation := tt.generation
+			if generation == 0 {
+				generation = 1
+			}
+			ingress := &singularityv1.GameServerIngress{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "lobby", Generation: generation},
+				Spec:       singularityv1.GameServerIngressSpec{Hostname: "play.example.com", Provider: "fake"},
+				Status: singularityv1.GameServerIngressStatus{
+					ID:                 "1",
+					Hostname:           "play.example.com",
+					Backends:           []string{"10.0.0.1:25565"},
+					BackendsHash:       "hash",
+					ObservedGeneration: 1,
+				},
+			}
+			if tt.provider != "" {
+				ingress.Spec.Provider = tt.provider
+			}
+			provider := &fakeProvider{}
+			if current := tt.current(inSync()); current != nil {
+				provider.ingresses = []*ingressprovider.Ingress{current}
+			}
+			r, recorder := newReconciler(ingress, map[string]ingressprovider.Provider{"fake": provider})
+
+			if err := r.reconcileDrift(ctx, "fake", provider); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Get(ctx, client.ObjectKeyFromObject(ingress), ingress); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ingress.Status, tt.want) {
+				t.Errorf("status = %+v, want %+v", ingress.Status, tt.want)
+			}
+
+			if tt.wantEvent != "" {
+				eventType := v1.EventTypeWarning
+				if tt.wantEvent == "Verified" {
+					eventType = v1.EventTypeNormal
+				}
+				expectEvent(t, recorder, eventType, tt.wantEvent)
+			} else if len(recorder.Events) > 0 {
+				t.Errorf("event %q recorded, want none", <-recorder.Events)
+			}
+		})
+	}
+}
+
+func TestHashBackends(t *testing.T) {
+	backend := func(name, ip string) *ingressprovider.Backend {
+		return &ingressprovider.Backend{
+			IP:       net.ParseIP(ip),
+			Port:     25565,
+			Protocol: ingressprovider.ProtocolTCP,
+			Weight:   ingressprovider.DefaultWeight,
+			Name:     name,
+		}
+	}
+	a, b := backend("a", "10.0.0.1"), backend("b", "10.0.0.2")
+	hash := hashBackends([]*ingressprovider.Backend{a, b})
+
+	if got := hashBackends([]*ingressprovider.Backend{b, a}); got != hash {
+		t.Errorf("hash of reordered backends = %s, want %s", got, hash)
+	}
+	if got := hashBackends(nil); got != hashBackends([]*ingressprovider.Backend{}) {
+		t.Errorf("hash of nil backends = %s, want the one of no backends", got)
+	}
+
+	changes := map[string]func(backend *ingressprovider.Backend){
+		"address":  func(backend *ingressprovider.Backend) { backend.IP = net.ParseIP("10.0.0.3") },
+		"port":     func(backend *ingressprovider.Backend) { backend.Port = 25566 },
+		"protocol": func(backend *ingressprovider.Backend) { backend.Protocol = ingressprovider.ProtocolUDP },
+		"weight":   func(backend *ingressprovider.Backend) { backend.Weight = 2 },
+		"draining": func(backend *ingressprovider.Backend) { backend.Draining = true },
+		"metadata": func(backend *ingressprovider.Backend) { backend.Metadata = map[string]string{"players": "1"} },
+	}
+	for name, change := range changes {
+		changed := *b
+		change(&changed)
+		if got := hashBackends([]*ingressprovider.Backend{a, &changed}); got == hash {
+			t.Errorf("hash of backends with changed %s = %s, want a different hash", name, got)
+		}
+	}
+}
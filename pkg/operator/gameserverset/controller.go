@@ -179,11 +179,10 @@ func computeReconciliationAction(list []*singularityv1.GameServer, targetReplica
 		case singularityv1.GameServerStateRequestReady,
 			singularityv1.GameServerStateReady:
 			handleGameServerUp(gs)
-		//case singularityv1.GameServerStateReserved:
-		//	handleGameServerUp(gs)
 
 		// GameServerStateShutdown - already handled above
 		// GameServerStateAllocated - already handled above
+		// GameServerStateReserved - already handled above
 		case singularityv1.GameServerStateError, singularityv1.GameServerStateUnhealthy:
 			scheduleDeletion(gs)
 		default:
@@ -269,6 +268,7 @@ func (r *Reconciler) updateStatus(ctx context.Context, gsSet *singularityv1.Game
 	for _, gs := range list {
 		if gs.IsBeingDeleted() {
 			status.ShutdownReplicas++
+			status.ShutdownInstances += gs.Status.Instances
 
 			// Don't count replicas that are being deleted
 			continue
@@ -280,9 +280,13 @@ func (r *Reconciler) updateStatus(ctx context.Context, gsSet *singularityv1.Game
 			status.ReadyReplicas++
 		case singularityv1.GameServerStateAllocated:
 			status.AllocatedReplicas++
+		case singularityv1.GameServerStateReserved:
+			status.ReservedReplicas++
 		}
 
-		// TODO: Instances
+		status.Instances += gs.Status.Instances
+		status.ReadyInstances += gs.Status.ReadyInstances
+		status.AllocatedInstances += gs.Status.AllocatedInstances
 	}
 
 	if gsSet.Status != status {
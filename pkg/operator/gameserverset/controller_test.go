@@ -0,0 +1,67 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package gameserverset
+
+import (
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestComputeReconciliationActionReserved(t *testing.T) {
+	gameServer := func(name string, state singularityv1.GameServerState) *singularityv1.GameServer {
+		return &singularityv1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     singularityv1.GameServerStatus{State: state},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		target     int
+		wantAdd    int
+		wantDelete int
+	}{
+		{name: "scale down keeps reserved and allocated servers", target: 0, wantDelete: 2},
+		{name: "reserved servers count towards the replicas", target: 4, wantDelete: 0},
+		{name: "scale up around reserved servers", target: 6, wantAdd: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := []*singularityv1.GameServer{
+				gameServer("reserved", singularityv1.GameServerStateReserved),
+				gameServer("allocated", singularityv1.GameServerStateAllocated),
+				gameServer("ready-a", singularityv1.GameServerStateReady),
+				gameServer("ready-b", singularityv1.GameServerStateReady),
+			}
+
+			add, toDelete, _ := computeReconciliationAction(list, tt.target)
+			if add != tt.wantAdd || len(toDelete) != tt.wantDelete {
+				t.Errorf("computeReconciliationAction() = add %d, delete %d, want add %d, delete %d",
+					add, len(toDelete), tt.wantAdd, tt.wantDelete)
+			}
+			for _, gs := range toDelete {
+				if gs.Status.State == singularityv1.GameServerStateReserved || gs.Status.State == singularityv1.GameServerStateAllocated {
+					t.Errorf("deleted %s gameserver %s", gs.Status.State, gs.ObjectMeta.Name)
+				}
+			}
+		})
+	}
+}
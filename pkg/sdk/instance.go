@@ -0,0 +1,100 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package sdk
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GameServerInstance returns the current GameServerInstance with the id
+func (s *SDK) GameServerInstance(ctx context.Context, id int) (*singularityv1.GameServerInstance, error) {
+	gsInstance := &singularityv1.GameServerInstance{}
+	key := client.ObjectKey{
+		Namespace: s.key.Namespace,
+		Name:      singularityv1.GameServerInstanceName(s.key.Name, id),
+	}
+	if err := s.client.Get(ctx, key, gsInstance); err != nil {
+		return nil, errors.Wrapf(err, "error retrieving gameserverinstance %s", key.Name)
+	}
+
+	return gsInstance, nil
+}
+
+// SetInstanceState changes the state of the GameServerInstance with the id, e.g. to Ready once its game ended
+func (s *SDK) SetInstanceState(ctx context.Context, id int, state singularityv1.GameServerInstanceState) error {
+	return s.updateInstanceStatus(ctx, id, func(gsInstance *singularityv1.GameServerInstance) error {
+		gsInstance.Status.State = state
+		return nil
+	})
+}
+
+// AddPlayer adds a player to the GameServerInstance with the id, failing if it is at capacity
+func (s *SDK) AddPlayer(ctx context.Context, id int, player string) error {
+	return s.updateInstanceStatus(ctx, id, func(gsInstance *singularityv1.GameServerInstance) error {
+		return gsInstance.AddPlayer(player)
+	})
+}
+
+// RemovePlayer removes a player from the GameServerInstance with the id
+func (s *SDK) RemovePlayer(ctx context.Context, id int, player string) error {
+	return s.updateInstanceStatus(ctx, id, func(gsInstance *singularityv1.GameServerInstance) error {
+		gsInstance.RemovePlayer(player)
+		return nil
+	})
+}
+
+// UpdateInstanceCounter adds delta to the named counter of the GameServerInstance with the id
+func (s *SDK) UpdateInstanceCounter(ctx context.Context, id int, name string, delta int64) error {
+	return s.updateInstanceStatus(ctx, id, func(gsInstance *singularityv1.GameServerInstance) error {
+		return gsInstance.UpdateCounter(name, delta)
+	})
+}
+
+// AppendInstanceList adds the values to the named list of the GameServerInstance with the id
+func (s *SDK) AppendInstanceList(ctx context.Context, id int, name string, values ...string) error {
+	return s.updateInstanceStatus(ctx, id, func(gsInstance *singularityv1.GameServerInstance) error {
+		return gsInstance.AppendList(name, values...)
+	})
+}
+
+// RemoveInstanceList removes the values from the named list of the GameServerInstance with the id
+func (s *SDK) RemoveInstanceList(ctx context.Context, id int, name string, values ...string) error {
+	return s.updateInstanceStatus(ctx, id, func(gsInstance *singularityv1.GameServerInstance) error {
+		return gsInstance.RemoveList(name, values...)
+	})
+}
+
+// updateInstanceStatus applies mutate to the status of the latest GameServerInstance, retrying on conflict
+func (s *SDK) updateInstanceStatus(ctx context.Context, id int, mutate func(gsInstance *singularityv1.GameServerInstance) error) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		gsInstance, err := s.GameServerInstance(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err = mutate(gsInstance); err != nil {
+			return err
+		}
+		return s.client.Status().Update(ctx, gsInstance)
+	})
+}
@@ -0,0 +1,203 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package sdk
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"github.com/pkg/errors"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	"io"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+	"sync"
+)
+
+var ErrorNoGameServer = errors.New("file does not contain a gameserver")
+
+// NewLocal returns an SDK for the GameServer in the YAML file, instead of one running in a cluster.
+// The file may contain GameServerInstances of the GameServer as separate documents.
+// Every change is written back to the file, so servers can be developed without a cluster.
+func NewLocal(path string) (*SDK, error) {
+	scheme := runtime.NewScheme()
+	if err := singularityv1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+
+	objects, err := readObjects(scheme, path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", path)
+	}
+
+	var key *client.ObjectKey
+	for _, obj := range objects {
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(metav1.NamespaceDefault)
+		}
+		if _, ok := obj.(*singularityv1.GameServer); ok && key == nil {
+			k := client.ObjectKeyFromObject(obj)
+			key = &k
+		}
+	}
+	if key == nil {
+		return nil, errors.Wrap(ErrorNoGameServer, path)
+	}
+
+	c := &localClient{
+		WithWatch: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+		path:      path,
+	}
+
+	return NewWithClient(c, key.Namespace, key.Name), nil
+}
+
+// readObjects decodes the GameServers and GameServerInstances of the YAML file
+func readObjects(scheme *runtime.Scheme, path string) ([]client.Object, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	decoder := serializer.NewCodecFactory(scheme).UniversalDeserializer()
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(f))
+
+	var objects []client.Object
+	for {
+		doc, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, err
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+
+		obj, _, err := decoder.Decode(doc, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		switch obj := obj.(type) {
+		case *singularityv1.GameServer, *singularityv1.GameServerInstance:
+			objects = append(objects, obj.(client.Object))
+		default:
+			return nil, errors.Errorf("unsupported object %s", obj.GetObjectKind().GroupVersionKind())
+		}
+	}
+}
+
+// localClient is a fake client, which writes its objects back to the file after every change
+type localClient struct {
+	client.WithWatch
+	path string
+	mu   sync.Mutex
+}
+
+func (c *localClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if err := c.WithWatch.Update(ctx, obj, opts...); err != nil {
+		return err
+	}
+	return c.save(ctx)
+}
+
+func (c *localClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if err := c.WithWatch.Patch(ctx, obj, patch, opts...); err != nil {
+		return err
+	}
+	return c.save(ctx)
+}
+
+func (c *localClient) Status() client.StatusWriter {
+	return &localStatusWriter{StatusWriter: c.WithWatch.Status(), client: c}
+}
+
+// save writes all objects to the file, in the same format they are read in
+func (c *localClient) save(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	gsList := &singularityv1.GameServerList{}
+	if err := c.List(ctx, gsList); err != nil {
+		return err
+	}
+	gsInstanceList := &singularityv1.GameServerInstanceList{}
+	if err := c.List(ctx, gsInstanceList); err != nil {
+		return err
+	}
+
+	var objects []client.Object
+	for i := range gsList.Items {
+		gsList.Items[i].TypeMeta = metav1.TypeMeta{APIVersion: singularityv1.GroupVersion.String(), Kind: "GameServer"}
+		objects = append(objects, &gsList.Items[i])
+	}
+	for i := range gsInstanceList.Items {
+		gsInstanceList.Items[i].TypeMeta = metav1.TypeMeta{APIVersion: singularityv1.GroupVersion.String(), Kind: "GameServerInstance"}
+		objects = append(objects, &gsInstanceList.Items[i])
+	}
+
+	var buf bytes.Buffer
+	for i, obj := range objects {
+		// The resource version is only meaningful to the fake client
+		obj.SetResourceVersion("")
+
+		doc, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		buf.Write(doc)
+	}
+
+	if err := os.WriteFile(c.path, buf.Bytes(), 0644); err != nil {
+		return errors.Wrapf(err, "error writing %s", c.path)
+	}
+
+	return nil
+}
+
+type localStatusWriter struct {
+	client.StatusWriter
+	client *localClient
+}
+
+func (w *localStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if err := w.StatusWriter.Update(ctx, obj, opts...); err != nil {
+		return err
+	}
+	return w.client.save(ctx)
+}
+
+func (w *localStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if err := w.StatusWriter.Patch(ctx, obj, patch, opts...); err != nil {
+		return err
+	}
+	return w.client.save(ctx)
+}
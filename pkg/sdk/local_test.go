@@ -0,0 +1,144 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package sdk
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const localGameServer = `apiVersion: singularity.innit.gg/v1
+kind: GameServer
+metadata:
+  name: lobby
+spec:
+  instances: 1
+status:
+  state: Starting
+---
+apiVersion: singularity.innit.gg/v1
+kind: GameServerInstance
+metadata:
+  name: lobby-0
+spec:
+  capacity: 2
+status:
+  state: Ready
+`
+
+func TestNewLocal(t *testing.T) {
+	ctx := context.Background()
+	path := writeLocal(t, localGameServer)
+
+	s, err := NewLocal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Ready(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.SetLabel(ctx, "map", "castle"); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.AddPlayer(ctx, 0, "steve"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every change is written back to the file, so a new SDK reads them again
+	s, err = NewLocal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs, err := s.GameServer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gs.ObjectMeta.Namespace != "default" {
+		t.Errorf("namespace = %q, want default", gs.ObjectMeta.Namespace)
+	}
+	if gs.Status.State != singularityv1.GameServerStateRequestReady {
+		t.Errorf("state = %s, want %s", gs.Status.State, singularityv1.GameServerStateRequestReady)
+	}
+	if label := gs.ObjectMeta.Labels[LabelPrefix+"map"]; label != "castle" {
+		t.Errorf("label = %q, want castle", label)
+	}
+	gsInstance, err := s.GameServerInstance(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gsInstance.Status.Players, []string{"steve"}) {
+		t.Errorf("players = %v, want [steve]", gsInstance.Status.Players)
+	}
+}
+
+func TestNewLocalErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr error
+	}{
+		{
+			name: "only instances",
+			content: `apiVersion: singularity.innit.gg/v1
+kind: GameServerInstance
+metadata:
+  name: lobby-0
+`,
+			wantErr: ErrorNoGameServer,
+		},
+		{name: "empty file", wantErr: ErrorNoGameServer},
+		{
+			name: "unsupported object",
+			content: `apiVersion: singularity.innit.gg/v1
+kind: Fleet
+metadata:
+  name: lobby
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewLocal(writeLocal(t, tt.content))
+			if err == nil {
+				t.Fatal("NewLocal() error = nil, want an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewLocal() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// writeLocal writes the content to a temporary file and returns its path
+func writeLocal(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "gameserver.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
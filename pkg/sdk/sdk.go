@@ -0,0 +1,266 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package sdk is used by game server processes to manage their own GameServer and GameServerInstances.
+// It authenticates with the ServiceAccount of the GameServer, which is only allowed to access its own resources.
+package sdk
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"innit.gg/singularity/pkg/apis/singularity"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/util/retry"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"time"
+)
+
+const (
+	// LabelPrefix is prepended to labels and annotations set through the SDK,
+	// so that they can't collide with the ones managed by singularity
+	LabelPrefix = singularity.GroupName + "/sdk-"
+
+	// watchRetryInterval is the time to wait before a closed watch is re-established
+	watchRetryInterval = time.Second
+)
+
+var ErrorMissingEnv = errors.New("missing environment variable")
+
+// SDK manages the GameServer the process is running in
+type SDK struct {
+	client client.WithWatch
+	key    client.ObjectKey
+}
+
+// New returns an SDK for the GameServer of the Pod it's running in, using the in-cluster configuration
+func New() (*SDK, error) {
+	name := os.Getenv(singularityv1.GameServerEnvName)
+	if name == "" {
+		return nil, errors.Wrap(ErrorMissingEnv, singularityv1.GameServerEnvName)
+	}
+	namespace := os.Getenv(singularityv1.GameServerEnvNamespace)
+	if namespace == "" {
+		return nil, errors.Wrap(ErrorMissingEnv, singularityv1.GameServerEnvNamespace)
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "error loading kubernetes config")
+	}
+
+	scheme := runtime.NewScheme()
+	if err = singularityv1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+
+	c, err := client.NewWithWatch(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating kubernetes client")
+	}
+
+	return NewWithClient(c, namespace, name), nil
+}
+
+// NewWithClient returns an SDK for the named GameServer using the given client, e.g. a fake one in tests
+func NewWithClient(c client.WithWatch, namespace, name string) *SDK {
+	return &SDK{
+		client: c,
+		key:    client.ObjectKey{Namespace: namespace, Name: name},
+	}
+}
+
+// GameServer returns the current GameServer
+func (s *SDK) GameServer(ctx context.Context) (*singularityv1.GameServer, error) {
+	gs := &singularityv1.GameServer{}
+	if err := s.client.Get(ctx, s.key, gs); err != nil {
+		return nil, errors.Wrapf(err, "error retrieving gameserver %s", s.key.Name)
+	}
+
+	return gs, nil
+}
+
+// Ready requests the GameServer to be Ready, either after starting or to be reused after being Allocated
+func (s *SDK) Ready(ctx context.Context) error {
+	return s.updateStatus(ctx, func(gs *singularityv1.GameServer) error {
+		return gs.RequestReady()
+	})
+}
+
+// Allocate marks the GameServer as Allocated, if it isn't already
+func (s *SDK) Allocate(ctx context.Context) error {
+	return s.updateStatus(ctx, func(gs *singularityv1.GameServer) error {
+		if gs.Status.State != singularityv1.GameServerStateAllocated {
+			gs.Allocate()
+		}
+		return nil
+	})
+}
+
+// Reserve marks the GameServer as Reserved for the duration, or indefinitely if the duration is zero
+func (s *SDK) Reserve(ctx context.Context, d time.Duration) error {
+	return s.updateStatus(ctx, func(gs *singularityv1.GameServer) error {
+		gs.Reserve(d)
+		return nil
+	})
+}
+
+// Shutdown shuts the GameServer down, deleting its Pod
+func (s *SDK) Shutdown(ctx context.Context) error {
+	return s.updateStatus(ctx, func(gs *singularityv1.GameServer) error {
+		gs.Status.State = singularityv1.GameServerStateShutdown
+		return nil
+	})
+}
+
+// Health reports the GameServer as healthy. It should be called once per health check period.
+func (s *SDK) Health(ctx context.Context) error {
+	return s.updateStatus(ctx, func(gs *singularityv1.GameServer) error {
+		now := metav1.Now()
+		gs.Status.LastHealthy = &now
+		return nil
+	})
+}
+
+// SetLabel sets a label on the GameServer, the key is prefixed with LabelPrefix
+func (s *SDK) SetLabel(ctx context.Context, key, value string) error {
+	return s.update(ctx, func(gs *singularityv1.GameServer) {
+		if gs.ObjectMeta.Labels == nil {
+			gs.ObjectMeta.Labels = make(map[string]string, 1)
+		}
+		gs.ObjectMeta.Labels[LabelPrefix+key] = value
+	})
+}
+
+// SetAnnotation sets an annotation on the GameServer, the key is prefixed with LabelPrefix
+func (s *SDK) SetAnnotation(ctx context.Context, key, value string) error {
+	return s.update(ctx, func(gs *singularityv1.GameServer) {
+		if gs.ObjectMeta.Annotations == nil {
+			gs.ObjectMeta.Annotations = make(map[string]string, 1)
+		}
+		gs.ObjectMeta.Annotations[LabelPrefix+key] = value
+	})
+}
+
+// UpdateCounter adds delta to the named counter of the GameServer
+func (s *SDK) UpdateCounter(ctx context.Context, name string, delta int64) error {
+	return s.updateStatus(ctx, func(gs *singularityv1.GameServer) error {
+		return gs.UpdateCounter(name, delta)
+	})
+}
+
+// AppendList adds the values to the named list of the GameServer
+func (s *SDK) AppendList(ctx context.Context, name string, values ...string) error {
+	return s.updateStatus(ctx, func(gs *singularityv1.GameServer) error {
+		return gs.AppendList(name, values...)
+	})
+}
+
+// RemoveList removes the values from the named list of the GameServer
+func (s *SDK) RemoveList(ctx context.Context, name string, values ...string) error {
+	return s.updateStatus(ctx, func(gs *singularityv1.GameServer) error {
+		return gs.RemoveList(name, values...)
+	})
+}
+
+// WatchGameServer calls f with the current GameServer, and again every time it changes.
+// It blocks until the context is cancelled.
+func (s *SDK) WatchGameServer(ctx context.Context, f func(gs *singularityv1.GameServer)) error {
+	for {
+		gs, err := s.GameServer(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		// The watch starts after the current GameServer, which is reported first. Not every client sends the
+		// current object when a watch starts, e.g. the local one, and changes between two watches aren't lost.
+		list := &singularityv1.GameServerList{}
+		w, err := s.client.Watch(ctx, list, client.InNamespace(s.key.Namespace), client.MatchingFields{"metadata.name": s.key.Name},
+			&client.ListOptions{Raw: &metav1.ListOptions{ResourceVersion: gs.ObjectMeta.ResourceVersion}})
+		if err != nil {
+			return errors.Wrapf(err, "error watching gameserver %s", s.key.Name)
+		}
+
+		f(gs)
+		if done := s.watchEvents(ctx, w, f); done {
+			return nil
+		}
+
+		// The watch was closed by the API server, re-establish it unless we're done.
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(watchRetryInterval):
+		}
+	}
+}
+
+// watchEvents calls f for every GameServer event of the watch, until either it or the context is closed
+func (s *SDK) watchEvents(ctx context.Context, w watch.Interface, f func(gs *singularityv1.GameServer)) bool {
+	defer w.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return true
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return false
+			}
+			// Not every client supports field selectors on watches
+			if gs, ok := event.Object.(*singularityv1.GameServer); ok && gs.ObjectMeta.Name == s.key.Name {
+				f(gs)
+			}
+		}
+	}
+}
+
+// update applies mutate to the latest GameServer, retrying on conflict
+func (s *SDK) update(ctx context.Context, mutate func(gs *singularityv1.GameServer)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		gs, err := s.GameServer(ctx)
+		if err != nil {
+			return err
+		}
+
+		mutate(gs)
+		return s.client.Update(ctx, gs)
+	})
+}
+
+// updateStatus applies mutate to the status of the latest GameServer, retrying on conflict
+func (s *SDK) updateStatus(ctx context.Context, mutate func(gs *singularityv1.GameServer) error) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		gs, err := s.GameServer(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err = mutate(gs); err != nil {
+			return err
+		}
+		return s.client.Status().Update(ctx, gs)
+	})
+}
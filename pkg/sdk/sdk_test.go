@@ -0,0 +1,343 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package sdk
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+	"time"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(singularityv1.AddToScheme(scheme))
+}
+
+// newSDK returns an SDK of the GameServer lobby, backed by a fake client containing the objects
+func newSDK(objs ...client.Object) *SDK {
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	return NewWithClient(c, "default", "lobby")
+}
+
+// gameServer returns the GameServer lobby in the state
+func gameServer(state singularityv1.GameServerState) *singularityv1.GameServer {
+	return &singularityv1.GameServer{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "lobby"},
+		Status:     singularityv1.GameServerStatus{State: state},
+	}
+}
+
+// mustGameServer returns the current GameServer of the SDK, failing the test if it can't be retrieved
+func mustGameServer(t *testing.T, s *SDK) *singularityv1.GameServer {
+	t.Helper()
+
+	gs, err := s.GameServer(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return gs
+}
+
+func TestReady(t *testing.T) {
+	tests := []struct {
+		state     singularityv1.GameServerState
+		wantState singularityv1.GameServerState
+		wantErr   error
+	}{
+		{state: singularityv1.GameServerStateStarting, wantState: singularityv1.GameServerStateRequestReady},
+		{state: singularityv1.GameServerStateAllocated, wantState: singularityv1.GameServerStateRequestReady},
+		{state: singularityv1.GameServerStateReady, wantState: singularityv1.GameServerStateReady},
+		{state: singularityv1.GameServerStateShutdown, wantState: singularityv1.GameServerStateShutdown, wantErr: singularityv1.ErrorInvalidStateTransition},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.state), func(t *testing.T) {
+			s := newSDK(gameServer(tt.state))
+
+			if err := s.Ready(context.Background()); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Ready() error = %v, want %v", err, tt.wantErr)
+			}
+
+			gs := mustGameServer(t, s)
+			if gs.Status.State != tt.wantState {
+				t.Errorf("state = %s, want %s", gs.Status.State, tt.wantState)
+			}
+			if tt.wantState == singularityv1.GameServerStateRequestReady && gs.Status.ReadyRequestedFrom != tt.state {
+				t.Errorf("readyRequestedFrom = %s, want %s", gs.Status.ReadyRequestedFrom, tt.state)
+			}
+		})
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	ctx := context.Background()
+	s := newSDK(gameServer(singularityv1.GameServerStateReady))
+
+	if err := s.Allocate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Allocating an Allocated server again doesn't count as another allocation
+	if err := s.Allocate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	gs := mustGameServer(t, s)
+	if gs.Status.State != singularityv1.GameServerStateAllocated || gs.Status.Allocations != 1 {
+		t.Errorf("state = %s after %d allocations, want Allocated after 1", gs.Status.State, gs.Status.Allocations)
+	}
+}
+
+func TestReserve(t *testing.T) {
+	ctx := context.Background()
+	s := newSDK(gameServer(singularityv1.GameServerStateReady))
+
+	if err := s.Reserve(ctx, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	gs := mustGameServer(t, s)
+	if gs.Status.State != singularityv1.GameServerStateReserved || gs.Status.ReservedUntil == nil ||
+		time.Until(gs.Status.ReservedUntil.Time) > time.Minute {
+		t.Errorf("Reserve(1m) = %s until %v", gs.Status.State, gs.Status.ReservedUntil)
+	}
+
+	if err := s.Reserve(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	gs = mustGameServer(t, s)
+	if gs.Status.State != singularityv1.GameServerStateReserved || gs.Status.ReservedUntil != nil {
+		t.Errorf("Reserve(0) = %s until %v, want indefinitely", gs.Status.State, gs.Status.ReservedUntil)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	s := newSDK(gameServer(singularityv1.GameServerStateAllocated))
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if gs := mustGameServer(t, s); gs.Status.State != singularityv1.GameServerStateShutdown {
+		t.Errorf("state = %s, want Shutdown", gs.Status.State)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	s := newSDK(gameServer(singularityv1.GameServerStateReady))
+
+	before := time.Now().Add(-time.Second)
+	if err := s.Health(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	gs := mustGameServer(t, s)
+	if gs.Status.LastHealthy == nil || gs.Status.LastHealthy.Time.Before(before) {
+		t.Errorf("lastHealthy = %v, want now", gs.Status.LastHealthy)
+	}
+	if gs.Status.State != singularityv1.GameServerStateReady {
+		t.Errorf("state = %s, want it to be unchanged", gs.Status.State)
+	}
+}
+
+func TestSetLabelAndAnnotation(t *testing.T) {
+	ctx := context.Background()
+	gs := gameServer(singularityv1.GameServerStateReady)
+	gs.ObjectMeta.Labels = map[string]string{singularityv1.FleetNameLabel: "lobby"}
+	s := newSDK(gs)
+
+	if err := s.SetLabel(ctx, "map", "castle"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetAnnotation(ctx, "motd", "Welcome!"); err != nil {
+		t.Fatal(err)
+	}
+
+	gs = mustGameServer(t, s)
+	wantLabels := map[string]string{singularityv1.FleetNameLabel: "lobby", LabelPrefix + "map": "castle"}
+	if !reflect.DeepEqual(gs.ObjectMeta.Labels, wantLabels) {
+		t.Errorf("labels = %v, want %v", gs.ObjectMeta.Labels, wantLabels)
+	}
+	wantAnnotations := map[string]string{LabelPrefix + "motd": "Welcome!"}
+	if !reflect.DeepEqual(gs.ObjectMeta.Annotations, wantAnnotations) {
+		t.Errorf("annotations = %v, want %v", gs.ObjectMeta.Annotations, wantAnnotations)
+	}
+}
+
+func TestCountersAndLists(t *testing.T) {
+	ctx := context.Background()
+	gs := gameServer(singularityv1.GameServerStateReady)
+	gs.Spec.CountersAndLists = singularityv1.CountersAndLists{
+		Counters: map[string]singularityv1.Counter{"rooms": {Count: 1, Capacity: 2}},
+		Lists:    map[string]singularityv1.List{"spectators": {Capacity: 2}},
+	}
+	s := newSDK(gs)
+
+	if err := s.UpdateCounter(ctx, "rooms", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateCounter(ctx, "rooms", 1); !errors.Is(err, singularityv1.ErrorCounterOutOfRange) {
+		t.Errorf("UpdateCounter() beyond capacity error = %v, want %v", err, singularityv1.ErrorCounterOutOfRange)
+	}
+	if err := s.UpdateCounter(ctx, "players", 1); !errors.Is(err, singularityv1.ErrorCounterNotFound) {
+		t.Errorf("UpdateCounter() of a missing counter error = %v, want %v", err, singularityv1.ErrorCounterNotFound)
+	}
+
+	if err := s.AppendList(ctx, "spectators", "alex", "steve"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AppendList(ctx, "spectators", "herobrine"); !errors.Is(err, singularityv1.ErrorListAtCapacity) {
+		t.Errorf("AppendList() beyond capacity error = %v, want %v", err, singularityv1.ErrorListAtCapacity)
+	}
+	if err := s.RemoveList(ctx, "spectators", "alex"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RemoveList(ctx, "players", "alex"); !errors.Is(err, singularityv1.ErrorListNotFound) {
+		t.Errorf("RemoveList() of a missing list error = %v, want %v", err, singularityv1.ErrorListNotFound)
+	}
+
+	current := mustGameServer(t, s).CountersAndLists()
+	if count := current.Counters["rooms"].Count; count != 2 {
+		t.Errorf("rooms = %d, want 2", count)
+	}
+	if values := current.Lists["spectators"].Values; !reflect.DeepEqual(values, []string{"steve"}) {
+		t.Errorf("spectators = %v, want [steve]", values)
+	}
+}
+
+func TestInstances(t *testing.T) {
+	ctx := context.Background()
+	gsInstance := &singularityv1.GameServerInstance{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: singularityv1.GameServerInstanceName("lobby", 0)},
+		Spec: singularityv1.GameServerInstanceSpec{
+			Capacity:         1,
+			CountersAndLists: singularityv1.CountersAndLists{Counters: map[string]singularityv1.Counter{"rounds": {Capacity: 3}}},
+		},
+		Status: singularityv1.GameServerInstanceStatus{State: singularityv1.GameServerInstanceStateReady},
+	}
+	s := newSDK(gameServer(singularityv1.GameServerStateReady), gsInstance)
+
+	if err := s.AddPlayer(ctx, 0, "steve"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddPlayer(ctx, 0, "alex"); err == nil {
+		t.Error("AddPlayer() beyond capacity succeeded")
+	}
+	if err := s.UpdateInstanceCounter(ctx, 0, "rounds", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetInstanceState(ctx, 0, singularityv1.GameServerInstanceStateAllocated); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddPlayer(ctx, 1, "steve"); err == nil {
+		t.Error("AddPlayer() to a missing instance succeeded")
+	}
+
+	got, err := s.GameServerInstance(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.State != singularityv1.GameServerInstanceStateAllocated {
+		t.Errorf("state = %s, want Allocated", got.Status.State)
+	}
+	if !reflect.DeepEqual(got.Status.Players, []string{"steve"}) {
+		t.Errorf("players = %v, want [steve]", got.Status.Players)
+	}
+	if count := got.CountersAndLists().Counters["rounds"].Count; count != 1 {
+		t.Errorf("rounds = %d, want 1", count)
+	}
+
+	if err = s.RemovePlayer(ctx, 0, "steve"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = s.GameServerInstance(ctx, 0); err != nil || len(got.Status.Players) != 0 {
+		t.Errorf("players = %v, want none: %v", got.Status.Players, err)
+	}
+}
+
+func TestGameServerNotFound(t *testing.T) {
+	s := newSDK()
+
+	if err := s.Ready(context.Background()); err == nil {
+		t.Error("Ready() of a missing GameServer succeeded")
+	}
+	if err := s.SetLabel(context.Background(), "map", "castle"); err == nil {
+		t.Error("SetLabel() of a missing GameServer succeeded")
+	}
+}
+
+func TestWatchGameServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	other := gameServer(singularityv1.GameServerStateReady)
+	other.ObjectMeta.Name = "other"
+	s := newSDK(gameServer(singularityv1.GameServerStateReady), other)
+
+	states := make(chan singularityv1.GameServerState, 10)
+	done := make(chan error)
+	go func() {
+		done <- s.WatchGameServer(ctx, func(gs *singularityv1.GameServer) {
+			states <- gs.Status.State
+		})
+	}()
+
+	// The current GameServer is reported first, changes of other GameServers aren't reported
+	expectState(t, states, singularityv1.GameServerStateReady)
+	other.Status.State = singularityv1.GameServerStateAllocated
+	if err := s.client.Status().Update(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Allocate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expectState(t, states, singularityv1.GameServerStateAllocated)
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("WatchGameServer() error = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("WatchGameServer() didn't return after the context was cancelled")
+	}
+	if len(states) > 0 {
+		t.Errorf("unexpected state %s", <-states)
+	}
+}
+
+// expectState fails the test unless the next state reported to the watch is the wanted one
+func expectState(t *testing.T, states <-chan singularityv1.GameServerState, want singularityv1.GameServerState) {
+	t.Helper()
+
+	select {
+	case state := <-states:
+		if state != want {
+			t.Errorf("watched state = %s, want %s", state, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no state watched, want %s", want)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package sdkserver
+
+import (
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	"innit.gg/singularity/pkg/sdkserver/sdkpb"
+)
+
+// gameServerToProto converts the GameServer to its sdkpb representation
+func gameServerToProto(gs *singularityv1.GameServer) *sdkpb.GameServer {
+	countersAndLists := gs.CountersAndLists()
+	res := &sdkpb.GameServer{
+		Name:        gs.ObjectMeta.Name,
+		Namespace:   gs.ObjectMeta.Namespace,
+		Labels:      gs.ObjectMeta.Labels,
+		Annotations: gs.ObjectMeta.Annotations,
+		State:       string(gs.Status.State),
+		Address:     gs.Status.Address,
+		NodeName:    gs.Status.NodeName,
+		Allocations: gs.Status.Allocations,
+		Counters:    countersToProto(countersAndLists.Counters),
+		Lists:       listsToProto(countersAndLists.Lists),
+	}
+	for _, port := range gs.Status.Ports {
+		res.Ports = append(res.Ports, &sdkpb.Port{Name: port.Name, Port: port.Port})
+	}
+
+	return res
+}
+
+// instanceToProto converts the GameServerInstance to its sdkpb representation
+func instanceToProto(gsInstance *singularityv1.GameServerInstance) *sdkpb.GameServerInstance {
+	countersAndLists := gsInstance.CountersAndLists()
+	return &sdkpb.GameServerInstance{
+		Name:        gsInstance.ObjectMeta.Name,
+		Labels:      gsInstance.ObjectMeta.Labels,
+		Annotations: gsInstance.ObjectMeta.Annotations,
+		State:       string(gsInstance.Status.State),
+		Map:         gsInstance.Spec.Map,
+		Capacity:    gsInstance.Spec.Capacity,
+		Players:     gsInstance.Status.Players,
+		Counters:    countersToProto(countersAndLists.Counters),
+		Lists:       listsToProto(countersAndLists.Lists),
+	}
+}
+
+func countersToProto(counters map[string]singularityv1.Counter) map[string]*sdkpb.Counter {
+	res := make(map[string]*sdkpb.Counter, len(counters))
+	for name, counter := range counters {
+		res[name] = &sdkpb.Counter{Count: counter.Count, Capacity: counter.Capacity}
+	}
+
+	return res
+}
+
+func listsToProto(lists map[string]singularityv1.List) map[string]*sdkpb.List {
+	res := make(map[string]*sdkpb.List, len(lists))
+	for name, list := range lists {
+		res[name] = &sdkpb.List{Capacity: list.Capacity, Values: list.Values}
+	}
+
+	return res
+}
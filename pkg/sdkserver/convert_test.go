@@ -0,0 +1,103 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package sdkserver
+
+import (
+	"google.golang.org/protobuf/proto"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	"innit.gg/singularity/pkg/sdkserver/sdkpb"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestGameServerToProto(t *testing.T) {
+	gs := &singularityv1.GameServer{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "lobby",
+			Labels:      map[string]string{"app": "lobby"},
+			Annotations: map[string]string{"motd": "Welcome!"},
+		},
+		Spec: singularityv1.GameServerSpec{
+			CountersAndLists: singularityv1.CountersAndLists{
+				Counters: map[string]singularityv1.Counter{"rooms": {Count: 1, Capacity: 4}, "rounds": {Capacity: 3}},
+				Lists:    map[string]singularityv1.List{"spectators": {Capacity: 2}},
+			},
+		},
+		Status: singularityv1.GameServerStatus{
+			State:       singularityv1.GameServerStateAllocated,
+			Address:     "10.0.0.1",
+			NodeName:    "node-a",
+			Ports:       []singularityv1.GameServerStatusPort{{Name: "minecraft", Port: 25565}},
+			Allocations: 2,
+			// The status contains the counters and lists which changed
+			CountersAndLists: singularityv1.CountersAndLists{
+				Counters: map[string]singularityv1.Counter{"rooms": {Count: 3, Capacity: 4}},
+				Lists:    map[string]singularityv1.List{"spectators": {Capacity: 2, Values: []string{"alex"}}},
+			},
+		},
+	}
+
+	want := &sdkpb.GameServer{
+		Name:        "lobby",
+		Namespace:   "default",
+		Labels:      map[string]string{"app": "lobby"},
+		Annotations: map[string]string{"motd": "Welcome!"},
+		State:       "Allocated",
+		Address:     "10.0.0.1",
+		NodeName:    "node-a",
+		Ports:       []*sdkpb.Port{{Name: "minecraft", Port: 25565}},
+		Allocations: 2,
+		Counters:    map[string]*sdkpb.Counter{"rooms": {Count: 3, Capacity: 4}, "rounds": {Capacity: 3}},
+		Lists:       map[string]*sdkpb.List{"spectators": {Capacity: 2, Values: []string{"alex"}}},
+	}
+	if got := gameServerToProto(gs); !proto.Equal(got, want) {
+		t.Errorf("gameServerToProto() = %v, want %v", got, want)
+	}
+}
+
+func TestInstanceToProto(t *testing.T) {
+	gsInstance := &singularityv1.GameServerInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "lobby-0", Labels: map[string]string{"mode": "duels"}},
+		Spec: singularityv1.GameServerInstanceSpec{
+			Map:              "castle",
+			Capacity:         2,
+			CountersAndLists: singularityv1.CountersAndLists{Counters: map[string]singularityv1.Counter{"rounds": {Capacity: 3}}},
+		},
+		Status: singularityv1.GameServerInstanceStatus{
+			State:            singularityv1.GameServerInstanceStateAllocated,
+			Players:          []string{"steve"},
+			CountersAndLists: singularityv1.CountersAndLists{Counters: map[string]singularityv1.Counter{"rounds": {Count: 1, Capacity: 3}}},
+		},
+	}
+
+	want := &sdkpb.GameServerInstance{
+		Name:     "lobby-0",
+		Labels:   map[string]string{"mode": "duels"},
+		State:    "Allocated",
+		Map:      "castle",
+		Capacity: 2,
+		Players:  []string{"steve"},
+		Counters: map[string]*sdkpb.Counter{"rounds": {Count: 1, Capacity: 3}},
+		Lists:    map[string]*sdkpb.List{},
+	}
+	if got := instanceToProto(gsInstance); !proto.Equal(got, want) {
+		t.Errorf("instanceToProto() = %v, want %v", got, want)
+	}
+}
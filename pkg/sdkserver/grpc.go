@@ -0,0 +1,53 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package sdkserver
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// grpcError maps an SDK error to a gRPC status
+func grpcError(err error) error {
+	switch {
+	case k8serrors.IsNotFound(err),
+		errors.Is(err, singularityv1.ErrorCounterNotFound),
+		errors.Is(err, singularityv1.ErrorListNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, singularityv1.ErrorCounterOutOfRange),
+		errors.Is(err, singularityv1.ErrorListAtCapacity),
+		errors.Is(err, singularityv1.ErrorInstanceAtCapacity),
+		errors.Is(err, singularityv1.ErrorInvalidStateTransition):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case k8serrors.IsForbidden(err):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case k8serrors.IsConflict(err):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,185 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package sdkserver
+
+import (
+	"bufio"
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	"innit.gg/singularity/pkg/sdkserver/sdkpb"
+	"strconv"
+	"time"
+)
+
+// keepAliveInterval is the interval empty lines are written to idle watches, to detect closed connections
+const keepAliveInterval = 30 * time.Second
+
+// App returns the fiber app serving the SDK over HTTP.
+// Requests and responses use the JSON mapping of the sdkpb messages, an empty body is an empty message.
+func (s *Server) App() *fiber.App {
+	app := fiber.New(fiber.Config{
+		DisableStartupMessage: true,
+	})
+
+	api := app.Group("/v1")
+	api.Post("/ready", handle(s.Ready))
+	api.Post("/allocate", handle(s.Allocate))
+	api.Post("/reserve", handle(s.Reserve))
+	api.Post("/shutdown", handle(s.Shutdown))
+	api.Post("/health", handle(s.Health))
+
+	api.Get("/gameserver", handle(s.GetGameServer))
+	api.Get("/watch/gameserver", s.handleWatchGameServer)
+
+	api.Put("/metadata/label", handle(s.SetLabel))
+	api.Put("/metadata/annotation", handle(s.SetAnnotation))
+
+	api.Post("/counters/update", handle(s.UpdateCounter))
+	api.Post("/lists/append", handle(s.AppendList))
+	api.Post("/lists/remove", handle(s.RemoveList))
+
+	api.Get("/instances/:id", s.handleGetGameServerInstance)
+	api.Post("/instances/state", handle(s.SetInstanceState))
+	api.Post("/instances/players/add", handle(s.AddPlayer))
+	api.Post("/instances/players/remove", handle(s.RemovePlayer))
+
+	return app
+}
+
+// handle returns a handler calling the gRPC method with the request decoded from the body
+func handle[Req any, PReq interface {
+	*Req
+	proto.Message
+}, Res proto.Message](method func(context.Context, PReq) (Res, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := PReq(new(Req))
+		if len(c.Body()) > 0 {
+			if err := protojson.Unmarshal(c.Body(), req); err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, err.Error())
+			}
+		}
+
+		res, err := method(c.UserContext(), req)
+		if err != nil {
+			return httpError(err)
+		}
+
+		return send(c, res)
+	}
+}
+
+func (s *Server) handleGetGameServerInstance(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	res, err := s.GetGameServerInstance(c.UserContext(), &sdkpb.InstanceRequest{Instance: int32(id)})
+	if err != nil {
+		return httpError(err)
+	}
+
+	return send(c, res)
+}
+
+// handleWatchGameServer streams the GameServer as newline delimited JSON, starting with the current one.
+// The GameServer is retrieved before streaming, so that errors are still reported with a status code.
+func (s *Server) handleWatchGameServer(c *fiber.Ctx) error {
+	if _, err := s.SDK.GameServer(c.UserContext()); err != nil {
+		return httpError(grpcError(err))
+	}
+
+	c.Set(fiber.HeaderContentType, "application/x-ndjson")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		updates := make(chan *singularityv1.GameServer, 1)
+		go func() {
+			_ = s.SDK.WatchGameServer(ctx, func(gs *singularityv1.GameServer) {
+				select {
+				case updates <- gs:
+				case <-ctx.Done():
+				}
+			})
+		}()
+
+		ticker := time.NewTicker(keepAliveInterval)
+		defer ticker.Stop()
+
+		for {
+			var line []byte
+			select {
+			case gs := <-updates:
+				var err error
+				if line, err = protojson.Marshal(gameServerToProto(gs)); err != nil {
+					return
+				}
+			case <-ticker.C:
+			}
+
+			// Writing fails once the client closed the connection
+			_, _ = w.Write(append(line, '\n'))
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+// send writes the JSON encoded response
+func send(c *fiber.Ctx, res proto.Message) error {
+	body, err := protojson.Marshal(res)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(body)
+}
+
+// httpError maps the gRPC status of an error to an HTTP error
+func httpError(err error) error {
+	s := status.Convert(err)
+
+	code := fiber.StatusInternalServerError
+	switch s.Code() {
+	case codes.NotFound:
+		code = fiber.StatusNotFound
+	case codes.FailedPrecondition:
+		code = fiber.StatusPreconditionFailed
+	case codes.PermissionDenied:
+		code = fiber.StatusForbidden
+	case codes.Aborted:
+		code = fiber.StatusConflict
+	case codes.DeadlineExceeded:
+		code = fiber.StatusGatewayTimeout
+	case codes.Canceled:
+		code = fiber.StatusRequestTimeout
+	}
+
+	return fiber.NewError(code, s.Message())
+}
@@ -0,0 +1,254 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package sdkserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	"innit.gg/singularity/pkg/sdk"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTP(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		// check verifies the GameServer afterwards, if set
+		check func(t *testing.T, gs *singularityv1.GameServer)
+	}{
+		{
+			name: "ready", method: fiber.MethodPost, path: "/v1/ready", wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, gs *singularityv1.GameServer) {
+				if gs.Status.State != singularityv1.GameServerStateRequestReady {
+					t.Errorf("state = %s, want RequestReady", gs.Status.State)
+				}
+			},
+		},
+		{
+			name: "allocate", method: fiber.MethodPost, path: "/v1/allocate", wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, gs *singularityv1.GameServer) {
+				if gs.Status.State != singularityv1.GameServerStateAllocated || gs.Status.Allocations != 1 {
+					t.Errorf("state = %s after %d allocations, want Allocated after 1", gs.Status.State, gs.Status.Allocations)
+				}
+			},
+		},
+		{
+			// int64 fields are strings in the JSON mapping, but numbers are accepted as well
+			name: "reserve", method: fiber.MethodPost, path: "/v1/reserve", body: `{"seconds": 60}`, wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, gs *singularityv1.GameServer) {
+				if gs.Status.State != singularityv1.GameServerStateReserved || gs.Status.ReservedUntil == nil {
+					t.Errorf("state = %s until %v, want Reserved for a minute", gs.Status.State, gs.Status.ReservedUntil)
+				}
+			},
+		},
+		{
+			name: "shutdown", method: fiber.MethodPost, path: "/v1/shutdown", wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, gs *singularityv1.GameServer) {
+				if gs.Status.State != singularityv1.GameServerStateShutdown {
+					t.Errorf("state = %s, want Shutdown", gs.Status.State)
+				}
+			},
+		},
+		{
+			name: "health", method: fiber.MethodPost, path: "/v1/health", wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, gs *singularityv1.GameServer) {
+				if gs.Status.LastHealthy == nil {
+					t.Error("lastHealthy isn't set")
+				}
+			},
+		},
+		{
+			name: "label", method: fiber.MethodPut, path: "/v1/metadata/label", body: `{"key": "map", "value": "castle"}`, wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, gs *singularityv1.GameServer) {
+				if gs.ObjectMeta.Labels[sdk.LabelPrefix+"map"] != "castle" {
+					t.Errorf("labels = %v, want the prefixed map label", gs.ObjectMeta.Labels)
+				}
+			},
+		},
+		{
+			name: "annotation", method: fiber.MethodPut, path: "/v1/metadata/annotation", body: `{"key": "motd", "value": "Welcome!"}`, wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, gs *singularityv1.GameServer) {
+				if gs.ObjectMeta.Annotations[sdk.LabelPrefix+"motd"] != "Welcome!" {
+					t.Errorf("annotations = %v, want the prefixed motd annotation", gs.ObjectMeta.Annotations)
+				}
+			},
+		},
+		{
+			name: "counter", method: fiber.MethodPost, path: "/v1/counters/update", body: `{"name": "rooms", "delta": "1"}`, wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, gs *singularityv1.GameServer) {
+				if count := gs.CountersAndLists().Counters["rooms"].Count; count != 2 {
+					t.Errorf("rooms = %d, want 2", count)
+				}
+			},
+		},
+		{name: "counter out of range", method: fiber.MethodPost, path: "/v1/counters/update", body: `{"name": "rooms", "delta": "2"}`, wantStatus: fiber.StatusPreconditionFailed},
+		{name: "missing counter", method: fiber.MethodPost, path: "/v1/counters/update", body: `{"name": "players", "delta": "1"}`, wantStatus: fiber.StatusNotFound},
+		{name: "instance list", method: fiber.MethodPost, path: "/v1/lists/append", body: `{"name": "spectators", "values": ["alex"], "instance": 0}`, wantStatus: fiber.StatusOK},
+		{name: "missing list", method: fiber.MethodPost, path: "/v1/lists/remove", body: `{"name": "spectators", "values": ["alex"]}`, wantStatus: fiber.StatusNotFound},
+		{name: "instance state", method: fiber.MethodPost, path: "/v1/instances/state", body: `{"instance": 0, "state": "Allocated"}`, wantStatus: fiber.StatusOK},
+		{name: "add player", method: fiber.MethodPost, path: "/v1/instances/players/add", body: `{"instance": 0, "player": "steve"}`, wantStatus: fiber.StatusOK},
+		{name: "remove player", method: fiber.MethodPost, path: "/v1/instances/players/remove", body: `{"instance": 0, "player": "steve"}`, wantStatus: fiber.StatusOK},
+		{name: "missing instance", method: fiber.MethodGet, path: "/v1/instances/1", wantStatus: fiber.StatusNotFound},
+		{name: "invalid instance", method: fiber.MethodGet, path: "/v1/instances/first", wantStatus: fiber.StatusBadRequest},
+		{name: "invalid body", method: fiber.MethodPut, path: "/v1/metadata/label", body: `{"key": 1}`, wantStatus: fiber.StatusBadRequest},
+		{name: "unknown field", method: fiber.MethodPost, path: "/v1/reserve", body: `{"minutes": 1}`, wantStatus: fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, c := newServer()
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			res, err := s.App().Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != tt.wantStatus {
+				body, _ := io.ReadAll(res.Body)
+				t.Fatalf("status = %d, want %d: %s", res.StatusCode, tt.wantStatus, body)
+			}
+			if tt.check != nil {
+				tt.check(t, gameServer(t, c))
+			}
+		})
+	}
+}
+
+func TestHTTPGet(t *testing.T) {
+	s, _ := newServer()
+
+	res, err := s.App().Test(httptest.NewRequest(fiber.MethodGet, "/v1/gameserver", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != fiber.StatusOK || res.Header.Get(fiber.HeaderContentType) != fiber.MIMEApplicationJSON {
+		t.Fatalf("response = %d %s, want 200 with JSON", res.StatusCode, res.Header.Get(fiber.HeaderContentType))
+	}
+	var gs map[string]interface{}
+	if err = json.NewDecoder(res.Body).Decode(&gs); err != nil {
+		t.Fatal(err)
+	}
+	if gs["name"] != "lobby" || gs["state"] != string(singularityv1.GameServerStateStarting) {
+		t.Errorf("gameserver = %v, want lobby in Starting state", gs)
+	}
+
+	res, err = s.App().Test(httptest.NewRequest(fiber.MethodGet, "/v1/instances/0", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gsInstance map[string]interface{}
+	if err = json.NewDecoder(res.Body).Decode(&gsInstance); err != nil {
+		t.Fatal(err)
+	}
+	if gsInstance["name"] != singularityv1.GameServerInstanceName("lobby", 0) || gsInstance["capacity"] != float64(1) {
+		t.Errorf("instance = %v, want lobby-0 with capacity 1", gsInstance)
+	}
+}
+
+func TestHTTPWatchGameServer(t *testing.T) {
+	s, _ := newServer()
+	app := s.App()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = app.Listener(ln)
+	}()
+	// The stream only ends once a keep-alive fails to be written, so the app isn't waited for
+	t.Cleanup(func() {
+		_ = ln.Close()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, fiber.MethodGet, "http://"+ln.Addr().String()+"/v1/watch/gameserver", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.Header.Get(fiber.HeaderContentType) != "application/x-ndjson" {
+		t.Errorf("content type = %s, want application/x-ndjson", res.Header.Get(fiber.HeaderContentType))
+	}
+
+	// The current GameServer is sent once, followed by its changes
+	lines := bufio.NewScanner(res.Body)
+	expectLine := func(state singularityv1.GameServerState) {
+		t.Helper()
+		if !lines.Scan() {
+			t.Fatalf("watch ended: %v", lines.Err())
+		}
+		var gs map[string]interface{}
+		if err := json.Unmarshal(lines.Bytes(), &gs); err != nil {
+			t.Fatal(err)
+		}
+		if gs["state"] != string(state) {
+			t.Errorf("watched state = %v, want %s", gs["state"], state)
+		}
+	}
+	expectLine(singularityv1.GameServerStateStarting)
+	if err = s.SDK.Ready(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expectLine(singularityv1.GameServerStateRequestReady)
+}
+
+func TestHTTPError(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want int
+	}{
+		{code: codes.NotFound, want: fiber.StatusNotFound},
+		{code: codes.FailedPrecondition, want: fiber.StatusPreconditionFailed},
+		{code: codes.PermissionDenied, want: fiber.StatusForbidden},
+		{code: codes.Aborted, want: fiber.StatusConflict},
+		{code: codes.DeadlineExceeded, want: fiber.StatusGatewayTimeout},
+		{code: codes.Canceled, want: fiber.StatusRequestTimeout},
+		{code: codes.Internal, want: fiber.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			var fiberErr *fiber.Error
+			if err := httpError(status.Error(tt.code, "message")); !errors.As(err, &fiberErr) || fiberErr.Code != tt.want || fiberErr.Message != "message" {
+				t.Errorf("httpError() = %v, want %d with the message", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,677 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package sdkpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// SDKClient is the client API for SDK service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SDKClient interface {
+	// Requests the GameServer to be Ready, after starting or to be reused after being Allocated
+	Ready(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
+	Allocate(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
+	Reserve(ctx context.Context, in *Duration, opts ...grpc.CallOption) (*Empty, error)
+	Shutdown(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
+	// Reports the GameServer as healthy, forwarded at most once per second
+	Health(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
+	GetGameServer(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GameServer, error)
+	// Streams the current GameServer, and again every time it changes
+	WatchGameServer(ctx context.Context, in *Empty, opts ...grpc.CallOption) (SDK_WatchGameServerClient, error)
+	// Labels and annotations are prefixed with singularity.innit.gg/sdk-
+	SetLabel(ctx context.Context, in *KeyValue, opts ...grpc.CallOption) (*Empty, error)
+	SetAnnotation(ctx context.Context, in *KeyValue, opts ...grpc.CallOption) (*Empty, error)
+	UpdateCounter(ctx context.Context, in *CounterUpdate, opts ...grpc.CallOption) (*Empty, error)
+	AppendList(ctx context.Context, in *ListUpdate, opts ...grpc.CallOption) (*Empty, error)
+	RemoveList(ctx context.Context, in *ListUpdate, opts ...grpc.CallOption) (*Empty, error)
+	GetGameServerInstance(ctx context.Context, in *InstanceRequest, opts ...grpc.CallOption) (*GameServerInstance, error)
+	SetInstanceState(ctx context.Context, in *InstanceState, opts ...grpc.CallOption) (*Empty, error)
+	AddPlayer(ctx context.Context, in *PlayerRequest, opts ...grpc.CallOption) (*Empty, error)
+	RemovePlayer(ctx context.Context, in *PlayerRequest, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type sDKClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSDKClient(cc grpc.ClientConnInterface) SDKClient {
+	return &sDKClient{cc}
+}
+
+func (c *sDKClient) Ready(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/singularity.sdk.v1.SDK/Ready", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sDKClient) Allocate(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/singularity.sdk.v1.SDK/Allocate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sDKClient) Reserve(ctx context.Context, in *Duration, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/singularity.sdk.v1.SDK/Reserve", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sDKClient) Shutdown(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/singularity.sdk.v1.SDK/Shutdown", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sDKClient) Health(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/singularity.sdk.v1.SDK/Health", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sDKClient) GetGameServer(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GameServer, error) {
+	out := new(GameServer)
+	err := c.cc.Invoke(ctx, "/singularity.sdk.v1.SDK/GetGameServer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sDKClient) WatchGameServer(ctx context.Context, in *Empty, opts ...grpc.CallOption) (SDK_WatchGameServerClient, error) {
+	stream, err := c.cc.NewStream(ctx, &SDK_ServiceDesc.Streams[0], "/singularity.sdk.v1.SDK/WatchGameServer", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &sDKWatchGameServerClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SDK_WatchGameServerClient interface {
+	Recv() (*GameServer, error)
+	grpc.ClientStream
+}
+
+type sDKWatchGameServerClient struct {
+	grpc.ClientStream
+}
+
+func (x *sDKWatchGameServerClient) Recv() (*GameServer, error) {
+	m := new(GameServer)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *sDKClient) SetLabel(ctx context.Context, in *KeyValue, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/singularity.sdk.v1.SDK/SetLabel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sDKClient) SetAnnotation(ctx context.Context, in *KeyValue, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/singularity.sdk.v1.SDK/SetAnnotation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sDKClient) UpdateCounter(ctx context.Context, in *CounterUpdate, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/singularity.sdk.v1.SDK/UpdateCounter", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sDKClient) AppendList(ctx context.Context, in *ListUpdate, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/singularity.sdk.v1.SDK/AppendList", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sDKClient) RemoveList(ctx context.Context, in *ListUpdate, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/singularity.sdk.v1.SDK/RemoveList", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sDKClient) GetGameServerInstance(ctx context.Context, in *InstanceRequest, opts ...grpc.CallOption) (*GameServerInstance, error) {
+	out := new(GameServerInstance)
+	err := c.cc.Invoke(ctx, "/singularity.sdk.v1.SDK/GetGameServerInstance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sDKClient) SetInstanceState(ctx context.Context, in *InstanceState, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/singularity.sdk.v1.SDK/SetInstanceState", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sDKClient) AddPlayer(ctx context.Context, in *PlayerRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/singularity.sdk.v1.SDK/AddPlayer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sDKClient) RemovePlayer(ctx context.Context, in *PlayerRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/singularity.sdk.v1.SDK/RemovePlayer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SDKServer is the server API for SDK service.
+// All implementations must embed UnimplementedSDKServer
+// for forward compatibility
+type SDKServer interface {
+	// Requests the GameServer to be Ready, after starting or to be reused after being Allocated
+	Ready(context.Context, *Empty) (*Empty, error)
+	Allocate(context.Context, *Empty) (*Empty, error)
+	Reserve(context.Context, *Duration) (*Empty, error)
+	Shutdown(context.Context, *Empty) (*Empty, error)
+	// Reports the GameServer as healthy, forwarded at most once per second
+	Health(context.Context, *Empty) (*Empty, error)
+	GetGameServer(context.Context, *Empty) (*GameServer, error)
+	// Streams the current GameServer, and again every time it changes
+	WatchGameServer(*Empty, SDK_WatchGameServerServer) error
+	// Labels and annotations are prefixed with singularity.innit.gg/sdk-
+	SetLabel(context.Context, *KeyValue) (*Empty, error)
+	SetAnnotation(context.Context, *KeyValue) (*Empty, error)
+	UpdateCounter(context.Context, *CounterUpdate) (*Empty, error)
+	AppendList(context.Context, *ListUpdate) (*Empty, error)
+	RemoveList(context.Context, *ListUpdate) (*Empty, error)
+	GetGameServerInstance(context.Context, *InstanceRequest) (*GameServerInstance, error)
+	SetInstanceState(context.Context, *InstanceState) (*Empty, error)
+	AddPlayer(context.Context, *PlayerRequest) (*Empty, error)
+	RemovePlayer(context.Context, *PlayerRequest) (*Empty, error)
+	mustEmbedUnimplementedSDKServer()
+}
+
+// UnimplementedSDKServer must be embedded to have forward compatible implementations.
+type UnimplementedSDKServer struct {
+}
+
+func (UnimplementedSDKServer) Ready(context.Context, *Empty) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ready not implemented")
+}
+func (UnimplementedSDKServer) Allocate(context.Context, *Empty) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Allocate not implemented")
+}
+func (UnimplementedSDKServer) Reserve(context.Context, *Duration) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Reserve not implemented")
+}
+func (UnimplementedSDKServer) Shutdown(context.Context, *Empty) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Shutdown not implemented")
+}
+func (UnimplementedSDKServer) Health(context.Context, *Empty) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Health not implemented")
+}
+func (UnimplementedSDKServer) GetGameServer(context.Context, *Empty) (*GameServer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetGameServer not implemented")
+}
+func (UnimplementedSDKServer) WatchGameServer(*Empty, SDK_WatchGameServerServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchGameServer not implemented")
+}
+func (UnimplementedSDKServer) SetLabel(context.Context, *KeyValue) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLabel not implemented")
+}
+func (UnimplementedSDKServer) SetAnnotation(context.Context, *KeyValue) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetAnnotation not implemented")
+}
+func (UnimplementedSDKServer) UpdateCounter(context.Context, *CounterUpdate) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateCounter not implemented")
+}
+func (UnimplementedSDKServer) AppendList(context.Context, *ListUpdate) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AppendList not implemented")
+}
+func (UnimplementedSDKServer) RemoveList(context.Context, *ListUpdate) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveList not implemented")
+}
+func (UnimplementedSDKServer) GetGameServerInstance(context.Context, *InstanceRequest) (*GameServerInstance, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetGameServerInstance not implemented")
+}
+func (UnimplementedSDKServer) SetInstanceState(context.Context, *InstanceState) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetInstanceState not implemented")
+}
+func (UnimplementedSDKServer) AddPlayer(context.Context, *PlayerRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddPlayer not implemented")
+}
+func (UnimplementedSDKServer) RemovePlayer(context.Context, *PlayerRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemovePlayer not implemented")
+}
+func (UnimplementedSDKServer) mustEmbedUnimplementedSDKServer() {}
+
+// UnsafeSDKServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SDKServer will
+// result in compilation errors.
+type UnsafeSDKServer interface {
+	mustEmbedUnimplementedSDKServer()
+}
+
+func RegisterSDKServer(s grpc.ServiceRegistrar, srv SDKServer) {
+	s.RegisterService(&SDK_ServiceDesc, srv)
+}
+
+func _SDK_Ready_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SDKServer).Ready(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/singularity.sdk.v1.SDK/Ready",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SDKServer).Ready(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SDK_Allocate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SDKServer).Allocate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/singularity.sdk.v1.SDK/Allocate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SDKServer).Allocate(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SDK_Reserve_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Duration)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SDKServer).Reserve(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/singularity.sdk.v1.SDK/Reserve",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SDKServer).Reserve(ctx, req.(*Duration))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SDK_Shutdown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SDKServer).Shutdown(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/singularity.sdk.v1.SDK/Shutdown",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SDKServer).Shutdown(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SDK_Health_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SDKServer).Health(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/singularity.sdk.v1.SDK/Health",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SDKServer).Health(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SDK_GetGameServer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SDKServer).GetGameServer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/singularity.sdk.v1.SDK/GetGameServer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SDKServer).GetGameServer(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SDK_WatchGameServer_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SDKServer).WatchGameServer(m, &sDKWatchGameServerServer{stream})
+}
+
+type SDK_WatchGameServerServer interface {
+	Send(*GameServer) error
+	grpc.ServerStream
+}
+
+type sDKWatchGameServerServer struct {
+	grpc.ServerStream
+}
+
+func (x *sDKWatchGameServerServer) Send(m *GameServer) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _SDK_SetLabel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyValue)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SDKServer).SetLabel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/singularity.sdk.v1.SDK/SetLabel",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SDKServer).SetLabel(ctx, req.(*KeyValue))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SDK_SetAnnotation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyValue)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SDKServer).SetAnnotation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/singularity.sdk.v1.SDK/SetAnnotation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SDKServer).SetAnnotation(ctx, req.(*KeyValue))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SDK_UpdateCounter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CounterUpdate)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SDKServer).UpdateCounter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/singularity.sdk.v1.SDK/UpdateCounter",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SDKServer).UpdateCounter(ctx, req.(*CounterUpdate))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SDK_AppendList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUpdate)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SDKServer).AppendList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/singularity.sdk.v1.SDK/AppendList",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SDKServer).AppendList(ctx, req.(*ListUpdate))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SDK_RemoveList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUpdate)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SDKServer).RemoveList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/singularity.sdk.v1.SDK/RemoveList",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SDKServer).RemoveList(ctx, req.(*ListUpdate))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SDK_GetGameServerInstance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InstanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SDKServer).GetGameServerInstance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/singularity.sdk.v1.SDK/GetGameServerInstance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SDKServer).GetGameServerInstance(ctx, req.(*InstanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SDK_SetInstanceState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InstanceState)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SDKServer).SetInstanceState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/singularity.sdk.v1.SDK/SetInstanceState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SDKServer).SetInstanceState(ctx, req.(*InstanceState))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SDK_AddPlayer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PlayerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SDKServer).AddPlayer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/singularity.sdk.v1.SDK/AddPlayer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SDKServer).AddPlayer(ctx, req.(*PlayerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SDK_RemovePlayer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PlayerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SDKServer).RemovePlayer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/singularity.sdk.v1.SDK/RemovePlayer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SDKServer).RemovePlayer(ctx, req.(*PlayerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SDK_ServiceDesc is the grpc.ServiceDesc for SDK service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SDK_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "singularity.sdk.v1.SDK",
+	HandlerType: (*SDKServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Ready",
+			Handler:    _SDK_Ready_Handler,
+		},
+		{
+			MethodName: "Allocate",
+			Handler:    _SDK_Allocate_Handler,
+		},
+		{
+			MethodName: "Reserve",
+			Handler:    _SDK_Reserve_Handler,
+		},
+		{
+			MethodName: "Shutdown",
+			Handler:    _SDK_Shutdown_Handler,
+		},
+		{
+			MethodName: "Health",
+			Handler:    _SDK_Health_Handler,
+		},
+		{
+			MethodName: "GetGameServer",
+			Handler:    _SDK_GetGameServer_Handler,
+		},
+		{
+			MethodName: "SetLabel",
+			Handler:    _SDK_SetLabel_Handler,
+		},
+		{
+			MethodName: "SetAnnotation",
+			Handler:    _SDK_SetAnnotation_Handler,
+		},
+		{
+			MethodName: "UpdateCounter",
+			Handler:    _SDK_UpdateCounter_Handler,
+		},
+		{
+			MethodName: "AppendList",
+			Handler:    _SDK_AppendList_Handler,
+		},
+		{
+			MethodName: "RemoveList",
+			Handler:    _SDK_RemoveList_Handler,
+		},
+		{
+			MethodName: "GetGameServerInstance",
+			Handler:    _SDK_GetGameServerInstance_Handler,
+		},
+		{
+			MethodName: "SetInstanceState",
+			Handler:    _SDK_SetInstanceState_Handler,
+		},
+		{
+			MethodName: "AddPlayer",
+			Handler:    _SDK_AddPlayer_Handler,
+		},
+		{
+			MethodName: "RemovePlayer",
+			Handler:    _SDK_RemovePlayer_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchGameServer",
+			Handler:       _SDK_WatchGameServer_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "sdk.proto",
+}
@@ -0,0 +1,163 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package sdkserver exposes the SDK to game servers written in any language.
+// It runs as a sidecar of the GameServer Pod, serving HTTP and gRPC on localhost.
+package sdkserver
+
+import (
+	"context"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	"innit.gg/singularity/pkg/sdk"
+	"innit.gg/singularity/pkg/sdkserver/sdkpb"
+	"sync"
+	"time"
+)
+
+// healthInterval is the minimum interval health reports are forwarded in, to keep the load on the API server low
+const healthInterval = time.Second
+
+// Server implements sdkpb.SDKServer on top of the SDK
+type Server struct {
+	sdkpb.UnimplementedSDKServer
+
+	SDK *sdk.SDK
+
+	mu         sync.Mutex
+	lastHealth time.Time
+}
+
+func (s *Server) Ready(ctx context.Context, _ *sdkpb.Empty) (*sdkpb.Empty, error) {
+	return empty(s.SDK.Ready(ctx))
+}
+
+func (s *Server) Allocate(ctx context.Context, _ *sdkpb.Empty) (*sdkpb.Empty, error) {
+	return empty(s.SDK.Allocate(ctx))
+}
+
+func (s *Server) Reserve(ctx context.Context, req *sdkpb.Duration) (*sdkpb.Empty, error) {
+	return empty(s.SDK.Reserve(ctx, time.Duration(req.Seconds)*time.Second))
+}
+
+func (s *Server) Shutdown(ctx context.Context, _ *sdkpb.Empty) (*sdkpb.Empty, error) {
+	return empty(s.SDK.Shutdown(ctx))
+}
+
+func (s *Server) Health(ctx context.Context, _ *sdkpb.Empty) (*sdkpb.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.lastHealth) < healthInterval {
+		return &sdkpb.Empty{}, nil
+	}
+	if err := s.SDK.Health(ctx); err != nil {
+		return nil, grpcError(err)
+	}
+	s.lastHealth = time.Now()
+
+	return &sdkpb.Empty{}, nil
+}
+
+func (s *Server) GetGameServer(ctx context.Context, _ *sdkpb.Empty) (*sdkpb.GameServer, error) {
+	gs, err := s.SDK.GameServer(ctx)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return gameServerToProto(gs), nil
+}
+
+func (s *Server) WatchGameServer(_ *sdkpb.Empty, stream sdkpb.SDK_WatchGameServerServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	// The SDK reports the current GameServer first
+	var sendErr error
+	err := s.SDK.WatchGameServer(ctx, func(gs *singularityv1.GameServer) {
+		if sendErr = stream.Send(gameServerToProto(gs)); sendErr != nil {
+			cancel()
+		}
+	})
+	if sendErr != nil {
+		return sendErr
+	}
+	if err != nil {
+		return grpcError(err)
+	}
+
+	return nil
+}
+
+func (s *Server) SetLabel(ctx context.Context, req *sdkpb.KeyValue) (*sdkpb.Empty, error) {
+	return empty(s.SDK.SetLabel(ctx, req.Key, req.Value))
+}
+
+func (s *Server) SetAnnotation(ctx context.Context, req *sdkpb.KeyValue) (*sdkpb.Empty, error) {
+	return empty(s.SDK.SetAnnotation(ctx, req.Key, req.Value))
+}
+
+func (s *Server) UpdateCounter(ctx context.Context, req *sdkpb.CounterUpdate) (*sdkpb.Empty, error) {
+	if req.Instance != nil {
+		return empty(s.SDK.UpdateInstanceCounter(ctx, int(*req.Instance), req.Name, req.Delta))
+	}
+	return empty(s.SDK.UpdateCounter(ctx, req.Name, req.Delta))
+}
+
+func (s *Server) AppendList(ctx context.Context, req *sdkpb.ListUpdate) (*sdkpb.Empty, error) {
+	if req.Instance != nil {
+		return empty(s.SDK.AppendInstanceList(ctx, int(*req.Instance), req.Name, req.Values...))
+	}
+	return empty(s.SDK.AppendList(ctx, req.Name, req.Values...))
+}
+
+func (s *Server) RemoveList(ctx context.Context, req *sdkpb.ListUpdate) (*sdkpb.Empty, error) {
+	if req.Instance != nil {
+		return empty(s.SDK.RemoveInstanceList(ctx, int(*req.Instance), req.Name, req.Values...))
+	}
+	return empty(s.SDK.RemoveList(ctx, req.Name, req.Values...))
+}
+
+func (s *Server) GetGameServerInstance(ctx context.Context, req *sdkpb.InstanceRequest) (*sdkpb.GameServerInstance, error) {
+	gsInstance, err := s.SDK.GameServerInstance(ctx, int(req.Instance))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return instanceToProto(gsInstance), nil
+}
+
+func (s *Server) SetInstanceState(ctx context.Context, req *sdkpb.InstanceState) (*sdkpb.Empty, error) {
+	return empty(s.SDK.SetInstanceState(ctx, int(req.Instance), singularityv1.GameServerInstanceState(req.State)))
+}
+
+func (s *Server) AddPlayer(ctx context.Context, req *sdkpb.PlayerRequest) (*sdkpb.Empty, error) {
+	return empty(s.SDK.AddPlayer(ctx, int(req.Instance), req.Player))
+}
+
+func (s *Server) RemovePlayer(ctx context.Context, req *sdkpb.PlayerRequest) (*sdkpb.Empty, error) {
+	return empty(s.SDK.RemovePlayer(ctx, int(req.Instance), req.Player))
+}
+
+// empty returns the response of methods without a result
+func empty(err error) (*sdkpb.Empty, error) {
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &sdkpb.Empty{}, nil
+}
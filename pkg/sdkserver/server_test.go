@@ -0,0 +1,354 @@
+/*
+ *     Singularity is an open-source game server orchestration framework
+ *     Copyright (C) 2022 Innit Incorporated
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU Affero General Public License as published
+ *     by the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU Affero General Public License for more details.
+ *
+ *     You should have received a copy of the GNU Affero General Public License
+ *     along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package sdkserver
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	singularityv1 "innit.gg/singularity/pkg/apis/singularity/v1"
+	"innit.gg/singularity/pkg/sdk"
+	"innit.gg/singularity/pkg/sdkserver/sdkpb"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"net"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+	"time"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(singularityv1.AddToScheme(scheme))
+}
+
+// newServer returns a Server of the GameServer lobby with an instance, backed by a fake client
+func newServer() (*Server, client.Client) {
+	gs := &singularityv1.GameServer{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "lobby"},
+		Spec: singularityv1.GameServerSpec{
+			Instances: 1,
+			CountersAndLists: singularityv1.CountersAndLists{
+				Counters: map[string]singularityv1.Counter{"rooms": {Count: 1, Capacity: 2}},
+			},
+		},
+		Status: singularityv1.GameServerStatus{State: singularityv1.GameServerStateStarting},
+	}
+	gsInstance := &singularityv1.GameServerInstance{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: singularityv1.GameServerInstanceName("lobby", 0)},
+		Spec: singularityv1.GameServerInstanceSpec{
+			Capacity:         1,
+			CountersAndLists: singularityv1.CountersAndLists{Lists: map[string]singularityv1.List{"spectators": {Capacity: 2}}},
+		},
+		Status: singularityv1.GameServerInstanceStatus{State: singularityv1.GameServerInstanceStateReady},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs, gsInstance).Build()
+	return &Server{SDK: sdk.NewWithClient(c, "default", "lobby")}, c
+}
+
+// dial serves the Server over gRPC on an in-memory listener, returning a client connected to it
+func dial(t *testing.T, s *Server) sdkpb.SDKClient {
+	t.Helper()
+
+	ln := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	sdkpb.RegisterSDKServer(server, s)
+	go func() {
+		_ = server.Serve(ln)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return ln.Dial()
+		}),
+		grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return sdkpb.NewSDKClient(conn)
+}
+
+// gameServer returns the current GameServer lobby of the client
+func gameServer(t *testing.T, c client.Client) *singularityv1.GameServer {
+	t.Helper()
+
+	gs := &singularityv1.GameServer{}
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "lobby"}, gs); err != nil {
+		t.Fatal(err)
+	}
+
+	return gs
+}
+
+// instance returns the current GameServerInstance of the client with the id
+func instance(t *testing.T, c client.Client, id int) *singularityv1.GameServerInstance {
+	t.Helper()
+
+	gsInstance := &singularityv1.GameServerInstance{}
+	key := client.ObjectKey{Namespace: "default", Name: singularityv1.GameServerInstanceName("lobby", id)}
+	if err := c.Get(context.Background(), key, gsInstance); err != nil {
+		t.Fatal(err)
+	}
+
+	return gsInstance
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func TestGRPC(t *testing.T) {
+	ctx := context.Background()
+	s, c := newServer()
+	sdkClient := dial(t, s)
+
+	if _, err := sdkClient.Ready(ctx, &sdkpb.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	if state := gameServer(t, c).Status.State; state != singularityv1.GameServerStateRequestReady {
+		t.Errorf("state after Ready = %s, want RequestReady", state)
+	}
+
+	if _, err := sdkClient.Reserve(ctx, &sdkpb.Duration{Seconds: 60}); err != nil {
+		t.Fatal(err)
+	}
+	if gs := gameServer(t, c); gs.Status.State != singularityv1.GameServerStateReserved || gs.Status.ReservedUntil == nil ||
+		time.Until(gs.Status.ReservedUntil.Time) > time.Minute {
+		t.Errorf("state after Reserve = %s until %v, want Reserved for a minute", gs.Status.State, gs.Status.ReservedUntil)
+	}
+
+	if _, err := sdkClient.Allocate(ctx, &sdkpb.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sdkClient.Health(ctx, &sdkpb.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sdkClient.SetLabel(ctx, &sdkpb.KeyValue{Key: "map", Value: "castle"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sdkClient.SetAnnotation(ctx, &sdkpb.KeyValue{Key: "motd", Value: "Welcome!"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sdkClient.UpdateCounter(ctx, &sdkpb.CounterUpdate{Name: "rooms", Delta: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	gs, err := sdkClient.GetGameServer(ctx, &sdkpb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gs.State != string(singularityv1.GameServerStateAllocated) || gs.Allocations != 1 {
+		t.Errorf("state = %s after %d allocations, want Allocated after 1", gs.State, gs.Allocations)
+	}
+	if gs.Labels[sdk.LabelPrefix+"map"] != "castle" || gs.Annotations[sdk.LabelPrefix+"motd"] != "Welcome!" {
+		t.Errorf("metadata = %v %v, want the prefixed label and annotation", gs.Labels, gs.Annotations)
+	}
+	if rooms := gs.Counters["rooms"]; rooms.GetCount() != 2 || rooms.GetCapacity() != 2 {
+		t.Errorf("rooms = %v, want 2 of 2", rooms)
+	}
+	if gameServer(t, c).Status.LastHealthy == nil {
+		t.Error("lastHealthy isn't set after Health")
+	}
+
+	if _, err = sdkClient.Shutdown(ctx, &sdkpb.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	if state := gameServer(t, c).Status.State; state != singularityv1.GameServerStateShutdown {
+		t.Errorf("state after Shutdown = %s, want Shutdown", state)
+	}
+}
+
+func TestGRPCInstances(t *testing.T) {
+	ctx := context.Background()
+	s, c := newServer()
+	sdkClient := dial(t, s)
+
+	if _, err := sdkClient.AddPlayer(ctx, &sdkpb.PlayerRequest{Instance: 0, Player: "steve"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sdkClient.AppendList(ctx, &sdkpb.ListUpdate{Name: "spectators", Values: []string{"alex", "herobrine"}, Instance: int32Ptr(0)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sdkClient.RemoveList(ctx, &sdkpb.ListUpdate{Name: "spectators", Values: []string{"herobrine"}, Instance: int32Ptr(0)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sdkClient.SetInstanceState(ctx, &sdkpb.InstanceState{Instance: 0, State: string(singularityv1.GameServerInstanceStateAllocated)}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := sdkClient.GetGameServerInstance(ctx, &sdkpb.InstanceRequest{Instance: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.State != string(singularityv1.GameServerInstanceStateAllocated) || !reflect.DeepEqual(res.Players, []string{"steve"}) {
+		t.Errorf("instance = %s with %v, want Allocated with steve", res.State, res.Players)
+	}
+	if values := res.Lists["spectators"].GetValues(); !reflect.DeepEqual(values, []string{"alex"}) {
+		t.Errorf("spectators = %v, want [alex]", values)
+	}
+
+	if _, err = sdkClient.RemovePlayer(ctx, &sdkpb.PlayerRequest{Instance: 0, Player: "steve"}); err != nil {
+		t.Fatal(err)
+	}
+	if players := instance(t, c, 0).Status.Players; len(players) != 0 {
+		t.Errorf("players = %v, want none", players)
+	}
+}
+
+func TestGRPCErrors(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newServer()
+	sdkClient := dial(t, s)
+
+	tests := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{
+			name: "missing instance",
+			call: func() error {
+				_, err := sdkClient.GetGameServerInstance(ctx, &sdkpb.InstanceRequest{Instance: 1})
+				return err
+			},
+			want: codes.NotFound,
+		},
+		{
+			name: "missing counter",
+			call: func() error {
+				_, err := sdkClient.UpdateCounter(ctx, &sdkpb.CounterUpdate{Name: "players", Delta: 1})
+				return err
+			},
+			want: codes.NotFound,
+		},
+		{
+			name: "counter out of range",
+			call: func() error {
+				_, err := sdkClient.UpdateCounter(ctx, &sdkpb.CounterUpdate{Name: "rooms", Delta: -2})
+				return err
+			},
+			want: codes.FailedPrecondition,
+		},
+		{
+			name: "instance at capacity",
+			call: func() error {
+				if _, err := sdkClient.AddPlayer(ctx, &sdkpb.PlayerRequest{Instance: 0, Player: "steve"}); err != nil {
+					return err
+				}
+				_, err := sdkClient.AddPlayer(ctx, &sdkpb.PlayerRequest{Instance: 0, Player: "alex"})
+				return err
+			},
+			want: codes.FailedPrecondition,
+		},
+		{
+			name: "invalid state transition",
+			call: func() error {
+				if _, err := sdkClient.Shutdown(ctx, &sdkpb.Empty{}); err != nil {
+					return err
+				}
+				_, err := sdkClient.Ready(ctx, &sdkpb.Empty{})
+				return err
+			},
+			want: codes.FailedPrecondition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := status.Code(tt.call()); code != tt.want {
+				t.Errorf("code = %s, want %s", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestGRPCWatchGameServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, _ := newServer()
+	sdkClient := dial(t, s)
+
+	stream, err := sdkClient.WatchGameServer(ctx, &sdkpb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The current GameServer is sent once, followed by its changes
+	gs, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gs.State != string(singularityv1.GameServerStateStarting) {
+		t.Errorf("first state = %s, want Starting", gs.State)
+	}
+
+	if _, err = sdkClient.Ready(ctx, &sdkpb.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	if gs, err = stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	if gs.State != string(singularityv1.GameServerStateRequestReady) {
+		t.Errorf("next state = %s, want RequestReady", gs.State)
+	}
+}
+
+func TestGRPCError(t *testing.T) {
+	resource := singularityv1.GroupVersion.WithResource("gameservers").GroupResource()
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{name: "not found", err: k8serrors.NewNotFound(resource, "lobby"), want: codes.NotFound},
+		{name: "list not found", err: errors.Wrap(singularityv1.ErrorListNotFound, "spectators"), want: codes.NotFound},
+		{name: "list at capacity", err: singularityv1.ErrorListAtCapacity, want: codes.FailedPrecondition},
+		{name: "forbidden", err: k8serrors.NewForbidden(resource, "other", errors.New("denied")), want: codes.PermissionDenied},
+		{name: "conflict", err: k8serrors.NewConflict(resource, "lobby", errors.New("modified")), want: codes.Aborted},
+		{name: "deadline exceeded", err: errors.Wrap(context.DeadlineExceeded, "error retrieving gameserver"), want: codes.DeadlineExceeded},
+		{name: "canceled", err: context.Canceled, want: codes.Canceled},
+		{name: "unknown", err: errors.New("unavailable"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := grpcError(tt.err)
+			if code := status.Code(err); code != tt.want {
+				t.Errorf("grpcError() code = %s, want %s", code, tt.want)
+			}
+			if status.Convert(err).Message() != tt.err.Error() {
+				t.Errorf("grpcError() message = %q, want %q", status.Convert(err).Message(), tt.err.Error())
+			}
+		})
+	}
+}